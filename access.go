@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+// accessRules are the compiled IP allowlist and bearer token settings
+type accessRules struct {
+	networks    []*net.IPNet
+	bearerToken func() (config.Secret, error)
+}
+
+// accessControl wraps a handler with the optional IP allowlist and bearer token checks.
+// TLS, client certificates and basic auth are handled by the exporter-toolkit web config.
+type accessControl struct {
+	next  http.Handler
+	rules atomic.Pointer[accessRules]
+}
+
+// newAccessControl creates the access control middleware for the server config
+func newAccessControl(next http.Handler, cfg config.ServerConfig) (*accessControl, error) {
+	a := &accessControl{next: next}
+	if err := a.update(cfg); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// update replaces the rules, keeping the current ones if the new config is invalid
+func (a *accessControl) update(cfg config.ServerConfig) error {
+	networks, err := config.ParseNetworks(cfg.AllowedNetworks)
+	if err != nil {
+		return err
+	}
+
+	bearerToken := func() (config.Secret, error) { return cfg.BearerToken, nil }
+	if cfg.BearerTokenFile != "" {
+		bearerToken = config.NewSecretFile(cfg.BearerTokenFile).Get
+	}
+	a.rules.Store(&accessRules{networks: networks, bearerToken: bearerToken})
+	return nil
+}
+
+// ServeHTTP checks the client address and bearer token before passing the request on
+func (a *accessControl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rules := a.rules.Load()
+	if len(rules.networks) > 0 && !clientAllowed(r.RemoteAddr, rules.networks) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	token, err := rules.bearerToken()
+	if err != nil {
+		log.Printf("Error reading bearer token: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if token != "" {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	a.next.ServeHTTP(w, r)
+}
+
+// clientAllowed reports whether the request's remote address is in one of the networks.
+// Forwarding headers are deliberately ignored, as they can be set by any client.
+func clientAllowed(remoteAddr string, networks []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
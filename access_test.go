@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+func TestAccessControl(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler, err := newAccessControl(ok, config.ServerConfig{
+		BearerToken:     "s3cret",
+		AllowedNetworks: []string{"10.0.0.0/8", "192.168.1.5"},
+	})
+	if err != nil {
+		t.Fatalf("newAccessControl failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		auth       string
+		want       int
+	}{
+		{"allowed network and token", "10.1.2.3:5000", "Bearer s3cret", http.StatusOK},
+		{"allowed single IP", "192.168.1.5:5000", "Bearer s3cret", http.StatusOK},
+		{"wrong token", "10.1.2.3:5000", "Bearer nope", http.StatusUnauthorized},
+		{"basic auth instead of token", "10.1.2.3:5000", "Basic czNjcmV0", http.StatusUnauthorized},
+		{"missing token", "10.1.2.3:5000", "", http.StatusUnauthorized},
+		{"outside allowlist", "192.168.1.6:5000", "Bearer s3cret", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAccessControlInvalidNetwork(t *testing.T) {
+	if _, err := newAccessControl(http.NotFoundHandler(), config.ServerConfig{AllowedNetworks: []string{"not-an-ip"}}); err == nil {
+		t.Error("expected error for invalid network")
+	}
+}
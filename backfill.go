@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/collector"
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/common/model"
+)
+
+// runBackfill implements the "backfill" subcommand, which writes historical metrics
+// from PVE RRD data as OpenMetrics for "promtool tsdb create-blocks-from openmetrics"
+func runBackfill(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	sinceFlag := fs.String("since", "30d", "How far back to backfill (e.g. 12h, 30d, 1y)")
+	outFile := fs.String("out", "-", "Output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	since, err := model.ParseDuration(*sinceFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --since value: %w", err)
+	}
+
+	cfg, err := config.LoadFromFile(*configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outFile != "-" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+
+	proxmoxCollector := collector.NewProxmoxCollector(cfg)
+	return proxmoxCollector.Backfill(out, time.Duration(since))
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/collector"
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// checkStatus is a Nagios plugin state, which is also the exit code
+type checkStatus int
+
+const (
+	statusOK checkStatus = iota
+	statusWarning
+	statusCritical
+	statusUnknown
+)
+
+func (s checkStatus) String() string {
+	return [...]string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}[s]
+}
+
+// checkValue is a single value evaluated against the thresholds, reported as perfdata
+type checkValue struct {
+	label string
+	value float64
+}
+
+// metricFamilies maps metric names to their gathered families
+type metricFamilies map[string]*dto.MetricFamily
+
+// nagiosCheck evaluates the metrics of its collectors against warning and critical thresholds
+type nagiosCheck struct {
+	collectors []string
+	noun       string // what the values are, e.g. "storages"
+	unit       string // perfdata unit of measure
+	// lowerIsWorse inverts the thresholds: a value below them is a problem
+	lowerIsWorse      bool
+	warning, critical float64
+	// optional checks report OK when there is nothing to check, instead of UNKNOWN
+	optional bool
+	// values extracts the values to check; a non-empty reason fails the check regardless of the thresholds
+	values func(f metricFamilies, now time.Time) (values []checkValue, reason string)
+}
+
+// nagiosChecks are the checks available as "check <name>"
+var nagiosChecks = map[string]nagiosCheck{
+	"quorum": {
+		collectors: []string{"cluster"}, noun: "offline nodes", warning: 0, critical: 1,
+		values: quorumValues,
+	},
+	"storage-usage": {
+		collectors: []string{"storage"}, noun: "storages", unit: "%", warning: 80, critical: 90,
+		values: func(f metricFamilies, _ time.Time) ([]checkValue, string) {
+			return samples(f, "pve_storage_used_fraction", 100, "node", "storage"), ""
+		},
+	},
+	"zfs-health": {
+		collectors: []string{"zfs"}, noun: "pools", lowerIsWorse: true, warning: 1, critical: 1, optional: true,
+		values: func(f metricFamilies, _ time.Time) ([]checkValue, string) {
+			return samples(f, "pve_zfs_pool_health_status", 1, "node", "pool"), ""
+		},
+	},
+	"backup-age": {
+		// The guest collector lists every guest, including the ones never backed up
+		collectors: []string{"guest", "backup"}, noun: "guests", unit: "h", warning: 26, critical: 50,
+		values: backupAgeValues,
+	},
+	"certificate-expiry": {
+		collectors: []string{"certificate"}, noun: "certificates", unit: "d", lowerIsWorse: true, warning: 30, critical: 7,
+		values: func(f metricFamilies, _ time.Time) ([]checkValue, string) {
+			return samples(f, "pve_certificate_expiry_seconds", 1.0/86400, "node"), ""
+		},
+	},
+	"replication": {
+		collectors: []string{"replication"}, noun: "jobs", lowerIsWorse: true, warning: 1, critical: 1, optional: true,
+		values: func(f metricFamilies, _ time.Time) ([]checkValue, string) {
+			return samples(f, "pve_replication_status", 1, "job"), ""
+		},
+	},
+	"ha-errors": {
+		collectors: []string{"cluster"}, noun: "HA resources in error", warning: 0, critical: 0,
+		values: func(f metricFamilies, _ time.Time) ([]checkValue, string) {
+			return samples(f, "pve_ha_resources_error", 1), ""
+		},
+	},
+}
+
+// nagiosCheckNames returns the sorted check names
+func nagiosCheckNames() []string {
+	names := make([]string, 0, len(nagiosChecks))
+	for name := range nagiosChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runCheck implements the "check" subcommand, a Nagios/Icinga plugin. It exits with the plugin
+// status instead of returning, so that errors are reported as UNKNOWN.
+func runCheck(args []string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	check, ok := nagiosChecks[name]
+	if !ok {
+		fmt.Printf("UNKNOWN - usage: pve-exporter check <%s> [flags]\n", strings.Join(nagiosCheckNames(), "|"))
+		os.Exit(int(statusUnknown))
+	}
+
+	fs := flag.NewFlagSet("check "+name, flag.ContinueOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	warning := fs.Float64("warning", check.warning, "Warning threshold")
+	critical := fs.Float64("critical", check.critical, "Critical threshold")
+	if err := fs.Parse(args[1:]); err != nil {
+		// The default exit code for bad flags would read as WARNING
+		os.Exit(int(statusUnknown))
+	}
+	check.warning, check.critical = *warning, *critical
+
+	os.Exit(int(runNagiosCheck(os.Stdout, name, check, *configFile)))
+	return nil
+}
+
+// runNagiosCheck collects the check's metrics and writes the plugin output
+func runNagiosCheck(w io.Writer, name string, check nagiosCheck, configFile string) checkStatus {
+	families, err := collectFamilies(configFile, check.collectors)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "PVE %s UNKNOWN - %v\n", strings.ToUpper(name), err)
+		return statusUnknown
+	}
+	return check.evaluate(w, name, families, time.Now())
+}
+
+// collectFamilies runs a single collection of the named collectors
+func collectFamilies(configFile string, names []string) (metricFamilies, error) {
+	cfg, err := config.LoadFromFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	metrics, err := collector.NewProxmoxCollector(cfg).CollectByName(names)
+	if err != nil {
+		return nil, err
+	}
+	var all []prometheus.Metric
+	for _, name := range names {
+		all = append(all, metrics[name]...)
+	}
+	gathered, err := gather(all)
+	if err != nil {
+		return nil, err
+	}
+	families := make(metricFamilies)
+	for _, mf := range gathered {
+		families[mf.GetName()] = mf
+	}
+	return families, nil
+}
+
+// evaluate checks the values against the thresholds and writes the plugin output with perfdata
+func (check nagiosCheck) evaluate(w io.Writer, name string, families metricFamilies, now time.Time) checkStatus {
+	values, reason := check.values(families, now)
+	if len(values) == 0 && reason == "" {
+		if check.optional {
+			_, _ = fmt.Fprintf(w, "PVE %s OK - no %s found\n", strings.ToUpper(name), check.noun)
+			return statusOK
+		}
+		_, _ = fmt.Fprintf(w, "PVE %s UNKNOWN - no data, check the API connection and permissions\n", strings.ToUpper(name))
+		return statusUnknown
+	}
+
+	status := statusOK
+	var problems, perfdata []string
+	for _, v := range values {
+		s := check.status(v.value)
+		if s != statusOK {
+			problems = append(problems, fmt.Sprintf("%s=%s%s (%s)", v.label, formatCheckValue(v.value), check.unit, s))
+		}
+		status = max(status, s)
+		perfdata = append(perfdata, fmt.Sprintf("'%s'=%s%s;%s;%s", v.label, formatCheckValue(v.value), perfUnit(check.unit),
+			formatCheckValue(check.warning), formatCheckValue(check.critical)))
+	}
+
+	message := fmt.Sprintf("%d %s checked", len(values), check.noun)
+	if reason != "" {
+		status = statusCritical
+		problems = append([]string{reason}, problems...)
+	}
+	if len(problems) > 0 {
+		message = strings.Join(problems, ", ")
+	}
+	if len(perfdata) > 0 {
+		message += " | " + strings.Join(perfdata, " ")
+	}
+	_, _ = fmt.Fprintf(w, "PVE %s %s - %s\n", strings.ToUpper(name), status, message)
+	return status
+}
+
+// status compares a value against the thresholds
+func (check nagiosCheck) status(value float64) checkStatus {
+	exceeds := func(threshold float64) bool {
+		if check.lowerIsWorse {
+			return value < threshold
+		}
+		return value > threshold
+	}
+	switch {
+	case exceeds(check.critical):
+		return statusCritical
+	case exceeds(check.warning):
+		return statusWarning
+	default:
+		return statusOK
+	}
+}
+
+// formatCheckValue rounds a value to two decimals
+func formatCheckValue(v float64) string {
+	return fmt.Sprintf("%g", math.Round(v*100)/100)
+}
+
+// perfUnit returns the unit for perfdata, which only knows %, s, B and c
+func perfUnit(unit string) string {
+	if unit == "%" {
+		return unit
+	}
+	return ""
+}
+
+// samples returns the scaled values of a metric, labelled by joining the given label values with "/"
+func samples(f metricFamilies, name string, scale float64, labels ...string) []checkValue {
+	var values []checkValue
+	for _, m := range f[name].GetMetric() {
+		label := name
+		if len(labels) > 0 {
+			label = joinLabels(m, labels)
+		}
+		values = append(values, checkValue{label: label, value: metricValue(m) * scale})
+	}
+	return values
+}
+
+// joinLabels joins the values of the given labels of a metric with "/"
+func joinLabels(m *dto.Metric, names []string) string {
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, lp := range m.GetLabel() {
+			if lp.GetName() == name {
+				parts = append(parts, lp.GetValue())
+			}
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// quorumValues returns the number of offline nodes, failing the check if the cluster lost quorum
+func quorumValues(f metricFamilies, _ time.Time) ([]checkValue, string) {
+	quorate := samples(f, "pve_cluster_quorate", 1)
+	total := samples(f, "pve_cluster_nodes_total", 1)
+	online := samples(f, "pve_cluster_nodes_online", 1)
+	if len(quorate) == 0 || len(total) == 0 || len(online) == 0 {
+		return nil, ""
+	}
+
+	reason := ""
+	if quorate[0].value == 0 {
+		reason = "cluster is not quorate"
+	}
+	return []checkValue{{label: "nodes_offline", value: total[0].value - online[0].value}}, reason
+}
+
+// backupAgeValues returns the hours since the last backup of each guest, under any metric naming scheme.
+// Guests are taken from the guest status series, so guests that were never backed up fail the check.
+func backupAgeValues(f metricFamilies, now time.Time) ([]checkValue, string) {
+	latest := make(map[string]float64)
+	for _, name := range []string{"pve_vm_last_backup_timestamp", "pve_lxc_last_backup_timestamp", "pve_guest_last_backup_timestamp"} {
+		for _, m := range f[name].GetMetric() {
+			guest := joinLabels(m, []string{"vmid", "name"})
+			latest[guest] = max(latest[guest], metricValue(m))
+		}
+	}
+
+	var values []checkValue
+	var missing []string
+	seen := make(map[string]bool)
+	for _, name := range []string{"pve_vm_status", "pve_lxc_status", "pve_guest_status"} {
+		for _, m := range f[name].GetMetric() {
+			guest := joinLabels(m, []string{"vmid", "name"})
+			if seen[guest] {
+				continue
+			}
+			seen[guest] = true
+			timestamp, ok := latest[guest]
+			if !ok {
+				missing = append(missing, guest)
+				continue
+			}
+			values = append(values, checkValue{label: guest, value: now.Sub(time.Unix(int64(timestamp), 0)).Hours()})
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].label < values[j].label })
+
+	if len(missing) == 0 {
+		return values, ""
+	}
+	sort.Strings(missing)
+	return values, "no backup: " + strings.Join(missing, ", ")
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testFamilies gathers const gauges with the given name and labels into metric families
+func testFamilies(t *testing.T, name string, labels []string, values map[string]float64) metricFamilies {
+	t.Helper()
+	desc := prometheus.NewDesc(name, "test", labels, nil)
+	var metrics []prometheus.Metric
+	for lv, v := range values {
+		var lvs []string
+		if lv != "" {
+			lvs = strings.Split(lv, ",")
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lvs...))
+	}
+	gathered, err := gather(metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	families := make(metricFamilies)
+	for _, mf := range gathered {
+		families[mf.GetName()] = mf
+	}
+	return families
+}
+
+// mergeFamilies merges metric families gathered separately
+func mergeFamilies(all ...metricFamilies) metricFamilies {
+	merged := make(metricFamilies)
+	for _, families := range all {
+		for name, mf := range families {
+			merged[name] = mf
+		}
+	}
+	return merged
+}
+
+func TestNagiosCheckStatus(t *testing.T) {
+	higher := nagiosCheck{warning: 80, critical: 90}
+	lower := nagiosCheck{lowerIsWorse: true, warning: 30, critical: 7}
+
+	tests := []struct {
+		name  string
+		check nagiosCheck
+		value float64
+		want  checkStatus
+	}{
+		{name: "below warning", check: higher, value: 50, want: statusOK},
+		{name: "at warning", check: higher, value: 80, want: statusOK},
+		{name: "above warning", check: higher, value: 85, want: statusWarning},
+		{name: "above critical", check: higher, value: 95, want: statusCritical},
+		{name: "lower is worse ok", check: lower, value: 60, want: statusOK},
+		{name: "lower is worse warning", check: lower, value: 20, want: statusWarning},
+		{name: "lower is worse critical", check: lower, value: 3, want: statusCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check.status(tt.value); got != tt.want {
+				t.Errorf("status(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNagiosCheckEvaluate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		check    string
+		families metricFamilies
+		want     checkStatus
+		output   string
+	}{
+		{
+			check: "storage-usage",
+			families: testFamilies(t, "pve_storage_used_fraction", []string{"node", "storage"},
+				map[string]float64{"pve1,local": 0.5, "pve1,local-zfs": 0.85}),
+			want:   statusWarning,
+			output: "PVE STORAGE-USAGE WARNING - pve1/local-zfs=85% (WARNING) | 'pve1/local'=50%;80;90 'pve1/local-zfs'=85%;80;90",
+		},
+		{
+			check:    "zfs-health",
+			families: testFamilies(t, "pve_zfs_pool_health_status", []string{"node", "pool"}, map[string]float64{"pve1,rpool": 0}),
+			want:     statusCritical,
+			output:   "PVE ZFS-HEALTH CRITICAL - pve1/rpool=0 (CRITICAL)",
+		},
+		{
+			check:    "zfs-health",
+			families: metricFamilies{},
+			want:     statusOK,
+			output:   "PVE ZFS-HEALTH OK - no pools found",
+		},
+		{
+			check:    "ha-errors",
+			families: testFamilies(t, "pve_ha_resources_error", nil, map[string]float64{"": 0}),
+			want:     statusOK,
+			output:   "PVE HA-ERRORS OK - 1 HA resources in error checked | 'pve_ha_resources_error'=0;0;0",
+		},
+		{
+			check:    "storage-usage",
+			families: metricFamilies{},
+			want:     statusUnknown,
+			output:   "PVE STORAGE-USAGE UNKNOWN - no data",
+		},
+		{
+			check: "backup-age",
+			families: mergeFamilies(
+				testFamilies(t, "pve_vm_status", []string{"node", "vmid", "name"}, map[string]float64{"pve1,100,web": 1}),
+				testFamilies(t, "pve_vm_last_backup_timestamp", []string{"node", "vmid", "name"},
+					map[string]float64{"pve1,100,web": float64(now.Add(-60 * time.Hour).Unix())}),
+			),
+			want:   statusCritical,
+			output: "100/web=60h (CRITICAL)",
+		},
+		{
+			check: "backup-age",
+			families: mergeFamilies(
+				testFamilies(t, "pve_vm_status", []string{"node", "vmid", "name"}, map[string]float64{"pve1,100,web": 1}),
+				testFamilies(t, "pve_lxc_status", []string{"node", "vmid", "name"}, map[string]float64{"pve1,200,dns": 1}),
+				testFamilies(t, "pve_vm_last_backup_timestamp", []string{"node", "vmid", "name"},
+					map[string]float64{"pve1,100,web": float64(now.Add(-2 * time.Hour).Unix())}),
+			),
+			want:   statusCritical,
+			output: "PVE BACKUP-AGE CRITICAL - no backup: 200/dns | '100/web'=2;26;50",
+		},
+		{
+			check:    "backup-age",
+			families: testFamilies(t, "pve_lxc_status", []string{"node", "vmid", "name"}, map[string]float64{"pve1,200,dns": 1}),
+			want:     statusCritical,
+			output:   "PVE BACKUP-AGE CRITICAL - no backup: 200/dns\n",
+		},
+		{
+			check: "certificate-expiry",
+			families: testFamilies(t, "pve_certificate_expiry_seconds", []string{"node"},
+				map[string]float64{"pve1": 90 * 86400}),
+			want:   statusOK,
+			output: "'pve1'=90;30;7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.check, func(t *testing.T) {
+			var out strings.Builder
+			if got := nagiosChecks[tt.check].evaluate(&out, tt.check, tt.families, now); got != tt.want {
+				t.Errorf("status = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("expected output to contain %q, got %q", tt.output, out.String())
+			}
+		})
+	}
+}
+
+func TestQuorumValues(t *testing.T) {
+	families := testFamilies(t, "pve_cluster_quorate", nil, map[string]float64{"": 0})
+	for name, mf := range testFamilies(t, "pve_cluster_nodes_total", nil, map[string]float64{"": 3}) {
+		families[name] = mf
+	}
+	for name, mf := range testFamilies(t, "pve_cluster_nodes_online", nil, map[string]float64{"": 1}) {
+		families[name] = mf
+	}
+
+	var out strings.Builder
+	if got := nagiosChecks["quorum"].evaluate(&out, "quorum", families, time.Now()); got != statusCritical {
+		t.Errorf("status = %v, want CRITICAL", got)
+	}
+	want := "PVE QUORUM CRITICAL - cluster is not quorate, nodes_offline=2 (CRITICAL) | 'nodes_offline'=2;0;1"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("expected output %q, got %q", want, out.String())
+	}
+}
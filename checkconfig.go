@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/exporter-toolkit/web"
+	"gopkg.in/yaml.v3"
+)
+
+// runCheckConfig implements the "check-config" subcommand, which validates the configuration
+// and prints the effective settings (file, environment and defaults combined) with secrets redacted
+func runCheckConfig(args []string) error {
+	fs := flag.NewFlagSet("check-config", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	webConfigFile := fs.String("web.config.file", "", "Path to exporter-toolkit web configuration (TLS, basic auth)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := checkConfig(os.Stdout, *configFile, *webConfigFile); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(os.Stderr, "Configuration is valid")
+	return nil
+}
+
+// checkConfig loads and validates the configuration and writes the effective config as YAML
+func checkConfig(w io.Writer, configFile, webConfigFile string) error {
+	cfg, err := config.LoadFromFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	if webConfigFile != "" {
+		cfg.Server.WebConfigFile = webConfigFile
+	}
+	if err := web.Validate(cfg.Server.WebConfigFile); err != nil {
+		return fmt.Errorf("invalid web configuration: %w", err)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	return enc.Close()
+}
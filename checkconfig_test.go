@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := "proxmox:\n  host: pve1\n  token_id: \"monitoring@pve!exporter\"\n  token_secret: \"super-secret-token\"\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := checkConfig(&out, path, ""); err != nil {
+		t.Fatalf("checkConfig failed: %v", err)
+	}
+	if strings.Contains(out.String(), "super-secret-token") {
+		t.Error("effective config leaks the token secret")
+	}
+	for _, want := range []string{"host: pve1", "token_secret: <secret>", "timeout: 30s", "metrics_path: /metrics"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected effective config to contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCheckConfigUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("proxmox:\n  host: pve1\n  pasword: secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := checkConfig(&strings.Builder{}, path, "")
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "pasword") {
+		t.Errorf("expected line-numbered unknown field error, got %v", err)
+	}
+}
@@ -1,33 +1,60 @@
 package collector
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
-// authenticate authenticates with Proxmox API
+// authenticate authenticates with Proxmox API, failing over to another endpoint if the current one is unreachable
 func (c *ProxmoxCollector) authenticate() error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
 	// Use token authentication if available
-	if c.config.TokenID != "" && c.config.TokenSecret != "" {
+	if c.config.UsesToken() {
 		return nil // Token auth doesn't need ticket
 	}
 
-	// Use password authentication
-	apiURL := fmt.Sprintf("https://%s:%d/api2/json/access/ticket", c.config.Host, c.config.Port)
+	endpoint := c.endpoints.current()
+	err := c.authenticateTo(context.Background(), endpoint)
+	var unreachable *endpointError
+	if errors.As(err, &unreachable) {
+		// failover re-authenticates against the new endpoint
+		if _, ferr := c.failover(endpoint); ferr == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// authenticateTo requests a new ticket from the given endpoint using password authentication
+func (c *ProxmoxCollector) authenticateTo(ctx context.Context, endpoint string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Re-read the password in case it was rotated
+	password, err := c.secret.Get()
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	apiURL := fmt.Sprintf("https://%s/api2/json/access/ticket", endpoint)
 
 	data := url.Values{}
 	data.Set("username", c.config.User)
-	data.Set("password", c.config.Password)
+	data.Set("password", string(password))
 
-	resp, err := c.client.PostForm(apiURL, data)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(data.Encode()))
 	if err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &endpointError{err: fmt.Errorf("authentication failed: %w", err)}
 	}
 	defer func() { _ = resp.Body.Close() }()
 
@@ -52,28 +79,48 @@ func (c *ProxmoxCollector) authenticate() error {
 	return nil
 }
 
-// apiRequest makes an authenticated API request
+// apiRequest makes an authenticated API request, failing over to another endpoint if the current one is unreachable
 func (c *ProxmoxCollector) apiRequest(path string) ([]byte, error) {
-	apiURL := fmt.Sprintf("https://%s:%d/api2/json%s", c.config.Host, c.config.Port, path)
+	endpoint := c.endpoints.current()
+	body, err := c.apiRequestTo(context.Background(), endpoint, path)
+	var unreachable *endpointError
+	if !errors.As(err, &unreachable) {
+		return body, err
+	}
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	next, ferr := c.failover(endpoint)
+	if ferr != nil {
+		return nil, err
+	}
+	return c.apiRequestTo(context.Background(), next, path)
+}
+
+// apiRequestTo makes an authenticated API request against a specific endpoint
+func (c *ProxmoxCollector) apiRequestTo(ctx context.Context, endpoint, path string) ([]byte, error) {
+	apiURL := fmt.Sprintf("https://%s/api2/json%s", endpoint, path)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add authentication
-	c.mutex.RLock()
-	if c.config.TokenID != "" && c.config.TokenSecret != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", c.config.TokenID, c.config.TokenSecret))
+	if c.config.UsesToken() {
+		secret, err := c.secret.Get()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", c.config.TokenID, string(secret)))
 	} else {
+		c.mutex.RLock()
 		req.Header.Set("Cookie", fmt.Sprintf("PVEAuthCookie=%s", c.ticket))
 		req.Header.Set("CSRFPreventionToken", c.csrf)
+		c.mutex.RUnlock()
 	}
-	c.mutex.RUnlock()
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, &endpointError{err: err}
 	}
 	defer func() { _ = resp.Body.Close() }()
 
@@ -0,0 +1,269 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// rrdTimeframes are the PVE RRD timeframes from finest to coarsest resolution,
+// with the history each one covers
+var rrdTimeframes = []struct {
+	Name string
+	Span time.Duration
+}{
+	{"hour", time.Hour},
+	{"day", 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"year", 365 * 24 * time.Hour},
+}
+
+// backfillDesc is a gauge descriptor defined in NewProxmoxCollector, with the name and help
+// text written to the OpenMetrics family
+type backfillDesc struct {
+	desc *prometheus.Desc
+	name string
+	help string
+}
+
+// rrdMapping maps RRD data sources to the gauges they are backfilled as
+type rrdMapping map[string]backfillDesc
+
+// backfillWriter accumulates timestamped samples grouped by metric family
+type backfillWriter struct {
+	families map[string]*dto.MetricFamily
+}
+
+// add appends RRD samples of all mapped data sources as timestamped gauge samples
+func (b *backfillWriter) add(mapping rrdMapping, series map[string][]rrdSample, labels ...string) {
+	for key, d := range mapping {
+		samples, ok := series[key]
+		if !ok {
+			continue
+		}
+
+		family, ok := b.families[d.name]
+		if !ok {
+			name, help := d.name, d.help
+			family = &dto.MetricFamily{Name: &name, Help: &help, Type: dto.MetricType_GAUGE.Enum()}
+			b.families[d.name] = family
+		}
+
+		for _, sample := range samples {
+			pb := &dto.Metric{}
+			if err := prometheus.MustNewConstMetric(d.desc, prometheus.GaugeValue, sample.Value, labels...).Write(pb); err != nil {
+				continue
+			}
+			ts := int64(sample.Time) * 1000
+			pb.TimestampMs = &ts
+			family.Metric = append(family.Metric, pb)
+		}
+	}
+}
+
+// writeTo writes all families as OpenMetrics, sorted by name
+func (b *backfillWriter) writeTo(w io.Writer) error {
+	names := make([]string, 0, len(b.families))
+	for name := range b.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := expfmt.MetricFamilyToOpenMetrics(w, b.families[name]); err != nil {
+			return err
+		}
+	}
+	_, err := expfmt.FinalizeOpenMetrics(w)
+	return err
+}
+
+// fetchRRDHistory fetches RRD data for every timeframe needed to cover the window since start.
+// Finer timeframes take precedence, coarser ones only fill in older history.
+func (c *ProxmoxCollector) fetchRRDHistory(path string, start time.Time) (map[string][]rrdSample, error) {
+	merged := make(map[string][]rrdSample)
+	since := time.Since(start)
+	cutoff := math.Inf(1)
+
+	for _, tf := range rrdTimeframes {
+		data, err := c.apiRequest(fmt.Sprintf("%s?timeframe=%s&cf=AVERAGE", path, tf.Name))
+		if err != nil {
+			return nil, err
+		}
+		series, err := parseRRDSeries(data)
+		if err != nil {
+			return nil, err
+		}
+
+		earliest := cutoff
+		for key, samples := range series {
+			var older []rrdSample
+			for _, sample := range samples {
+				if sample.Time < cutoff && sample.Time >= float64(start.Unix()) {
+					older = append(older, sample)
+				}
+				earliest = math.Min(earliest, sample.Time)
+			}
+			merged[key] = append(older, merged[key]...)
+		}
+		cutoff = earliest
+
+		if tf.Span >= since {
+			break
+		}
+	}
+	return merged, nil
+}
+
+// Backfill writes historical node, guest and storage metrics from PVE RRD data as OpenMetrics,
+// suitable for "promtool tsdb create-blocks-from openmetrics". Only gauges are exported,
+// because RRD stores rates and averages rather than the raw counters.
+func (c *ProxmoxCollector) Backfill(w io.Writer, since time.Duration) error {
+	if err := c.authenticate(); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	_, nodes, err := c.fetchNodes()
+	if err != nil {
+		return fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+
+	start := time.Now().Add(-since)
+	b := &backfillWriter{families: make(map[string]*dto.MetricFamily)}
+
+	for _, node := range nodes {
+		labels := append([]string{node}, c.labels.nodeValues(node)...)
+		c.backfillTarget(b, fmt.Sprintf("/nodes/%s/rrddata", node), start, c.nodeRRDMapping(), labels...)
+	}
+
+	configs := c.newGuestConfigCache()
+	// Only fully collected guests have live CPU and memory metrics; light and excluded ones are skipped
+	for vmid, guest := range c.filter.detailedGuests(c.fetchGuests(nodes)) {
+		labels := append([]string{guest.Node, vmid, guest.Name}, c.guestLabelValues(vmid, guest, configs)...)
+		c.backfillGuest(b, start, vmid, guest, labels)
+	}
+
+	// Shared storages are backfilled for every node, like the storage collector exports them
+	for _, node := range nodes {
+		for _, s := range c.fetchNodeContentStorages(node) {
+			path := fmt.Sprintf("/nodes/%s/storage/%s/rrddata", node, url.PathEscape(s.Storage))
+			c.backfillTarget(b, path, start, c.storageRRDMapping(), node, s.Storage, s.Type)
+		}
+	}
+
+	return b.writeTo(w)
+}
+
+// backfillTarget fetches the RRD history of a single node, guest or storage and adds it to the writer
+func (c *ProxmoxCollector) backfillTarget(b *backfillWriter, path string, start time.Time, mapping rrdMapping, labels ...string) {
+	series, err := c.fetchRRDHistory(path, start)
+	if err != nil {
+		log.Printf("Error fetching RRD history from %s: %v", path, err)
+		return
+	}
+	b.add(mapping, series, labels...)
+}
+
+// nodeRRDMapping maps node RRD data sources to node metrics
+func (c *ProxmoxCollector) nodeRRDMapping() rrdMapping {
+	return rrdMapping{
+		"cpu":       {c.nodeCPULoad, "pve_node_cpu_load", "Node CPU load"},
+		"maxcpu":    {c.nodeCPUs, "pve_node_cpus_total", "Total number of CPUs"},
+		"iowait":    {c.nodeIOWait, "pve_node_iowait", "Node I/O wait ratio"},
+		"loadavg":   {c.nodeLoad1, "pve_node_load1", "Node load average 1 minute"},
+		"memtotal":  {c.nodeMemoryTotal, "pve_node_memory_total_bytes", "Total memory in bytes"},
+		"memused":   {c.nodeMemoryUsed, "pve_node_memory_used_bytes", "Used memory in bytes"},
+		"swaptotal": {c.nodeSwapTotal, "pve_node_swap_total_bytes", "Total swap in bytes"},
+		"swapused":  {c.nodeSwapUsed, "pve_node_swap_used_bytes", "Used swap in bytes"},
+		"roottotal": {c.nodeRootfsTotal, "pve_node_rootfs_total_bytes", "Node root filesystem total size in bytes"},
+		"rootused":  {c.nodeRootfsUsed, "pve_node_rootfs_used_bytes", "Node root filesystem used in bytes"},
+		"netin": {c.nodeRRDNetIn, "pve_node_rrd_network_receive_bytes_per_second",
+			"Node network receive rate in bytes per second from the latest RRD sample"},
+		"netout": {c.nodeRRDNetOut, "pve_node_rrd_network_transmit_bytes_per_second",
+			"Node network transmit rate in bytes per second from the latest RRD sample"},
+	}
+}
+
+// guestBackfillDescs holds the legacy VM and LXC gauges and the unified guest gauge of one RRD data source
+type guestBackfillDescs struct {
+	qemu, lxc, unified backfillDesc
+}
+
+// guestRRDDescs maps guest RRD data sources to the metrics shared by VMs and containers
+func (c *ProxmoxCollector) guestRRDDescs() map[string]guestBackfillDescs {
+	return map[string]guestBackfillDescs{
+		"cpu": {
+			qemu:    backfillDesc{c.vmCPU, "pve_vm_cpu_usage", "VM CPU usage"},
+			lxc:     backfillDesc{c.lxcCPU, "pve_lxc_cpu_usage", "LXC CPU usage"},
+			unified: backfillDesc{c.guestCPU, "pve_guest_cpu_usage", "Guest CPU usage"},
+		},
+		"maxcpu": {
+			qemu:    backfillDesc{c.vmCPUs, "pve_vm_cpus", "Number of CPUs allocated to VM"},
+			lxc:     backfillDesc{c.lxcCPUs, "pve_lxc_cpus", "Number of CPUs allocated to LXC"},
+			unified: backfillDesc{c.guestCPUs, "pve_guest_cpus", "Number of CPUs allocated to guest"},
+		},
+		"mem": {
+			qemu:    backfillDesc{c.vmMemory, "pve_vm_memory_used_bytes", "VM memory usage in bytes"},
+			lxc:     backfillDesc{c.lxcMemory, "pve_lxc_memory_used_bytes", "LXC memory usage in bytes"},
+			unified: backfillDesc{c.guestMemory, "pve_guest_memory_used_bytes", "Guest memory usage in bytes"},
+		},
+		"maxmem": {
+			qemu:    backfillDesc{c.vmMaxMemory, "pve_vm_memory_max_bytes", "VM maximum memory in bytes"},
+			lxc:     backfillDesc{c.lxcMaxMemory, "pve_lxc_memory_max_bytes", "LXC maximum memory in bytes"},
+			unified: backfillDesc{c.guestMaxMemory, "pve_guest_memory_max_bytes", "Guest maximum memory in bytes"},
+		},
+		"maxdisk": {
+			qemu:    backfillDesc{c.vmMaxDisk, "pve_vm_disk_max_bytes", "VM maximum disk in bytes"},
+			lxc:     backfillDesc{c.lxcMaxDisk, "pve_lxc_disk_max_bytes", "LXC maximum disk in bytes"},
+			unified: backfillDesc{c.guestMaxDisk, "pve_guest_disk_max_bytes", "Guest maximum disk in bytes"},
+		},
+	}
+}
+
+// backfillGuest fetches the RRD history of a guest and adds it under the configured naming scheme,
+// like emitGuestMetric does for live metrics. labels are node, vmid, name and any extra labels.
+func (c *ProxmoxCollector) backfillGuest(b *backfillWriter, start time.Time, vmid string, guest GuestInfo, labels []string) {
+	path := fmt.Sprintf("/nodes/%s/%s/%s/rrddata", guest.Node, guest.Type, vmid)
+	series, err := c.fetchRRDHistory(path, start)
+	if err != nil {
+		log.Printf("Error fetching RRD history from %s: %v", path, err)
+		return
+	}
+
+	legacy := rrdMapping{}
+	if guest.Type == "lxc" {
+		legacy["disk"] = backfillDesc{c.lxcDisk, "pve_lxc_disk_used_bytes", "LXC disk usage in bytes"} // exported for containers under every naming scheme
+	}
+	unified := rrdMapping{}
+	for key, d := range c.guestRRDDescs() {
+		if c.naming != config.NamingUnified {
+			legacy[key] = d.qemu
+			if guest.Type == "lxc" {
+				legacy[key] = d.lxc
+			}
+		}
+		if c.naming == config.NamingUnified || c.naming == config.NamingBoth {
+			unified[key] = d.unified
+		}
+	}
+	b.add(legacy, series, labels...)
+	b.add(unified, series, append(append([]string{}, labels...), guest.Type)...)
+}
+
+// storageRRDMapping maps storage RRD data sources to storage metrics
+func (c *ProxmoxCollector) storageRRDMapping() rrdMapping {
+	return rrdMapping{
+		"total": {c.storageTotal, "pve_storage_total_bytes", "Total storage size in bytes"},
+		"used":  {c.storageUsed, "pve_storage_used_bytes", "Used storage in bytes"},
+	}
+}
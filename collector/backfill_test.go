@@ -0,0 +1,220 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+func TestBackfillDescs(t *testing.T) {
+	c := NewProxmoxCollector(&config.Config{Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"}})
+
+	var descs []backfillDesc
+	for _, mapping := range []rrdMapping{c.nodeRRDMapping(), c.storageRRDMapping()} {
+		for _, d := range mapping {
+			descs = append(descs, d)
+		}
+	}
+	for _, d := range c.guestRRDDescs() {
+		descs = append(descs, d.qemu, d.lxc, d.unified)
+	}
+
+	// The name and help written to OpenMetrics must match the live descriptor
+	for _, d := range descs {
+		want := fmt.Sprintf("fqName: %s, help: %s,", strconv.Quote(d.name), strconv.Quote(d.help))
+		if !strings.Contains(d.desc.String(), want) {
+			t.Errorf("%s does not match descriptor %s", want, d.desc)
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	now := time.Now().Truncate(time.Minute).Unix()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]interface{}{{"node": "pve1"}}))
+	mux.HandleFunc("/api2/json/cluster/resources", jsonHandler([]map[string]interface{}{
+		{"vmid": 100, "node": "pve1", "name": "web", "type": "qemu"},
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/storage", jsonHandler([]map[string]interface{}{}))
+	mux.HandleFunc("/api2/json/nodes/pve1/rrddata", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("timeframe") != "hour" {
+			t.Errorf("unexpected timeframe %q for a 30m backfill", r.URL.Query().Get("timeframe"))
+		}
+		jsonHandler([]map[string]interface{}{
+			{"time": now - 120, "cpu": 0.1, "memused": 1024},
+			{"time": now - 60, "cpu": 0.2, "memused": 2048},
+			{"time": now},
+		})(w, r)
+	})
+	mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/rrddata", jsonHandler([]map[string]interface{}{
+		{"time": now - 60, "cpu": 0.5, "maxmem": 4096, "netin": 100},
+	}))
+
+	c := newTestCollector(t, mux)
+
+	var buf bytes.Buffer
+	if err := c.Backfill(&buf, 30*time.Minute); err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE pve_node_cpu_load gauge",
+		`pve_node_cpu_load{node="pve1"} 0.1 `,
+		`pve_node_cpu_load{node="pve1"} 0.2 `,
+		`pve_node_memory_used_bytes{node="pve1"} 2048.0 `,
+		`pve_vm_cpu_usage{name="web",node="pve1",vmid="100"} 0.5 `,
+		`pve_vm_memory_max_bytes{name="web",node="pve1",vmid="100"} 4096.0 `,
+		"# EOF",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	// Network rates are not counters and must not be backfilled as guest counters
+	if strings.Contains(out, "pve_vm_network_in_bytes_total") {
+		t.Error("unexpected guest network counter in backfill output")
+	}
+}
+
+func TestBackfillNaming(t *testing.T) {
+	now := time.Now().Truncate(time.Minute).Unix()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]interface{}{{"node": "pve1"}}))
+	mux.HandleFunc("/api2/json/cluster/resources", jsonHandler([]map[string]interface{}{
+		{"vmid": 100, "node": "pve1", "name": "web", "type": "qemu"},
+		{"vmid": 200, "node": "pve1", "name": "dns", "type": "lxc"},
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/storage", jsonHandler([]map[string]interface{}{}))
+	mux.HandleFunc("/api2/json/nodes/pve1/rrddata", jsonHandler([]map[string]interface{}{}))
+	mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/rrddata", jsonHandler([]map[string]interface{}{
+		{"time": now - 60, "cpu": 0.5},
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/lxc/200/rrddata", jsonHandler([]map[string]interface{}{
+		{"time": now - 60, "cpu": 0.25, "disk": 1024},
+	}))
+
+	legacy := []string{
+		`pve_vm_cpu_usage{name="web",node="pve1",vmid="100"} 0.5 `,
+		`pve_lxc_cpu_usage{name="dns",node="pve1",vmid="200"} 0.25 `,
+	}
+	unified := []string{
+		`pve_guest_cpu_usage{name="web",node="pve1",type="qemu",vmid="100"} 0.5 `,
+		`pve_guest_cpu_usage{name="dns",node="pve1",type="lxc",vmid="200"} 0.25 `,
+	}
+	tests := []struct {
+		naming  string
+		want    []string
+		notWant []string
+	}{
+		{config.NamingLegacy, legacy, unified},
+		{config.NamingUnified, unified, legacy},
+		{config.NamingBoth, append(append([]string{}, legacy...), unified...), nil},
+	}
+
+	c := newTestCollector(t, mux)
+	for _, tt := range tests {
+		t.Run(tt.naming, func(t *testing.T) {
+			c.naming = tt.naming
+			var buf bytes.Buffer
+			if err := c.Backfill(&buf, 30*time.Minute); err != nil {
+				t.Fatalf("Backfill failed: %v", err)
+			}
+			out := buf.String()
+
+			// Container disk usage has no unified counterpart and is kept under every scheme
+			want := append(append([]string{}, tt.want...), `pve_lxc_disk_used_bytes{name="dns",node="pve1",vmid="200"} 1024.0 `)
+			for _, w := range want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output missing %q:\n%s", w, out)
+				}
+			}
+			for _, nw := range tt.notWant {
+				if strings.Contains(out, nw) {
+					t.Errorf("unexpected %q in output", nw)
+				}
+			}
+		})
+	}
+}
+
+func TestBackfillSharedStorage(t *testing.T) {
+	now := time.Now().Truncate(time.Minute).Unix()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]interface{}{{"node": "pve1"}, {"node": "pve2"}}))
+	mux.HandleFunc("/api2/json/cluster/resources", jsonHandler([]map[string]interface{}{}))
+	for _, node := range []string{"pve1", "pve2"} {
+		mux.HandleFunc("/api2/json/nodes/"+node+"/rrddata", jsonHandler([]map[string]interface{}{}))
+		mux.HandleFunc("/api2/json/nodes/"+node+"/storage", jsonHandler([]map[string]interface{}{
+			{"storage": "ceph", "type": "rbd", "content": "images", "active": 1, "shared": 1},
+		}))
+		mux.HandleFunc("/api2/json/nodes/"+node+"/storage/ceph/rrddata", jsonHandler([]map[string]interface{}{
+			{"time": now - 60, "total": 1000, "used": 250},
+		}))
+	}
+
+	c := newTestCollector(t, mux)
+	var buf bytes.Buffer
+	if err := c.Backfill(&buf, 30*time.Minute); err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, node := range []string{"pve1", "pve2"} {
+		want := `pve_storage_used_bytes{node="` + node + `",storage="ceph",type="rbd"} 250.0 `
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestBackfillFilter(t *testing.T) {
+	now := time.Now().Truncate(time.Minute).Unix()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]interface{}{{"node": "pve1"}}))
+	mux.HandleFunc("/api2/json/cluster/resources", jsonHandler([]map[string]interface{}{
+		{"vmid": 100, "node": "pve1", "name": "web", "type": "qemu"},
+		{"vmid": 101, "node": "pve1", "name": "ci-runner", "type": "qemu"},
+		{"vmid": 102, "node": "pve1", "name": "scratch", "type": "qemu", "tags": "ephemeral"},
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/storage", jsonHandler([]map[string]interface{}{}))
+	mux.HandleFunc("/api2/json/nodes/pve1/rrddata", jsonHandler([]map[string]interface{}{}))
+	for _, vmid := range []string{"100", "101", "102"} {
+		mux.HandleFunc("/api2/json/nodes/pve1/qemu/"+vmid+"/rrddata", jsonHandler([]map[string]interface{}{
+			{"time": now - 60, "cpu": 0.5},
+		}))
+	}
+
+	c := newTestCollector(t, mux)
+	c.filter = mustGuestFilter(t, config.FiltersConfig{
+		Exclude: []config.GuestFilterRule{{Name: "^ci-"}},
+		Light:   []config.GuestFilterRule{{Tags: []string{"ephemeral"}}},
+	})
+
+	var buf bytes.Buffer
+	if err := c.Backfill(&buf, 30*time.Minute); err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, `vmid="100"`) {
+		t.Errorf("output missing regular guest:\n%s", out)
+	}
+	// Excluded guests export nothing and light guests only their status, so neither is backfilled
+	for _, vmid := range []string{"101", "102"} {
+		if strings.Contains(out, `vmid="`+vmid+`"`) {
+			t.Errorf("filtered guest %s was backfilled:\n%s", vmid, out)
+		}
+	}
+}
@@ -27,13 +27,9 @@ type batchJob struct {
 
 // collectBackupMetricsWithGuests collects last backup timestamps for VMs and LXC containers
 // OPTIMIZATION #2: Uses pre-fetched guest data from /cluster/resources to avoid duplicate API calls
+// (Collect falls back to per-node guest lists when /cluster/resources fails)
 // Also optimized with: parallel log fetches, early exit, dynamic log limits
-func (c *ProxmoxCollector) collectBackupMetricsWithGuests(ch chan<- prometheus.Metric, nodes []string, guests map[string]GuestInfo) {
-	// If no guests were passed (API failed), fall back to fetching ourselves
-	if len(guests) == 0 {
-		c.fetchGuestsFallback(nodes, guests)
-	}
-
+func (c *ProxmoxCollector) collectBackupMetricsWithGuests(ch chan<- prometheus.Metric, nodes []string, guests map[string]GuestInfo, configs *guestConfigCache) {
 	// Now collect backup tasks and find latest successful backup per VMID
 	backups := make(map[string]int64) // key: vmid, value: endtime timestamp
 	var backupsMutex sync.Mutex
@@ -52,7 +48,7 @@ func (c *ProxmoxCollector) collectBackupMetricsWithGuests(ch chan<- prometheus.M
 	wg.Wait()
 
 	// Emit metrics for each guest with a backup
-	c.emitBackupMetrics(ch, backups, guests)
+	c.emitBackupMetrics(ch, backups, guests, configs)
 }
 
 // fetchGuestsFallback fetches guest info when /cluster/resources failed
@@ -76,15 +72,18 @@ func (c *ProxmoxCollector) fetchNodeGuests(nodeName string, guests map[string]Gu
 	if err == nil {
 		var vmResult struct {
 			Data []struct {
-				VMID int64  `json:"vmid"`
-				Name string `json:"name"`
+				VMID   int64   `json:"vmid"`
+				Name   string  `json:"name"`
+				Status string  `json:"status"`
+				CPUs   float64 `json:"cpus"`
+				MaxMem float64 `json:"maxmem"`
 			} `json:"data"`
 		}
 		if json.Unmarshal(vmData, &vmResult) == nil {
 			mu.Lock()
 			for _, vm := range vmResult.Data {
 				vmid := strconv.FormatInt(vm.VMID, 10)
-				guests[vmid] = GuestInfo{Node: nodeName, Name: vm.Name, Type: "qemu"}
+				guests[vmid] = GuestInfo{Node: nodeName, Name: vm.Name, Type: "qemu", Status: vm.Status, MaxCPU: vm.CPUs, MaxMem: vm.MaxMem}
 			}
 			mu.Unlock()
 		}
@@ -95,15 +94,18 @@ func (c *ProxmoxCollector) fetchNodeGuests(nodeName string, guests map[string]Gu
 	if err == nil {
 		var lxcResult struct {
 			Data []struct {
-				VMID int64  `json:"vmid"`
-				Name string `json:"name"`
+				VMID   int64   `json:"vmid"`
+				Name   string  `json:"name"`
+				Status string  `json:"status"`
+				CPUs   float64 `json:"cpus"`
+				MaxMem float64 `json:"maxmem"`
 			} `json:"data"`
 		}
 		if json.Unmarshal(lxcData, &lxcResult) == nil {
 			mu.Lock()
 			for _, lxc := range lxcResult.Data {
 				vmid := strconv.FormatInt(lxc.VMID, 10)
-				guests[vmid] = GuestInfo{Node: nodeName, Name: lxc.Name, Type: "lxc"}
+				guests[vmid] = GuestInfo{Node: nodeName, Name: lxc.Name, Type: "lxc", Status: lxc.Status, MaxCPU: lxc.CPUs, MaxMem: lxc.MaxMem}
 			}
 			mu.Unlock()
 		}
@@ -238,17 +240,17 @@ func (c *ProxmoxCollector) parseBackupLog(nodeName, upid string, totalGuests int
 }
 
 // emitBackupMetrics emits Prometheus metrics for backup timestamps
-func (c *ProxmoxCollector) emitBackupMetrics(ch chan<- prometheus.Metric, backups map[string]int64, guests map[string]GuestInfo) {
+func (c *ProxmoxCollector) emitBackupMetrics(ch chan<- prometheus.Metric, backups map[string]int64, guests map[string]GuestInfo, configs *guestConfigCache) {
 	for vmid, endtime := range backups {
 		guest, ok := guests[vmid]
 		if !ok {
 			continue // Skip if we don't have guest info (maybe deleted)
 		}
-		labels := []string{guest.Node, vmid, guest.Name}
-		if guest.Type == "qemu" {
-			ch <- prometheus.MustNewConstMetric(c.vmLastBackup, prometheus.GaugeValue, float64(endtime), labels...)
-		} else {
-			ch <- prometheus.MustNewConstMetric(c.lxcLastBackup, prometheus.GaugeValue, float64(endtime), labels...)
+		// Filtered guests still count towards the early exit above, but emit no backup series
+		if c.filter.classify(vmid, guest) != filterFull {
+			continue
 		}
+		labels := append([]string{guest.Node, vmid, guest.Name}, c.guestLabelValues(vmid, guest, configs)...)
+		c.emitGuestMetric(ch, guestDescs{c.vmLastBackup, c.lxcLastBackup, c.guestLastBackup}, prometheus.GaugeValue, float64(endtime), guest.Type, labels)
 	}
 }
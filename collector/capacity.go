@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// capacityNode holds the physical resources of a node and the resources allocated to its running guests
+type capacityNode struct {
+	Name         string
+	Online       bool
+	CPUs         float64
+	Memory       float64
+	MemoryUsed   float64
+	AllocatedCPU float64
+	AllocatedMem float64
+	HAMemory     []float64 // allocated memory of running HA-managed guests
+}
+
+// n1Result holds the outcome of simulating the loss of a single node
+type n1Result struct {
+	FailedNode string
+	Headroom   float64 // remaining nodes' free memory minus the failed node's HA memory
+	Fits       bool    // every HA guest could be placed on one of the remaining nodes
+}
+
+// buildCapacityNodes combines the nodes list with running guest allocations
+func buildCapacityNodes(nodesData []byte, guests map[string]GuestInfo) ([]*capacityNode, error) {
+	var result struct {
+		Data []struct {
+			Node   string  `json:"node"`
+			Status string  `json:"status"`
+			MaxCPU float64 `json:"maxcpu"`
+			Mem    float64 `json:"mem"`
+			MaxMem float64 `json:"maxmem"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(nodesData, &result); err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*capacityNode, 0, len(result.Data))
+	byName := make(map[string]*capacityNode, len(result.Data))
+	for _, n := range result.Data {
+		node := &capacityNode{
+			Name:       n.Node,
+			Online:     n.Status == "online",
+			CPUs:       n.MaxCPU,
+			Memory:     n.MaxMem,
+			MemoryUsed: n.Mem,
+		}
+		nodes = append(nodes, node)
+		byName[n.Node] = node
+	}
+
+	for _, guest := range guests {
+		node, ok := byName[guest.Node]
+		if !ok || guest.Status != "running" {
+			continue
+		}
+		node.AllocatedCPU += guest.MaxCPU
+		node.AllocatedMem += guest.MaxMem
+		if guest.HAState != "" {
+			node.HAMemory = append(node.HAMemory, guest.MaxMem)
+		}
+	}
+	return nodes, nil
+}
+
+// simulateN1 removes the online node with the most HA-managed memory and places its HA guests
+// on the remaining online nodes' free memory using first-fit decreasing
+func simulateN1(nodes []*capacityNode) (n1Result, bool) {
+	var failed *capacityNode
+	failedMem := 0.0
+	for _, n := range nodes {
+		if !n.Online {
+			continue
+		}
+		if mem := sum(n.HAMemory); failed == nil || mem > failedMem {
+			failed, failedMem = n, mem
+		}
+	}
+	if failed == nil {
+		return n1Result{}, false
+	}
+
+	var free []float64
+	for _, n := range nodes {
+		if n.Online && n != failed {
+			free = append(free, n.Memory-n.MemoryUsed)
+		}
+	}
+
+	result := n1Result{FailedNode: failed.Name, Headroom: sum(free) - failedMem, Fits: true}
+
+	demand := append([]float64(nil), failed.HAMemory...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(demand)))
+	for _, mem := range demand {
+		placed := false
+		for i := range free {
+			if free[i] >= mem {
+				free[i] -= mem
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			result.Fits = false
+		}
+	}
+	return result, true
+}
+
+// sum returns the sum of values
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// collectCapacityMetrics exports overcommit ratios, allocated resources and N+1 headroom
+func (c *ProxmoxCollector) collectCapacityMetrics(ch chan<- prometheus.Metric, nodesData []byte, guests map[string]GuestInfo) {
+	nodes, err := buildCapacityNodes(nodesData, guests)
+	if err != nil {
+		log.Printf("Error unmarshaling nodes data for capacity metrics: %v", err)
+		return
+	}
+
+	var cpus, memory, allocCPU, allocMem float64
+	for _, n := range nodes {
+		ch <- prometheus.MustNewConstMetric(c.nodeCPUAllocated, prometheus.GaugeValue, n.AllocatedCPU, n.Name)
+		ch <- prometheus.MustNewConstMetric(c.nodeMemoryAllocated, prometheus.GaugeValue, n.AllocatedMem, n.Name)
+		if n.CPUs > 0 {
+			ch <- prometheus.MustNewConstMetric(c.nodeCPUOvercommit, prometheus.GaugeValue, n.AllocatedCPU/n.CPUs, n.Name)
+		}
+		if n.Memory > 0 {
+			ch <- prometheus.MustNewConstMetric(c.nodeMemoryOvercommit, prometheus.GaugeValue, n.AllocatedMem/n.Memory, n.Name)
+		}
+
+		// Offline nodes contribute no physical capacity to the cluster
+		if n.Online {
+			cpus += n.CPUs
+			memory += n.Memory
+		}
+		allocCPU += n.AllocatedCPU
+		allocMem += n.AllocatedMem
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.clusterCPUAllocated, prometheus.GaugeValue, allocCPU)
+	ch <- prometheus.MustNewConstMetric(c.clusterMemoryAllocated, prometheus.GaugeValue, allocMem)
+	ch <- prometheus.MustNewConstMetric(c.clusterCPUTotal, prometheus.GaugeValue, cpus)
+	ch <- prometheus.MustNewConstMetric(c.clusterMemoryTotal, prometheus.GaugeValue, memory)
+	if cpus > 0 {
+		ch <- prometheus.MustNewConstMetric(c.clusterCPUOvercommit, prometheus.GaugeValue, allocCPU/cpus)
+	}
+	if memory > 0 {
+		ch <- prometheus.MustNewConstMetric(c.clusterMemoryOvercommit, prometheus.GaugeValue, allocMem/memory)
+	}
+
+	if n1, ok := simulateN1(nodes); ok {
+		fits := 0.0
+		if n1.Fits {
+			fits = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(c.clusterN1Headroom, prometheus.GaugeValue, n1.Headroom, n1.FailedNode)
+		ch <- prometheus.MustNewConstMetric(c.clusterN1Fits, prometheus.GaugeValue, fits, n1.FailedNode)
+	}
+}
@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const capacityNodesData = `{"data":[
+	{"node":"pve1","status":"online","maxcpu":8,"mem":20,"maxmem":64},
+	{"node":"pve2","status":"online","maxcpu":8,"mem":40,"maxmem":64},
+	{"node":"pve3","status":"offline","maxcpu":8,"mem":0,"maxmem":64}
+]}`
+
+func TestBuildCapacityNodes(t *testing.T) {
+	guests := map[string]GuestInfo{
+		"100": {Node: "pve1", Status: "running", MaxCPU: 4, MaxMem: 16, HAState: "started"},
+		"101": {Node: "pve1", Status: "running", MaxCPU: 8, MaxMem: 8},
+		"102": {Node: "pve1", Status: "stopped", MaxCPU: 16, MaxMem: 32},
+		"200": {Node: "pve2", Status: "running", MaxCPU: 2, MaxMem: 4, HAState: "started"},
+	}
+
+	nodes, err := buildCapacityNodes([]byte(capacityNodesData), guests)
+	if err != nil {
+		t.Fatalf("buildCapacityNodes failed: %v", err)
+	}
+	if len(nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %d", len(nodes))
+	}
+
+	pve1 := nodes[0]
+	if pve1.AllocatedCPU != 12 || pve1.AllocatedMem != 24 {
+		t.Errorf("expected pve1 allocation 12 CPU / 24 mem, got %v / %v", pve1.AllocatedCPU, pve1.AllocatedMem)
+	}
+	if len(pve1.HAMemory) != 1 || pve1.HAMemory[0] != 16 {
+		t.Errorf("expected one HA guest with 16 memory on pve1, got %v", pve1.HAMemory)
+	}
+}
+
+func TestSimulateN1(t *testing.T) {
+	tests := []struct {
+		name         string
+		nodes        []*capacityNode
+		wantFailed   string
+		wantHeadroom float64
+		wantFits     bool
+	}{
+		{
+			name: "fits",
+			nodes: []*capacityNode{
+				{Name: "pve1", Online: true, Memory: 64, MemoryUsed: 40, HAMemory: []float64{16, 8}},
+				{Name: "pve2", Online: true, Memory: 64, MemoryUsed: 32, HAMemory: []float64{4}},
+				{Name: "pve3", Online: false, Memory: 64, HAMemory: []float64{32}},
+			},
+			wantFailed:   "pve1",
+			wantHeadroom: 8,
+			wantFits:     true,
+		},
+		{
+			name: "fragmented free memory",
+			nodes: []*capacityNode{
+				{Name: "pve1", Online: true, Memory: 64, MemoryUsed: 40, HAMemory: []float64{24}},
+				{Name: "pve2", Online: true, Memory: 64, MemoryUsed: 48},
+				{Name: "pve3", Online: true, Memory: 64, MemoryUsed: 48},
+			},
+			wantFailed:   "pve1",
+			wantHeadroom: 8,
+			wantFits:     false,
+		},
+		{
+			name: "insufficient memory",
+			nodes: []*capacityNode{
+				{Name: "pve1", Online: true, Memory: 64, MemoryUsed: 60, HAMemory: []float64{32}},
+				{Name: "pve2", Online: true, Memory: 64, MemoryUsed: 48},
+			},
+			wantFailed:   "pve1",
+			wantHeadroom: -16,
+			wantFits:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := simulateN1(tt.nodes)
+			if !ok {
+				t.Fatal("expected a simulation result")
+			}
+			if got.FailedNode != tt.wantFailed || got.Headroom != tt.wantHeadroom || got.Fits != tt.wantFits {
+				t.Errorf("simulateN1() = %+v, want failed=%s headroom=%v fits=%v",
+					got, tt.wantFailed, tt.wantHeadroom, tt.wantFits)
+			}
+		})
+	}
+
+	if _, ok := simulateN1([]*capacityNode{{Name: "pve1", Online: false}}); ok {
+		t.Error("expected no result without online nodes")
+	}
+}
+
+func TestCollectCapacityMetrics(t *testing.T) {
+	c := NewProxmoxCollector(&config.Config{Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"}})
+	guests := map[string]GuestInfo{
+		"100": {Node: "pve1", Status: "running", MaxCPU: 12, MaxMem: 32, HAState: "started"},
+		"200": {Node: "pve2", Status: "running", MaxCPU: 4, MaxMem: 16},
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	c.collectCapacityMetrics(ch, []byte(capacityNodesData), guests)
+	close(ch)
+
+	values := make(map[*prometheus.Desc]float64)
+	for m := range ch {
+		if len(metricLabels(m)) == 0 {
+			values[m.Desc()] = getMetricValue(m)
+		}
+	}
+
+	// Offline pve3 contributes no physical capacity
+	if got := values[c.clusterCPUOvercommit]; got != 1 {
+		t.Errorf("expected cluster CPU overcommit 1, got %v", got)
+	}
+	if got := values[c.clusterMemoryTotal]; got != 128 {
+		t.Errorf("expected cluster memory total 128, got %v", got)
+	}
+}
@@ -0,0 +1,309 @@
+package collector
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAccrualGap caps the time charged between two scrapes, so exporter downtime is not billed
+// at whatever rate was last observed
+const maxAccrualGap = 15 * time.Minute
+
+// ledgerSaveInterval limits how often the ledger file is rewritten; flush writes pending changes on shutdown
+const ledgerSaveInterval = 5 * time.Minute
+
+// chargebackEntry holds the cost accumulated by a guest within one month
+type chargebackEntry struct {
+	VMID         string  `json:"vmid"`
+	Name         string  `json:"name"`
+	Node         string  `json:"node"`
+	Type         string  `json:"type"`
+	Pool         string  `json:"pool"`
+	Tags         string  `json:"tags"`
+	RunningHours float64 `json:"running_hours"`
+	CPU          float64 `json:"cpu"`
+	Memory       float64 `json:"memory"`
+	Storage      float64 `json:"storage"`
+}
+
+// costLedger accumulates guest costs per month between scrapes
+type costLedger struct {
+	mu        sync.Mutex
+	path      string
+	retention int // months kept, including the current one; 0 keeps all
+	last      time.Time
+	saved     time.Time
+	dirty     bool
+	Months    map[string]map[string]*chargebackEntry `json:"months"` // "2006-01" -> vmid -> entry
+}
+
+// newCostLedger creates a ledger, loading previously accumulated data from path if it exists
+func newCostLedger(path string, retention int) *costLedger {
+	l := &costLedger{path: path, retention: retention, Months: make(map[string]map[string]*chargebackEntry)}
+	if path == "" {
+		return l
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading cost ledger %s: %v", path, err)
+		}
+		return l
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		log.Printf("Error parsing cost ledger %s: %v", path, err)
+	}
+	if l.Months == nil {
+		l.Months = make(map[string]map[string]*chargebackEntry)
+	}
+	return l
+}
+
+// accrue charges the hourly costs for the time elapsed since the previous call
+func (l *costLedger) accrue(now time.Time, costs []guestCost) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	last := l.last
+	l.last = now
+	if last.IsZero() || !now.After(last) {
+		return
+	}
+	elapsed := now.Sub(last)
+	if elapsed > maxAccrualGap {
+		elapsed = maxAccrualGap
+	}
+	hours := elapsed.Hours()
+
+	month := now.UTC().Format("2006-01")
+	entries := l.Months[month]
+	if entries == nil {
+		entries = make(map[string]*chargebackEntry)
+		l.Months[month] = entries
+	}
+
+	for _, cost := range costs {
+		e := entries[cost.VMID]
+		if e == nil {
+			e = &chargebackEntry{VMID: cost.VMID}
+			entries[cost.VMID] = e
+		}
+		// Keep the latest metadata so renamed or migrated guests report their current values
+		e.Name, e.Node, e.Type = cost.Guest.Name, cost.Guest.Node, cost.Guest.Type
+		e.Pool, e.Tags = cost.Guest.Pool, cost.Guest.Tags
+		if cost.Guest.Status == "running" {
+			e.RunningHours += hours
+		}
+		e.CPU += cost.CPU * hours
+		e.Memory += cost.Memory * hours
+		e.Storage += cost.Storage * hours
+	}
+	l.prune(now)
+	l.dirty = true
+
+	if now.Sub(l.saved) >= ledgerSaveInterval {
+		l.saved = now
+		l.persist()
+	}
+}
+
+// prune drops months older than the retention; callers must hold l.mu
+func (l *costLedger) prune(now time.Time) {
+	if l.retention <= 0 {
+		return
+	}
+	oldest := now.UTC().AddDate(0, 1-l.retention, 1-now.UTC().Day()).Format("2006-01")
+	for month := range l.Months {
+		if month < oldest {
+			delete(l.Months, month)
+		}
+	}
+}
+
+// persist saves the ledger if it has a file and unsaved changes; callers must hold l.mu
+func (l *costLedger) persist() {
+	if l.path == "" || !l.dirty {
+		return
+	}
+	if err := l.save(); err != nil {
+		log.Printf("Error saving cost ledger %s: %v", l.path, err)
+		return
+	}
+	l.dirty = false
+}
+
+// flush saves changes accrued since the last save
+func (l *costLedger) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.persist()
+}
+
+// save writes the ledger atomically; callers must hold l.mu
+func (l *costLedger) save() error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ".ledger-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
+
+// chargebackRow is a single line of a chargeback report
+type chargebackRow struct {
+	Key          string  `json:"key"`
+	Name         string  `json:"name,omitempty"`
+	Node         string  `json:"node,omitempty"`
+	Pool         string  `json:"pool,omitempty"`
+	Tags         string  `json:"tags,omitempty"`
+	RunningHours float64 `json:"running_hours,omitempty"`
+	CPU          float64 `json:"cpu"`
+	Memory       float64 `json:"memory"`
+	Storage      float64 `json:"storage"`
+	Total        float64 `json:"total"`
+}
+
+// report aggregates a month of ledger entries by guest, pool or tag
+func (l *costLedger) report(month, group string) []chargebackRow {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rows := make(map[string]*chargebackRow)
+	for _, e := range l.Months[month] {
+		for _, key := range chargebackKeys(e, group) {
+			row := rows[key]
+			if row == nil {
+				row = &chargebackRow{Key: key}
+				if group == "guest" {
+					row.Name, row.Node, row.Pool, row.Tags = e.Name, e.Node, e.Pool, e.Tags
+					row.RunningHours = e.RunningHours
+				}
+				rows[key] = row
+			}
+			row.CPU += e.CPU
+			row.Memory += e.Memory
+			row.Storage += e.Storage
+			row.Total += e.CPU + e.Memory + e.Storage
+		}
+	}
+
+	result := make([]chargebackRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// chargebackKeys returns the report keys an entry is aggregated under.
+// A guest with several tags counts towards each of them.
+func chargebackKeys(e *chargebackEntry, group string) []string {
+	switch group {
+	case "pool":
+		if e.Pool == "" {
+			return []string{"(none)"}
+		}
+		return []string{e.Pool}
+	case "tag":
+		tags := splitTags(e.Tags)
+		if len(tags) == 0 {
+			return []string{"(none)"}
+		}
+		return tags
+	default:
+		return []string{e.VMID}
+	}
+}
+
+// ChargebackHandler serves a monthly chargeback report built from accumulated cost data.
+// Query parameters: month (YYYY-MM, default current), group (guest, pool, tag) and format (json, csv).
+func (c *ProxmoxCollector) ChargebackHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c.ledger == nil {
+			http.Error(w, "cost collection is disabled", http.StatusNotFound)
+			return
+		}
+
+		q := r.URL.Query()
+		month := q.Get("month")
+		if month == "" {
+			month = time.Now().UTC().Format("2006-01")
+		} else if _, err := time.Parse("2006-01", month); err != nil {
+			http.Error(w, "month must be in YYYY-MM format", http.StatusBadRequest)
+			return
+		}
+
+		group := q.Get("group")
+		if group == "" {
+			group = "guest"
+		}
+		if group != "guest" && group != "pool" && group != "tag" {
+			http.Error(w, "group must be guest, pool or tag", http.StatusBadRequest)
+			return
+		}
+
+		rows := c.ledger.report(month, group)
+		switch q.Get("format") {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"month":    month,
+				"group":    group,
+				"currency": c.cost.Currency,
+				"rows":     rows,
+			})
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=chargeback-%s-%s.csv", month, group))
+			writeChargebackCSV(w, group, rows)
+		default:
+			http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		}
+	}
+}
+
+// writeChargebackCSV writes report rows as CSV with a header line
+func writeChargebackCSV(w http.ResponseWriter, group string, rows []chargebackRow) {
+	cw := csv.NewWriter(w)
+	money := func(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
+
+	if group == "guest" {
+		_ = cw.Write([]string{"vmid", "name", "node", "pool", "tags", "running_hours", "cpu", "memory", "storage", "total"})
+	} else {
+		_ = cw.Write([]string{group, "cpu", "memory", "storage", "total"})
+	}
+
+	for _, row := range rows {
+		costs := []string{money(row.CPU), money(row.Memory), money(row.Storage), money(row.Total)}
+		if group == "guest" {
+			hours := strconv.FormatFloat(row.RunningHours, 'f', 2, 64)
+			_ = cw.Write(append([]string{row.Key, row.Name, row.Node, row.Pool, strings.ReplaceAll(row.Tags, ";", " "), hours}, costs...))
+		} else {
+			_ = cw.Write(append([]string{row.Key}, costs...))
+		}
+	}
+	cw.Flush()
+}
@@ -11,6 +11,25 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// clusterStatusEntry is a cluster or node entry of /cluster/status
+type clusterStatusEntry struct {
+	Type    string `json:"type"` // "cluster" or "node"
+	Name    string `json:"name"`
+	Quorate int    `json:"quorate"` // 1 if cluster has quorum
+	Online  int    `json:"online"`  // 1 if node is online
+	Nodes   int    `json:"nodes"`   // number of nodes (only in cluster type)
+	IP      string `json:"ip"`      // node address (only in node type)
+}
+
+// discoverEndpoints adds the addresses of online cluster nodes as fallback API endpoints
+func (c *ProxmoxCollector) discoverEndpoints(entries []clusterStatusEntry) {
+	for _, item := range entries {
+		if item.Type == "node" && item.Online == 1 && item.IP != "" {
+			c.endpoints.add(c.config.Endpoint(item.IP))
+		}
+	}
+}
+
 // collectClusterMetrics collects cluster status and HA resource metrics
 func (c *ProxmoxCollector) collectClusterMetrics(ch chan<- prometheus.Metric) {
 	// Fetch cluster status
@@ -21,13 +40,7 @@ func (c *ProxmoxCollector) collectClusterMetrics(ch chan<- prometheus.Metric) {
 	}
 
 	var result struct {
-		Data []struct {
-			Type    string `json:"type"` // "cluster" or "node"
-			Name    string `json:"name"`
-			Quorate int    `json:"quorate"` // 1 if cluster has quorum
-			Online  int    `json:"online"`  // 1 if node is online
-			Nodes   int    `json:"nodes"`   // number of nodes (only in cluster type)
-		} `json:"data"`
+		Data []clusterStatusEntry `json:"data"`
 	}
 
 	if err := json.Unmarshal(data, &result); err != nil {
@@ -35,6 +48,10 @@ func (c *ProxmoxCollector) collectClusterMetrics(ch chan<- prometheus.Metric) {
 		return
 	}
 
+	if c.config.DiscoverHosts {
+		c.discoverEndpoints(result.Data)
+	}
+
 	var nodesTotal, nodesOnline int
 	var hasClusterEntry bool
 	for _, item := range result.Data {
@@ -64,12 +81,17 @@ func (c *ProxmoxCollector) collectClusterMetrics(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(c.clusterNodesTotal, prometheus.GaugeValue, float64(nodesTotal))
 	ch <- prometheus.MustNewConstMetric(c.clusterNodesOnline, prometheus.GaugeValue, float64(nodesOnline))
 
-	// Fetch HA resources
+	c.collectHAMetrics(ch)
+}
+
+// collectHAMetrics collects HA resource counts
+func (c *ProxmoxCollector) collectHAMetrics(ch chan<- prometheus.Metric) {
 	haData, err := c.apiRequest("/cluster/ha/resources")
 	if err != nil {
 		// HA might not be configured, silently skip
 		ch <- prometheus.MustNewConstMetric(c.haResourcesTotal, prometheus.GaugeValue, 0)
 		ch <- prometheus.MustNewConstMetric(c.haResourcesActive, prometheus.GaugeValue, 0)
+		ch <- prometheus.MustNewConstMetric(c.haResourcesError, prometheus.GaugeValue, 0)
 		return
 	}
 
@@ -95,6 +117,33 @@ func (c *ProxmoxCollector) collectClusterMetrics(ch chan<- prometheus.Metric) {
 
 	ch <- prometheus.MustNewConstMetric(c.haResourcesTotal, prometheus.GaugeValue, float64(haTotal))
 	ch <- prometheus.MustNewConstMetric(c.haResourcesActive, prometheus.GaugeValue, float64(haActive))
+
+	// The configured state above is what the resource should be in; failures only show up in the manager status
+	statusData, err := c.apiRequest("/cluster/ha/status/current")
+	if err != nil {
+		log.Printf("Error fetching HA status: %v", err)
+		return
+	}
+
+	var statusResult struct {
+		Data []struct {
+			Type  string `json:"type"` // "quorum", "master", "lrm" or "service"
+			State string `json:"state"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(statusData, &statusResult); err != nil {
+		log.Printf("Error unmarshaling HA status: %v", err)
+		return
+	}
+
+	var haError int
+	for _, item := range statusResult.Data {
+		if item.Type == "service" && item.State == "error" {
+			haError++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.haResourcesError, prometheus.GaugeValue, float64(haError))
 }
 
 // collectReplicationMetrics collects replication job status metrics
@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollectHAMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/cluster/ha/resources", jsonHandler([]map[string]string{
+		{"sid": "vm:100", "state": "started"},
+		{"sid": "vm:101", "state": "started"},
+		{"sid": "ct:200", "state": "stopped"},
+	}))
+	mux.HandleFunc("/api2/json/cluster/ha/status/current", jsonHandler([]map[string]string{
+		{"type": "quorum", "state": "OK"},
+		{"type": "service", "sid": "vm:100", "state": "started"},
+		{"type": "service", "sid": "vm:101", "state": "error"},
+		{"type": "service", "sid": "ct:200", "state": "stopped"},
+	}))
+	c := newTestCollector(t, mux)
+
+	ch := make(chan prometheus.Metric, 10)
+	c.collectHAMetrics(ch)
+	close(ch)
+
+	got := make(map[string]float64)
+	for m := range ch {
+		name := strings.Split(strings.TrimPrefix(m.Desc().String(), `Desc{fqName: "`), `"`)[0]
+		got[name] = getMetricValue(m)
+	}
+	want := map[string]float64{
+		"pve_ha_resources_total":  3,
+		"pve_ha_resources_active": 2,
+		"pve_ha_resources_error":  1,
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %v, want %v", name, got[name], value)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package collector
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"strconv"
 	"sync"
@@ -9,8 +10,130 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// scrapeData is fetched once per scrape and shared by all collectors
+type scrapeData struct {
+	nodesData []byte
+	nodes     []string
+	guests    map[string]GuestInfo
+	// detailed are the guests that guest-scoped collectors see: filtered guests get at most their status.
+	// Capacity, storage content and cost still account for every guest.
+	detailed map[string]GuestInfo
+	// configs are shared between collectors and fetched at most once per scrape
+	configs *guestConfigCache
+	// oneShot waits for results that are normally computed in the background
+	oneShot bool
+}
+
+// namedCollector is a part of a scrape that can be selected by name, e.g. by the dump command
+type namedCollector struct {
+	name    string
+	enabled func(c *ProxmoxCollector) bool // nil for collectors that always run
+	collect func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData)
+}
+
+// exporterCollector is the name of the exporter's own metrics, which are always collected
+const exporterCollector = "exporter"
+
+// namedCollectors are run in parallel on every scrape
+var namedCollectors = []namedCollector{
+	{name: "node", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectNodeMetricsWithNodes(ch, s.nodesData)
+	}},
+	{name: "guest", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectVMMetricsWithNodes(ch, s.nodes, s.guests, s.configs)
+	}},
+	{name: "storage", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectStorageMetrics(ch, s.nodes)
+	}},
+	{name: "zfs", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectZFSMetricsWithNodes(ch, s.nodes)
+	}},
+	{name: "sensors", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectSensorsMetrics(ch)
+	}},
+	{name: "disk", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectDiskMetrics(ch, s.nodes)
+	}},
+	{name: "backup", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		// OPTIMIZATION #2: Pass pre-fetched guest data to avoid duplicate API calls
+		c.collectBackupMetricsWithGuests(ch, s.nodes, s.guests, s.configs)
+	}},
+	{name: "cluster", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectClusterMetrics(ch)
+	}},
+	{name: "replication", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectReplicationMetrics(ch)
+	}},
+	{name: "certificate", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectCertificateMetrics(ch, s.nodes)
+	}},
+	{name: "guest_info", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectGuestInfoMetrics(ch, s.detailed, s.configs)
+	}},
+	{name: "capacity", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectCapacityMetrics(ch, s.nodesData, s.guests)
+	}},
+	{name: "permissions", collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+		c.collectPermissionMetrics(ch, s.nodes)
+	}},
+
+	// Optional collectors
+	{name: "content",
+		enabled: func(c *ProxmoxCollector) bool { return c.collectors.Orphans.Enabled || c.collectors.Content.Enabled },
+		collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+			c.collectStorageContentMetrics(ch, s.nodes, s.guests, s.configs)
+		}},
+	{name: "rrd",
+		enabled: func(c *ProxmoxCollector) bool { return c.collectors.RRD.Enabled },
+		collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+			c.collectRRDMetrics(ch, s.nodes)
+		}},
+	{name: "cost",
+		enabled: func(c *ProxmoxCollector) bool { return c.cost.Enabled },
+		collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+			// Filters reduce monitoring detail, not the bill: every guest is charged
+			c.collectCostMetrics(ch, s.guests, s.configs)
+		}},
+	{name: "rightsizing",
+		enabled: func(c *ProxmoxCollector) bool { return c.collectors.Rightsizing.Enabled },
+		collect: func(c *ProxmoxCollector, ch chan<- prometheus.Metric, s *scrapeData) {
+			if s.oneShot {
+				c.refreshRightsizingNow(s.detailed)
+			}
+			c.collectRightsizingMetrics(ch, s.detailed)
+		}},
+}
+
+// CollectorNames returns the names of all collectors, including the exporter's own metrics
+func CollectorNames() []string {
+	names := []string{exporterCollector}
+	for _, nc := range namedCollectors {
+		names = append(names, nc.name)
+	}
+	return names
+}
+
 // Collect implements prometheus.Collector
 func (c *ProxmoxCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(func(string) chan<- prometheus.Metric { return ch }, nil, false)
+}
+
+// collect runs a scrape of the selected collectors (all enabled ones if selected is nil),
+// sending each collector's metrics to the channel returned by out
+func (c *ProxmoxCollector) collect(out func(name string) chan<- prometheus.Metric, selected map[string]bool, oneShot bool) {
+	self := out(exporterCollector)
+
+	// Exported before authentication so it is visible even when the API is unreachable
+	insecure := 0.0
+	if c.tlsInsecure {
+		insecure = 1
+	}
+	self <- prometheus.MustNewConstMetric(c.exporterTLSInsecure, prometheus.GaugeValue, insecure)
+
+	// Reported last, after any failover during this scrape
+	defer c.collectEndpointMetrics(self)
+	c.checkPrimaryEndpoint()
+
 	// Authenticate if needed
 	if err := c.authenticate(); err != nil {
 		log.Printf("Error during authentication: %v", err)
@@ -18,12 +141,49 @@ func (c *ProxmoxCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	// Fetch nodes list ONCE and reuse across all collection functions
-	nodesData, err := c.apiRequest("/nodes")
+	nodesData, nodes, err := c.fetchNodes()
 	if err != nil {
 		log.Printf("Error fetching nodes: %v", err)
 		return
 	}
 
+	// OPTIMIZATION #6: Fetch all guests ONCE using /cluster/resources (single API call)
+	guests := c.fetchGuests(nodes)
+
+	s := &scrapeData{
+		nodesData: nodesData,
+		nodes:     nodes,
+		guests:    guests,
+		detailed:  c.filter.detailedGuests(guests),
+		configs:   c.newGuestConfigCache(),
+		oneShot:   oneShot,
+	}
+
+	// Run all collection functions in parallel for better performance
+	var wg sync.WaitGroup
+	for _, nc := range namedCollectors {
+		if nc.enabled != nil && !nc.enabled(c) {
+			continue
+		}
+		if selected != nil && !selected[nc.name] {
+			continue
+		}
+		wg.Add(1)
+		go func(nc namedCollector) {
+			defer wg.Done()
+			nc.collect(c, out(nc.name), s)
+		}(nc)
+	}
+	wg.Wait()
+}
+
+// fetchNodes fetches the nodes list and returns the raw response along with the node names
+func (c *ProxmoxCollector) fetchNodes() ([]byte, []string, error) {
+	nodesData, err := c.apiRequest("/nodes")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var nodesResult struct {
 		Data []struct {
 			Node string `json:"node"`
@@ -31,8 +191,7 @@ func (c *ProxmoxCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	if err := json.Unmarshal(nodesData, &nodesResult); err != nil {
-		log.Printf("Error unmarshaling nodes: %v", err)
-		return
+		return nil, nil, fmt.Errorf("failed to unmarshal nodes: %w", err)
 	}
 
 	// Extract node names
@@ -40,84 +199,55 @@ func (c *ProxmoxCollector) Collect(ch chan<- prometheus.Metric) {
 	for i, n := range nodesResult.Data {
 		nodes[i] = n.Node
 	}
+	return nodesData, nodes, nil
+}
 
-	// OPTIMIZATION #6: Fetch all guests ONCE using /cluster/resources (single API call)
-	// This replaces N×2 per-node calls (/qemu + /lxc per node)
+// fetchGuests fetches all guests using /cluster/resources.
+// This replaces N×2 per-node calls (/qemu + /lxc per node), which are only used as a fallback.
+func (c *ProxmoxCollector) fetchGuests(nodes []string) map[string]GuestInfo {
 	guests := make(map[string]GuestInfo)
 	resourcesData, err := c.apiRequest("/cluster/resources?type=vm")
 	if err == nil {
 		var resourcesResult struct {
 			Data []struct {
-				VMID   int64  `json:"vmid"`
-				Node   string `json:"node"`
-				Name   string `json:"name"`
-				Type   string `json:"type"` // "qemu" or "lxc"
-				Status string `json:"status"`
+				VMID     int64   `json:"vmid"`
+				Node     string  `json:"node"`
+				Name     string  `json:"name"`
+				Type     string  `json:"type"` // "qemu" or "lxc"
+				Status   string  `json:"status"`
+				Tags     string  `json:"tags"`
+				Pool     string  `json:"pool"`
+				Lock     string  `json:"lock"`
+				HAState  string  `json:"hastate"`
+				Template int     `json:"template"`
+				MaxCPU   float64 `json:"maxcpu"`
+				MaxMem   float64 `json:"maxmem"`
 			} `json:"data"`
 		}
 		if json.Unmarshal(resourcesData, &resourcesResult) == nil {
 			for _, res := range resourcesResult.Data {
 				vmid := strconv.FormatInt(res.VMID, 10)
-				guests[vmid] = GuestInfo{Node: res.Node, Name: res.Name, Type: res.Type}
+				guests[vmid] = GuestInfo{
+					Node:     res.Node,
+					Name:     res.Name,
+					Type:     res.Type,
+					Status:   res.Status,
+					Tags:     res.Tags,
+					Pool:     res.Pool,
+					Lock:     res.Lock,
+					HAState:  res.HAState,
+					Template: res.Template == 1,
+					MaxCPU:   res.MaxCPU,
+					MaxMem:   res.MaxMem,
+				}
 			}
 		}
 	}
 
-	// Run all collection functions in parallel for better performance
-	var wg sync.WaitGroup
-
-	wg.Add(10)
-
-	go func() {
-		defer wg.Done()
-		c.collectNodeMetricsWithNodes(ch, nodesData)
-	}()
-
-	go func() {
-		defer wg.Done()
-		c.collectVMMetricsWithNodes(ch, nodes)
-	}()
-
-	go func() {
-		defer wg.Done()
-		c.collectStorageMetrics(ch, nodes)
-	}()
-
-	go func() {
-		defer wg.Done()
-		c.collectZFSMetricsWithNodes(ch, nodes)
-	}()
-
-	go func() {
-		defer wg.Done()
-		c.collectSensorsMetrics(ch)
-	}()
-
-	go func() {
-		defer wg.Done()
-		c.collectDiskMetrics(ch, nodes)
-	}()
-
-	go func() {
-		defer wg.Done()
-		// OPTIMIZATION #2: Pass pre-fetched guest data to avoid duplicate API calls
-		c.collectBackupMetricsWithGuests(ch, nodes, guests)
-	}()
-
-	go func() {
-		defer wg.Done()
-		c.collectClusterMetrics(ch)
-	}()
-
-	go func() {
-		defer wg.Done()
-		c.collectReplicationMetrics(ch)
-	}()
-
-	go func() {
-		defer wg.Done()
-		c.collectCertificateMetrics(ch, nodes)
-	}()
-
-	wg.Wait()
+	// If /cluster/resources failed, fall back to per-node guest lists before
+	// any collector goroutine reads the shared guests map
+	if len(guests) == 0 {
+		c.fetchGuestsFallback(nodes, guests)
+	}
+	return guests
 }
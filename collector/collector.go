@@ -2,6 +2,7 @@ package collector
 
 import (
 	"crypto/tls"
+	"log"
 	"net/http"
 	"sync"
 	"time"
@@ -12,11 +13,21 @@ import (
 
 // ProxmoxCollector collects metrics from Proxmox VE API
 type ProxmoxCollector struct {
-	config *config.ProxmoxConfig
-	client *http.Client
-	ticket string
-	csrf   string
-	mutex  sync.RWMutex
+	config      *config.ProxmoxConfig
+	secret      secretSource // password or API token secret
+	tlsInsecure bool         // PVE API certificate is not verified
+	endpoints   *endpointPool
+	collectors  config.CollectorsConfig
+	client      *http.Client
+	ticket      string
+	csrf        string
+	mutex       sync.RWMutex
+	rightsizing rightsizingCache
+	cost        config.CostConfig
+	ledger      *costLedger // nil unless cost collection is enabled
+	naming      string      // metric naming scheme for shared guest metrics
+	filter      *guestFilter
+	labels      *labelEnricher
 	// Node metrics
 	nodeUp          *prometheus.Desc
 	nodeUptime      *prometheus.Desc
@@ -171,6 +182,7 @@ type ProxmoxCollector struct {
 	clusterNodesOnline *prometheus.Desc
 	haResourcesTotal   *prometheus.Desc
 	haResourcesActive  *prometheus.Desc
+	haResourcesError   *prometheus.Desc
 
 	// Replication metrics
 	replicationLastSync *prometheus.Desc
@@ -179,23 +191,121 @@ type ProxmoxCollector struct {
 
 	// Certificate metrics
 	certificateExpiry *prometheus.Desc
+
+	// Guest configuration metrics
+	guestInfo           *prometheus.Desc
+	guestPendingChanges *prometheus.Desc
+	guestDiskSize       *prometheus.Desc
+	guestDiskInfo       *prometheus.Desc
+
+	// Orphaned volume metrics
+	storageOrphanedVolumes *prometheus.Desc
+	storageOrphanedBytes   *prometheus.Desc
+
+	// Storage content metrics
+	storageContentBytes      *prometheus.Desc
+	storageContentVolumes    *prometheus.Desc
+	storageContentGuestBytes *prometheus.Desc
+
+	// RRD metrics (latest samples aggregated by PVE)
+	nodeRRDCPU        *prometheus.Desc
+	nodeRRDIOWait     *prometheus.Desc
+	nodeRRDLoadAvg    *prometheus.Desc
+	nodeRRDMemoryUsed *prometheus.Desc
+	nodeRRDRootfsUsed *prometheus.Desc
+	nodeRRDSwapUsed   *prometheus.Desc
+	nodeRRDNetIn      *prometheus.Desc
+	nodeRRDNetOut     *prometheus.Desc
+	storageRRDTotal   *prometheus.Desc
+	storageRRDUsed    *prometheus.Desc
+
+	// Right-sizing metrics (cached, refreshed in the background)
+	rightsizingCPUUsage        *prometheus.Desc
+	rightsizingMemoryUsage     *prometheus.Desc
+	rightsizingCPUAllocated    *prometheus.Desc
+	rightsizingMemoryAllocated *prometheus.Desc
+	rightsizingRecommendedCPUs *prometheus.Desc
+	rightsizingRecommendedMem  *prometheus.Desc
+	rightsizingIdle            *prometheus.Desc
+	rightsizingLastRefresh     *prometheus.Desc
+
+	// Exporter self metrics
+	exporterTLSInsecure      *prometheus.Desc
+	exporterEndpointActive   *prometheus.Desc
+	exporterEndpointSwitches *prometheus.Desc
+	exporterPermissionOK     *prometheus.Desc
+
+	// Capacity metrics (derived from node and guest data)
+	nodeCPUAllocated        *prometheus.Desc
+	nodeMemoryAllocated     *prometheus.Desc
+	nodeCPUOvercommit       *prometheus.Desc
+	nodeMemoryOvercommit    *prometheus.Desc
+	clusterCPUAllocated     *prometheus.Desc
+	clusterMemoryAllocated  *prometheus.Desc
+	clusterCPUTotal         *prometheus.Desc
+	clusterMemoryTotal      *prometheus.Desc
+	clusterCPUOvercommit    *prometheus.Desc
+	clusterMemoryOvercommit *prometheus.Desc
+	clusterN1Headroom       *prometheus.Desc
+	clusterN1Fits           *prometheus.Desc
+
+	// Cost metrics (derived from the configured price table)
+	costGuestHourly   *prometheus.Desc
+	costPoolHourly    *prometheus.Desc
+	costTagHourly     *prometheus.Desc
+	costStorageHourly *prometheus.Desc
+
+	// Unified guest metrics (metrics.naming: unified or both)
+	guestStatus             *prometheus.Desc
+	guestUptime             *prometheus.Desc
+	guestCPU                *prometheus.Desc
+	guestCPUs               *prometheus.Desc
+	guestMemory             *prometheus.Desc
+	guestMaxMemory          *prometheus.Desc
+	guestMaxDisk            *prometheus.Desc
+	guestNetIn              *prometheus.Desc
+	guestNetOut             *prometheus.Desc
+	guestDiskRead           *prometheus.Desc
+	guestDiskWrite          *prometheus.Desc
+	guestHAManaged          *prometheus.Desc
+	guestPID                *prometheus.Desc
+	guestPressureCPUFull    *prometheus.Desc
+	guestPressureCPUSome    *prometheus.Desc
+	guestPressureIOFull     *prometheus.Desc
+	guestPressureIOSome     *prometheus.Desc
+	guestPressureMemoryFull *prometheus.Desc
+	guestPressureMemorySome *prometheus.Desc
+	guestLastBackup         *prometheus.Desc
 }
 
 // GuestInfo represents VM or LXC container info for sharing between collectors
 type GuestInfo struct {
-	Node string
-	Name string
-	Type string // "qemu" or "lxc"
+	Node     string
+	Name     string
+	Type     string // "qemu" or "lxc"
+	Status   string
+	Tags     string // semicolon separated, as returned by PVE
+	Pool     string
+	Lock     string
+	HAState  string
+	Template bool
+	MaxCPU   float64 // allocated vCPUs
+	MaxMem   float64 // allocated memory in bytes
 }
 
 // NewProxmoxCollector creates a new Proxmox collector
-func NewProxmoxCollector(cfg *config.ProxmoxConfig) *ProxmoxCollector {
+func NewProxmoxCollector(cfg *config.Config) *ProxmoxCollector {
+	tlsConfig, err := cfg.Proxmox.TLSConfig()
+	if err != nil {
+		// LoadFromFile already checked the files; keep verification on rather than falling back to insecure
+		log.Printf("Error building TLS configuration: %v", err)
+		tlsConfig = &tls.Config{}
+	}
+
 	client := &http.Client{
-		Timeout: cfg.Timeout,
+		Timeout: cfg.Proxmox.Timeout,
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: cfg.InsecureSkipVerify,
-			},
+			TLSClientConfig: tlsConfig,
 			// Connection pooling for better performance
 			MaxIdleConns:        100,
 			MaxIdleConnsPerHost: 10,
@@ -203,440 +313,470 @@ func NewProxmoxCollector(cfg *config.ProxmoxConfig) *ProxmoxCollector {
 		},
 	}
 
+	// Extra labels are appended after the base node/guest labels of node and guest metrics
+	labels := newLabelEnricher(cfg.Labels)
+	nodeLabels := func() []string {
+		return append([]string{"node"}, labels.nodeNames...)
+	}
+	guestLabels := func(specific ...string) []string {
+		base := append([]string{"node", "vmid", "name"}, labels.guestNames...)
+		return append(base, specific...)
+	}
+
+	filter, err := newGuestFilter(cfg.Filters)
+	if err != nil {
+		// Config.Validate rejects invalid rules, so this only fails for an unvalidated config
+		log.Fatalf("Error compiling guest filters: %v", err)
+	}
+
+	var ledger *costLedger
+	if cfg.Cost.Enabled {
+		ledger = newCostLedger(cfg.Cost.LedgerFile, cfg.Cost.RetentionMonths)
+	}
+
 	return &ProxmoxCollector{
-		config: cfg,
-		client: client,
+		config:      &cfg.Proxmox,
+		secret:      newSecretSource(&cfg.Proxmox),
+		tlsInsecure: cfg.Proxmox.TLSInsecure(),
+		endpoints:   newEndpointPool(cfg.Proxmox.Endpoints()),
+		collectors:  cfg.Collectors,
+		client:      client,
+		cost:        cfg.Cost,
+		ledger:      ledger,
+		naming:      cfg.Metrics.Naming,
+		filter:      filter,
+		labels:      labels,
 
 		// Node metrics
 		nodeUp: prometheus.NewDesc(
 			"pve_node_up",
 			"Node is up and reachable",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeUptime: prometheus.NewDesc(
 			"pve_node_uptime_seconds",
 			"Node uptime in seconds",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeCPULoad: prometheus.NewDesc(
 			"pve_node_cpu_load",
 			"Node CPU load",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeCPUs: prometheus.NewDesc(
 			"pve_node_cpus_total",
 			"Total number of CPUs",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeMemoryTotal: prometheus.NewDesc(
 			"pve_node_memory_total_bytes",
 			"Total memory in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeMemoryUsed: prometheus.NewDesc(
 			"pve_node_memory_used_bytes",
 			"Used memory in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeMemoryFree: prometheus.NewDesc(
 			"pve_node_memory_free_bytes",
 			"Free memory in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeSwapTotal: prometheus.NewDesc(
 			"pve_node_swap_total_bytes",
 			"Total swap in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeSwapUsed: prometheus.NewDesc(
 			"pve_node_swap_used_bytes",
 			"Used swap in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeSwapFree: prometheus.NewDesc(
 			"pve_node_swap_free_bytes",
 			"Free swap in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeVMCount: prometheus.NewDesc(
 			"pve_node_vm_count",
 			"Number of QEMU VMs",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeLXCCount: prometheus.NewDesc(
 			"pve_node_lxc_count",
 			"Number of LXC containers",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		// New node metrics
 		nodeLoad1: prometheus.NewDesc(
 			"pve_node_load1",
 			"Node load average 1 minute",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeLoad5: prometheus.NewDesc(
 			"pve_node_load5",
 			"Node load average 5 minutes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeLoad15: prometheus.NewDesc(
 			"pve_node_load15",
 			"Node load average 15 minutes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeIOWait: prometheus.NewDesc(
 			"pve_node_iowait",
 			"Node I/O wait ratio",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeIdle: prometheus.NewDesc(
 			"pve_node_idle",
 			"Node idle CPU ratio",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeCPUMhz: prometheus.NewDesc(
 			"pve_node_cpu_mhz",
 			"CPU frequency in MHz",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeRootfsTotal: prometheus.NewDesc(
 			"pve_node_rootfs_total_bytes",
 			"Node root filesystem total size in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeRootfsUsed: prometheus.NewDesc(
 			"pve_node_rootfs_used_bytes",
 			"Node root filesystem used in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeRootfsFree: prometheus.NewDesc(
 			"pve_node_rootfs_free_bytes",
 			"Node root filesystem free in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeCPUCores: prometheus.NewDesc(
 			"pve_node_cpu_cores",
 			"Number of CPU cores per socket",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeCPUSockets: prometheus.NewDesc(
 			"pve_node_cpu_sockets",
 			"Number of CPU sockets",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 		nodeKSMShared: prometheus.NewDesc(
 			"pve_node_ksm_shared_bytes",
 			"KSM shared memory in bytes",
-			[]string{"node"}, nil,
+			nodeLabels(), nil,
 		),
 
 		// VM metrics
 		vmStatus: prometheus.NewDesc(
 			"pve_vm_status",
 			"VM status (1=running, 0=stopped)",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmUptime: prometheus.NewDesc(
 			"pve_vm_uptime_seconds",
 			"VM uptime in seconds",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmCPU: prometheus.NewDesc(
 			"pve_vm_cpu_usage",
 			"VM CPU usage",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmCPUs: prometheus.NewDesc(
 			"pve_vm_cpus",
 			"Number of CPUs allocated to VM",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmMemory: prometheus.NewDesc(
 			"pve_vm_memory_used_bytes",
 			"VM memory usage in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmMaxMemory: prometheus.NewDesc(
 			"pve_vm_memory_max_bytes",
 			"VM maximum memory in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmMaxDisk: prometheus.NewDesc(
 			"pve_vm_disk_max_bytes",
 			"VM maximum disk in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmNetIn: prometheus.NewDesc(
 			"pve_vm_network_in_bytes_total",
 			"VM network input in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmNetOut: prometheus.NewDesc(
 			"pve_vm_network_out_bytes_total",
 			"VM network output in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmDiskRead: prometheus.NewDesc(
 			"pve_vm_disk_read_bytes_total",
 			"VM disk read in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmDiskWrite: prometheus.NewDesc(
 			"pve_vm_disk_write_bytes_total",
 			"VM disk write in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmFreeMem: prometheus.NewDesc(
 			"pve_vm_memory_free_bytes",
 			"VM free memory in bytes (from guest agent/balloon)",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmBalloon: prometheus.NewDesc(
 			"pve_vm_balloon_bytes",
 			"VM balloon target in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmHAManaged: prometheus.NewDesc(
 			"pve_vm_ha_managed",
 			"VM is managed by HA (1=yes, 0=no)",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmPID: prometheus.NewDesc(
 			"pve_vm_pid",
 			"VM process ID",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmMemHost: prometheus.NewDesc(
 			"pve_vm_memory_host_bytes",
 			"VM host memory allocation in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		// VM pressure metrics (Linux PSI)
 		vmPressureCPUFull: prometheus.NewDesc(
 			"pve_vm_pressure_cpu_full",
 			"VM CPU pressure full ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmPressureCPUSome: prometheus.NewDesc(
 			"pve_vm_pressure_cpu_some",
 			"VM CPU pressure some ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmPressureIOFull: prometheus.NewDesc(
 			"pve_vm_pressure_io_full",
 			"VM I/O pressure full ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmPressureIOSome: prometheus.NewDesc(
 			"pve_vm_pressure_io_some",
 			"VM I/O pressure some ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmPressureMemoryFull: prometheus.NewDesc(
 			"pve_vm_pressure_memory_full",
 			"VM memory pressure full ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmPressureMemorySome: prometheus.NewDesc(
 			"pve_vm_pressure_memory_some",
 			"VM memory pressure some ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		// VM balloon info
 		vmBalloonActual: prometheus.NewDesc(
 			"pve_vm_balloon_actual_bytes",
 			"VM balloon actual memory in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmBalloonMaxMem: prometheus.NewDesc(
 			"pve_vm_balloon_max_bytes",
 			"VM balloon maximum memory in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmBalloonTotalMem: prometheus.NewDesc(
 			"pve_vm_balloon_total_bytes",
 			"VM balloon total guest memory in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmBalloonMajorFaults: prometheus.NewDesc(
 			"pve_vm_balloon_major_page_faults_total",
 			"VM major page faults",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmBalloonMinorFaults: prometheus.NewDesc(
 			"pve_vm_balloon_minor_page_faults_total",
 			"VM minor page faults",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmBalloonMemSwappedIn: prometheus.NewDesc(
 			"pve_vm_balloon_mem_swapped_in_bytes",
 			"VM memory swapped in",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		vmBalloonMemSwappedOut: prometheus.NewDesc(
 			"pve_vm_balloon_mem_swapped_out_bytes",
 			"VM memory swapped out",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		// VM block device metrics
 		vmBlockReadBytes: prometheus.NewDesc(
 			"pve_vm_block_read_bytes_total",
 			"VM block device read in bytes",
-			[]string{"node", "vmid", "name", "device"}, nil,
+			guestLabels("device"), nil,
 		),
 		vmBlockWriteBytes: prometheus.NewDesc(
 			"pve_vm_block_write_bytes_total",
 			"VM block device write in bytes",
-			[]string{"node", "vmid", "name", "device"}, nil,
+			guestLabels("device"), nil,
 		),
 		vmBlockReadOps: prometheus.NewDesc(
 			"pve_vm_block_read_ops_total",
 			"VM block device read operations",
-			[]string{"node", "vmid", "name", "device"}, nil,
+			guestLabels("device"), nil,
 		),
 		vmBlockWriteOps: prometheus.NewDesc(
 			"pve_vm_block_write_ops_total",
 			"VM block device write operations",
-			[]string{"node", "vmid", "name", "device"}, nil,
+			guestLabels("device"), nil,
 		),
 		vmBlockFailedRead: prometheus.NewDesc(
 			"pve_vm_block_failed_read_ops_total",
 			"VM block device failed read operations",
-			[]string{"node", "vmid", "name", "device"}, nil,
+			guestLabels("device"), nil,
 		),
 		vmBlockFailedWrite: prometheus.NewDesc(
 			"pve_vm_block_failed_write_ops_total",
 			"VM block device failed write operations",
-			[]string{"node", "vmid", "name", "device"}, nil,
+			guestLabels("device"), nil,
 		),
 		vmBlockFlushOps: prometheus.NewDesc(
 			"pve_vm_block_flush_ops_total",
 			"VM block device flush operations",
-			[]string{"node", "vmid", "name", "device"}, nil,
+			guestLabels("device"), nil,
 		),
 		// VM NIC metrics
 		vmNICNetIn: prometheus.NewDesc(
 			"pve_vm_nic_in_bytes_total",
 			"VM NIC input in bytes",
-			[]string{"node", "vmid", "name", "interface"}, nil,
+			guestLabels("interface"), nil,
 		),
 		vmNICNetOut: prometheus.NewDesc(
 			"pve_vm_nic_out_bytes_total",
 			"VM NIC output in bytes",
-			[]string{"node", "vmid", "name", "interface"}, nil,
+			guestLabels("interface"), nil,
 		),
 
 		// LXC metrics
 		lxcStatus: prometheus.NewDesc(
 			"pve_lxc_status",
 			"LXC status (1=running, 0=stopped)",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcUptime: prometheus.NewDesc(
 			"pve_lxc_uptime_seconds",
 			"LXC uptime in seconds",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcCPU: prometheus.NewDesc(
 			"pve_lxc_cpu_usage",
 			"LXC CPU usage",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcCPUs: prometheus.NewDesc(
 			"pve_lxc_cpus",
 			"Number of CPUs allocated to LXC",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcMemory: prometheus.NewDesc(
 			"pve_lxc_memory_used_bytes",
 			"LXC memory usage in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcMaxMemory: prometheus.NewDesc(
 			"pve_lxc_memory_max_bytes",
 			"LXC maximum memory in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcDisk: prometheus.NewDesc(
 			"pve_lxc_disk_used_bytes",
 			"LXC disk usage in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcMaxDisk: prometheus.NewDesc(
 			"pve_lxc_disk_max_bytes",
 			"LXC maximum disk in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcNetIn: prometheus.NewDesc(
 			"pve_lxc_network_in_bytes_total",
 			"LXC network input in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcNetOut: prometheus.NewDesc(
 			"pve_lxc_network_out_bytes_total",
 			"LXC network output in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcDiskRead: prometheus.NewDesc(
 			"pve_lxc_disk_read_bytes_total",
 			"LXC disk read in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcDiskWrite: prometheus.NewDesc(
 			"pve_lxc_disk_write_bytes_total",
 			"LXC disk write in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcSwap: prometheus.NewDesc(
 			"pve_lxc_swap_used_bytes",
 			"LXC swap usage in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcMaxSwap: prometheus.NewDesc(
 			"pve_lxc_swap_max_bytes",
 			"LXC maximum swap in bytes",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcHAManaged: prometheus.NewDesc(
 			"pve_lxc_ha_managed",
 			"LXC is managed by HA (1=yes, 0=no)",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcPID: prometheus.NewDesc(
 			"pve_lxc_pid",
 			"LXC process ID",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		// LXC pressure metrics (Linux PSI)
 		lxcPressureCPUFull: prometheus.NewDesc(
 			"pve_lxc_pressure_cpu_full",
 			"LXC CPU pressure full ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcPressureCPUSome: prometheus.NewDesc(
 			"pve_lxc_pressure_cpu_some",
 			"LXC CPU pressure some ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcPressureIOFull: prometheus.NewDesc(
 			"pve_lxc_pressure_io_full",
 			"LXC I/O pressure full ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcPressureIOSome: prometheus.NewDesc(
 			"pve_lxc_pressure_io_some",
 			"LXC I/O pressure some ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcPressureMemoryFull: prometheus.NewDesc(
 			"pve_lxc_pressure_memory_full",
 			"LXC memory pressure full ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcPressureMemorySome: prometheus.NewDesc(
 			"pve_lxc_pressure_memory_some",
 			"LXC memory pressure some ratio",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 
 		// Storage metrics
@@ -843,12 +983,12 @@ func NewProxmoxCollector(cfg *config.ProxmoxConfig) *ProxmoxCollector {
 		vmLastBackup: prometheus.NewDesc(
 			"pve_vm_last_backup_timestamp",
 			"Unix timestamp of last successful backup",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 		lxcLastBackup: prometheus.NewDesc(
 			"pve_lxc_last_backup_timestamp",
 			"Unix timestamp of last successful backup",
-			[]string{"node", "vmid", "name"}, nil,
+			guestLabels(), nil,
 		),
 
 		// Cluster/HA metrics
@@ -877,6 +1017,11 @@ func NewProxmoxCollector(cfg *config.ProxmoxConfig) *ProxmoxCollector {
 			"Number of active HA resources",
 			nil, nil,
 		),
+		haResourcesError: prometheus.NewDesc(
+			"pve_ha_resources_error",
+			"Number of HA resources in error state",
+			nil, nil,
+		),
 
 		// Replication metrics
 		replicationLastSync: prometheus.NewDesc(
@@ -901,5 +1046,358 @@ func NewProxmoxCollector(cfg *config.ProxmoxConfig) *ProxmoxCollector {
 			"Seconds until SSL certificate expires",
 			[]string{"node"}, nil,
 		),
+
+		// Guest configuration metrics
+		guestInfo: prometheus.NewDesc(
+			"pve_guest_info",
+			"Guest configuration info (always 1)",
+			[]string{"node", "vmid", "name", "type", "tags", "pool", "template", "lock", "hastate",
+				"ostype", "machine", "bios", "cpu", "onboot", "protection", "agent"}, nil,
+		),
+		guestPendingChanges: prometheus.NewDesc(
+			"pve_guest_pending_changes",
+			"Number of pending guest configuration changes",
+			[]string{"node", "vmid", "name", "type"}, nil,
+		),
+		guestDiskSize: prometheus.NewDesc(
+			"pve_guest_disk_size_bytes",
+			"Configured size of a guest virtual disk in bytes",
+			[]string{"node", "vmid", "name", "type", "device", "storage", "volume"}, nil,
+		),
+		guestDiskInfo: prometheus.NewDesc(
+			"pve_guest_disk_info",
+			"Guest virtual disk configuration (always 1)",
+			[]string{"node", "vmid", "name", "type", "device", "storage", "volume",
+				"cache", "iothread", "discard", "ssd", "backup"}, nil,
+		),
+
+		// Orphaned volume metrics
+		storageOrphanedVolumes: prometheus.NewDesc(
+			"pve_storage_orphaned_volumes",
+			"Number of guest volumes not attached to any guest (reason: unreferenced, missing_guest, unused)",
+			[]string{"node", "storage", "reason"}, nil,
+		),
+		storageOrphanedBytes: prometheus.NewDesc(
+			"pve_storage_orphaned_volume_bytes",
+			"Size of guest volumes not attached to any guest in bytes",
+			[]string{"node", "storage", "reason"}, nil,
+		),
+
+		// Storage content metrics
+		storageContentBytes: prometheus.NewDesc(
+			"pve_storage_content_bytes",
+			"Total size of storage content by content type in bytes",
+			[]string{"node", "storage", "content"}, nil,
+		),
+		storageContentVolumes: prometheus.NewDesc(
+			"pve_storage_content_volumes",
+			"Number of volumes on a storage by content type",
+			[]string{"node", "storage", "content"}, nil,
+		),
+		storageContentGuestBytes: prometheus.NewDesc(
+			"pve_storage_content_guest_bytes",
+			"Total size of storage content owned by a guest in bytes",
+			[]string{"node", "storage", "vmid", "content"}, nil,
+		),
+
+		// RRD metrics
+		nodeRRDCPU: prometheus.NewDesc(
+			"pve_node_rrd_cpu_usage",
+			"Node CPU usage ratio from the latest RRD sample",
+			nodeLabels(), nil,
+		),
+		nodeRRDIOWait: prometheus.NewDesc(
+			"pve_node_rrd_iowait",
+			"Node I/O delay ratio from the latest RRD sample",
+			nodeLabels(), nil,
+		),
+		nodeRRDLoadAvg: prometheus.NewDesc(
+			"pve_node_rrd_loadavg",
+			"Node load average from the latest RRD sample",
+			nodeLabels(), nil,
+		),
+		nodeRRDMemoryUsed: prometheus.NewDesc(
+			"pve_node_rrd_memory_used_bytes",
+			"Node used memory in bytes from the latest RRD sample",
+			nodeLabels(), nil,
+		),
+		nodeRRDRootfsUsed: prometheus.NewDesc(
+			"pve_node_rrd_rootfs_used_bytes",
+			"Node root filesystem used in bytes from the latest RRD sample",
+			nodeLabels(), nil,
+		),
+		nodeRRDSwapUsed: prometheus.NewDesc(
+			"pve_node_rrd_swap_used_bytes",
+			"Node used swap in bytes from the latest RRD sample",
+			nodeLabels(), nil,
+		),
+		nodeRRDNetIn: prometheus.NewDesc(
+			"pve_node_rrd_network_receive_bytes_per_second",
+			"Node network receive rate in bytes per second from the latest RRD sample",
+			nodeLabels(), nil,
+		),
+		nodeRRDNetOut: prometheus.NewDesc(
+			"pve_node_rrd_network_transmit_bytes_per_second",
+			"Node network transmit rate in bytes per second from the latest RRD sample",
+			nodeLabels(), nil,
+		),
+		storageRRDTotal: prometheus.NewDesc(
+			"pve_storage_rrd_total_bytes",
+			"Storage total size in bytes from the latest RRD sample",
+			[]string{"node", "storage"}, nil,
+		),
+		storageRRDUsed: prometheus.NewDesc(
+			"pve_storage_rrd_used_bytes",
+			"Storage used size in bytes from the latest RRD sample",
+			[]string{"node", "storage"}, nil,
+		),
+
+		// Right-sizing metrics
+		rightsizingCPUUsage: prometheus.NewDesc(
+			"pve_guest_rightsizing_cpu_usage_cores",
+			"Guest CPU usage in cores over the right-sizing window (stat: p50, p95, max)",
+			[]string{"node", "vmid", "name", "type", "stat"}, nil,
+		),
+		rightsizingMemoryUsage: prometheus.NewDesc(
+			"pve_guest_rightsizing_memory_usage_bytes",
+			"Guest memory usage in bytes over the right-sizing window (stat: p50, p95, max)",
+			[]string{"node", "vmid", "name", "type", "stat"}, nil,
+		),
+		rightsizingCPUAllocated: prometheus.NewDesc(
+			"pve_guest_rightsizing_cpu_allocated",
+			"Number of vCPUs allocated to the guest",
+			[]string{"node", "vmid", "name", "type"}, nil,
+		),
+		rightsizingMemoryAllocated: prometheus.NewDesc(
+			"pve_guest_rightsizing_memory_allocated_bytes",
+			"Memory allocated to the guest in bytes",
+			[]string{"node", "vmid", "name", "type"}, nil,
+		),
+		rightsizingRecommendedCPUs: prometheus.NewDesc(
+			"pve_guest_rightsizing_recommended_cpus",
+			"Recommended number of vCPUs (p95 usage plus headroom)",
+			[]string{"node", "vmid", "name", "type"}, nil,
+		),
+		rightsizingRecommendedMem: prometheus.NewDesc(
+			"pve_guest_rightsizing_recommended_memory_bytes",
+			"Recommended memory in bytes (p95 usage plus headroom)",
+			[]string{"node", "vmid", "name", "type"}, nil,
+		),
+		rightsizingIdle: prometheus.NewDesc(
+			"pve_guest_rightsizing_idle",
+			"Guest stayed below the idle CPU threshold for the whole window (1=idle, 0=active)",
+			[]string{"node", "vmid", "name", "type"}, nil,
+		),
+		exporterTLSInsecure: prometheus.NewDesc(
+			"pve_exporter_tls_insecure",
+			"Whether TLS certificate verification of the PVE API is disabled (1 = insecure)",
+			nil, nil,
+		),
+		exporterEndpointActive: prometheus.NewDesc(
+			"pve_exporter_api_endpoint_active",
+			"Whether the PVE API endpoint is the one currently in use",
+			[]string{"endpoint"}, nil,
+		),
+		exporterEndpointSwitches: prometheus.NewDesc(
+			"pve_exporter_api_endpoint_switches_total",
+			"Number of times the exporter switched PVE API endpoints",
+			nil, nil,
+		),
+		exporterPermissionOK: prometheus.NewDesc(
+			"pve_exporter_permission_ok",
+			"Whether the API user or token has the privileges a collector needs on an ACL path",
+			[]string{"collector", "path"}, nil,
+		),
+		rightsizingLastRefresh: prometheus.NewDesc(
+			"pve_guest_rightsizing_last_refresh_timestamp_seconds",
+			"Unix timestamp of the last right-sizing refresh",
+			nil, nil,
+		),
+
+		// Capacity metrics
+		nodeCPUAllocated: prometheus.NewDesc(
+			"pve_node_cpu_allocated",
+			"Number of vCPUs allocated to running guests on the node",
+			[]string{"node"}, nil,
+		),
+		nodeMemoryAllocated: prometheus.NewDesc(
+			"pve_node_memory_allocated_bytes",
+			"Memory allocated to running guests on the node in bytes",
+			[]string{"node"}, nil,
+		),
+		nodeCPUOvercommit: prometheus.NewDesc(
+			"pve_node_cpu_overcommit_ratio",
+			"Ratio of vCPUs allocated to running guests to physical CPUs",
+			[]string{"node"}, nil,
+		),
+		nodeMemoryOvercommit: prometheus.NewDesc(
+			"pve_node_memory_overcommit_ratio",
+			"Ratio of memory allocated to running guests to physical memory",
+			[]string{"node"}, nil,
+		),
+		clusterCPUAllocated: prometheus.NewDesc(
+			"pve_cluster_cpu_allocated",
+			"Number of vCPUs allocated to running guests in the cluster",
+			nil, nil,
+		),
+		clusterMemoryAllocated: prometheus.NewDesc(
+			"pve_cluster_memory_allocated_bytes",
+			"Memory allocated to running guests in the cluster in bytes",
+			nil, nil,
+		),
+		clusterCPUTotal: prometheus.NewDesc(
+			"pve_cluster_cpus_total",
+			"Number of physical CPUs on online nodes",
+			nil, nil,
+		),
+		clusterMemoryTotal: prometheus.NewDesc(
+			"pve_cluster_memory_total_bytes",
+			"Physical memory of online nodes in bytes",
+			nil, nil,
+		),
+		clusterCPUOvercommit: prometheus.NewDesc(
+			"pve_cluster_cpu_overcommit_ratio",
+			"Ratio of vCPUs allocated to running guests to physical CPUs of online nodes",
+			nil, nil,
+		),
+		clusterMemoryOvercommit: prometheus.NewDesc(
+			"pve_cluster_memory_overcommit_ratio",
+			"Ratio of memory allocated to running guests to physical memory of online nodes",
+			nil, nil,
+		),
+		clusterN1Headroom: prometheus.NewDesc(
+			"pve_cluster_ha_n1_headroom_bytes",
+			"Free memory left on the remaining nodes after losing the node with the most HA-managed memory (negative if insufficient)",
+			[]string{"failed_node"}, nil,
+		),
+		clusterN1Fits: prometheus.NewDesc(
+			"pve_cluster_ha_n1_fits",
+			"Whether every HA-managed guest of the largest node fits on the remaining nodes (1=yes, 0=no)",
+			[]string{"failed_node"}, nil,
+		),
+
+		// Cost metrics
+		costGuestHourly: prometheus.NewDesc(
+			"pve_cost_guest_hourly",
+			"Hourly cost of resources allocated to the guest (resource: cpu, memory, storage)",
+			[]string{"node", "vmid", "name", "type", "pool", "resource"}, nil,
+		),
+		costPoolHourly: prometheus.NewDesc(
+			"pve_cost_pool_hourly",
+			"Hourly cost of all guests in the pool",
+			[]string{"pool"}, nil,
+		),
+		costTagHourly: prometheus.NewDesc(
+			"pve_cost_tag_hourly",
+			"Hourly cost of all guests carrying the tag",
+			[]string{"tag"}, nil,
+		),
+		costStorageHourly: prometheus.NewDesc(
+			"pve_cost_storage_hourly",
+			"Hourly cost of used space on the storage",
+			[]string{"node", "storage", "type"}, nil,
+		),
+
+		// Unified guest metrics
+		guestStatus: prometheus.NewDesc(
+			"pve_guest_status",
+			"Guest status (1=running, 0=stopped)",
+			guestLabels("type"), nil,
+		),
+		guestUptime: prometheus.NewDesc(
+			"pve_guest_uptime_seconds",
+			"Guest uptime in seconds",
+			guestLabels("type"), nil,
+		),
+		guestCPU: prometheus.NewDesc(
+			"pve_guest_cpu_usage",
+			"Guest CPU usage",
+			guestLabels("type"), nil,
+		),
+		guestCPUs: prometheus.NewDesc(
+			"pve_guest_cpus",
+			"Number of CPUs allocated to guest",
+			guestLabels("type"), nil,
+		),
+		guestMemory: prometheus.NewDesc(
+			"pve_guest_memory_used_bytes",
+			"Guest memory usage in bytes",
+			guestLabels("type"), nil,
+		),
+		guestMaxMemory: prometheus.NewDesc(
+			"pve_guest_memory_max_bytes",
+			"Guest maximum memory in bytes",
+			guestLabels("type"), nil,
+		),
+		guestMaxDisk: prometheus.NewDesc(
+			"pve_guest_disk_max_bytes",
+			"Guest maximum disk in bytes",
+			guestLabels("type"), nil,
+		),
+		guestNetIn: prometheus.NewDesc(
+			"pve_guest_network_in_bytes_total",
+			"Guest network input in bytes",
+			guestLabels("type"), nil,
+		),
+		guestNetOut: prometheus.NewDesc(
+			"pve_guest_network_out_bytes_total",
+			"Guest network output in bytes",
+			guestLabels("type"), nil,
+		),
+		guestDiskRead: prometheus.NewDesc(
+			"pve_guest_disk_read_bytes_total",
+			"Guest disk read in bytes",
+			guestLabels("type"), nil,
+		),
+		guestDiskWrite: prometheus.NewDesc(
+			"pve_guest_disk_write_bytes_total",
+			"Guest disk write in bytes",
+			guestLabels("type"), nil,
+		),
+		guestHAManaged: prometheus.NewDesc(
+			"pve_guest_ha_managed",
+			"Guest is managed by HA (1=yes, 0=no)",
+			guestLabels("type"), nil,
+		),
+		guestPID: prometheus.NewDesc(
+			"pve_guest_pid",
+			"Guest process ID",
+			guestLabels("type"), nil,
+		),
+		guestPressureCPUFull: prometheus.NewDesc(
+			"pve_guest_pressure_cpu_full",
+			"Guest CPU pressure full ratio",
+			guestLabels("type"), nil,
+		),
+		guestPressureCPUSome: prometheus.NewDesc(
+			"pve_guest_pressure_cpu_some",
+			"Guest CPU pressure some ratio",
+			guestLabels("type"), nil,
+		),
+		guestPressureIOFull: prometheus.NewDesc(
+			"pve_guest_pressure_io_full",
+			"Guest I/O pressure full ratio",
+			guestLabels("type"), nil,
+		),
+		guestPressureIOSome: prometheus.NewDesc(
+			"pve_guest_pressure_io_some",
+			"Guest I/O pressure some ratio",
+			guestLabels("type"), nil,
+		),
+		guestPressureMemoryFull: prometheus.NewDesc(
+			"pve_guest_pressure_memory_full",
+			"Guest memory pressure full ratio",
+			guestLabels("type"), nil,
+		),
+		guestPressureMemorySome: prometheus.NewDesc(
+			"pve_guest_pressure_memory_some",
+			"Guest memory pressure some ratio",
+			guestLabels("type"), nil,
+		),
+		guestLastBackup: prometheus.NewDesc(
+			"pve_guest_last_backup_timestamp",
+			"Unix timestamp of last successful backup",
+			guestLabels("type"), nil,
+		),
 	}
 }
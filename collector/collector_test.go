@@ -8,9 +8,11 @@ import (
 )
 
 func TestNewProxmoxCollector(t *testing.T) {
-	cfg := &config.ProxmoxConfig{
-		Host: "localhost",
-		User: "root@pam",
+	cfg := &config.Config{
+		Proxmox: config.ProxmoxConfig{
+			Host: "localhost",
+			User: "root@pam",
+		},
 	}
 
 	c := NewProxmoxCollector(cfg)
@@ -20,9 +22,11 @@ func TestNewProxmoxCollector(t *testing.T) {
 }
 
 func TestDescribe(t *testing.T) {
-	cfg := &config.ProxmoxConfig{
-		Host: "localhost",
-		User: "root@pam",
+	cfg := &config.Config{
+		Proxmox: config.ProxmoxConfig{
+			Host: "localhost",
+			User: "root@pam",
+		},
 	}
 
 	c := NewProxmoxCollector(cfg)
@@ -0,0 +1,213 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// contentTypes are the storage content types reported by the content collector
+var contentTypes = []string{"images", "rootdir", "backup", "iso", "vztmpl", "snippets"}
+
+// contentStorage represents an active storage whose content is listed by the content collectors
+type contentStorage struct {
+	Node    string
+	Storage string
+	Type    string
+	Content []string // content types configured on the storage (images, rootdir, backup, ...)
+	Shared  bool
+}
+
+// storageContentItem represents a volume from /nodes/{node}/storage/{storage}/content
+type storageContentItem struct {
+	VolID   string  `json:"volid"`
+	Content string  `json:"content"`
+	Format  string  `json:"format"`
+	Size    float64 `json:"size"`
+	VMID    int64   `json:"vmid"`
+}
+
+// hasContent checks if the storage is configured for any of the given content types
+func (s contentStorage) hasContent(types ...string) bool {
+	for _, have := range s.Content {
+		for _, want := range types {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// discoverContentStorages lists active storages on all nodes.
+// Shared storages are reported once, on the first node (in nodes order) that lists them.
+func (c *ProxmoxCollector) discoverContentStorages(nodes []string) []contentStorage {
+	perNode := make([][]contentStorage, len(nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, nodeName string) {
+			defer wg.Done()
+			perNode[i] = c.fetchNodeContentStorages(nodeName)
+		}(i, node)
+	}
+	wg.Wait()
+
+	var storages []contentStorage
+	seenShared := make(map[string]bool)
+	for _, nodeStorages := range perNode {
+		for _, s := range nodeStorages {
+			if s.Shared {
+				if seenShared[s.Storage] {
+					continue
+				}
+				seenShared[s.Storage] = true
+			}
+			storages = append(storages, s)
+		}
+	}
+	return storages
+}
+
+// fetchNodeContentStorages lists the active storages of a single node
+func (c *ProxmoxCollector) fetchNodeContentStorages(nodeName string) []contentStorage {
+	data, err := c.apiRequest(fmt.Sprintf("/nodes/%s/storage", nodeName))
+	if err != nil {
+		log.Printf("Error fetching storage for node %s: %v", nodeName, err)
+		return nil
+	}
+
+	var result struct {
+		Data []struct {
+			Storage string `json:"storage"`
+			Type    string `json:"type"`
+			Content string `json:"content"`
+			Active  int    `json:"active"`
+			Shared  int    `json:"shared"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		log.Printf("Error unmarshaling storage for node %s: %v", nodeName, err)
+		return nil
+	}
+
+	var storages []contentStorage
+	for _, s := range result.Data {
+		if s.Active != 1 {
+			continue
+		}
+		storages = append(storages, contentStorage{
+			Node:    nodeName,
+			Storage: s.Storage,
+			Type:    s.Type,
+			Content: strings.Split(s.Content, ","),
+			Shared:  s.Shared == 1,
+		})
+	}
+	return storages
+}
+
+// fetchStorageContent lists all volumes on a storage
+func (c *ProxmoxCollector) fetchStorageContent(s contentStorage) ([]storageContentItem, error) {
+	data, err := c.apiRequest(fmt.Sprintf("/nodes/%s/storage/%s/content", s.Node, url.PathEscape(s.Storage)))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []storageContentItem `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// collectStorageContentMetrics lists storage content once and feeds it to the enabled content collectors
+// (orphaned volume detection and content breakdown)
+func (c *ProxmoxCollector) collectStorageContentMetrics(ch chan<- prometheus.Metric, nodes []string, guests map[string]GuestInfo, configs *guestConfigCache) {
+	storages := c.discoverContentStorages(nodes)
+
+	var refs *volumeReferences
+	if c.collectors.Orphans.Enabled {
+		refs = c.buildVolumeReferences(guests, configs)
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range storages {
+		if !c.wantsStorageContent(s) {
+			continue
+		}
+		wg.Add(1)
+		go func(s contentStorage) {
+			defer wg.Done()
+
+			items, err := c.fetchStorageContent(s)
+			if err != nil {
+				log.Printf("Error fetching content of storage %s on node %s: %v", s.Storage, s.Node, err)
+				return
+			}
+
+			if refs != nil && s.hasContent("images", "rootdir") {
+				c.emitOrphanMetrics(ch, s, items, refs)
+			}
+			if c.collectors.Content.Enabled {
+				c.emitContentMetrics(ch, s, items, guests)
+			}
+		}(s)
+	}
+	wg.Wait()
+}
+
+// wantsStorageContent checks if any enabled content collector needs the content of a storage
+func (c *ProxmoxCollector) wantsStorageContent(s contentStorage) bool {
+	if c.collectors.Content.Enabled && s.hasContent(contentTypes...) {
+		return true
+	}
+	return c.collectors.Orphans.Enabled && s.hasContent("images", "rootdir")
+}
+
+// emitContentMetrics aggregates storage content by content type and by owning VMID. Per-guest series
+// are skipped for guests excluded by the filters; volumes of guests that no longer exist are kept.
+func (c *ProxmoxCollector) emitContentMetrics(ch chan<- prometheus.Metric, s contentStorage, items []storageContentItem, guests map[string]GuestInfo) {
+	type ownerKey struct {
+		vmid    string
+		content string
+	}
+
+	bytes := make(map[string]float64)
+	counts := make(map[string]float64)
+	for _, t := range contentTypes {
+		if s.hasContent(t) {
+			bytes[t] = 0
+			counts[t] = 0
+		}
+	}
+	owners := make(map[ownerKey]float64)
+
+	for _, item := range items {
+		bytes[item.Content] += item.Size
+		counts[item.Content]++
+		if item.VMID > 0 {
+			owners[ownerKey{strconv.FormatInt(item.VMID, 10), item.Content}] += item.Size
+		}
+	}
+
+	for content, total := range bytes {
+		ch <- prometheus.MustNewConstMetric(c.storageContentBytes, prometheus.GaugeValue, total, s.Node, s.Storage, content)
+		ch <- prometheus.MustNewConstMetric(c.storageContentVolumes, prometheus.GaugeValue, counts[content], s.Node, s.Storage, content)
+	}
+	for owner, total := range owners {
+		if guest, ok := guests[owner.vmid]; ok && c.filter.classify(owner.vmid, guest) == filterExclude {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.storageContentGuestBytes, prometheus.GaugeValue, total, s.Node, s.Storage, owner.vmid, owner.content)
+	}
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEmitContentMetrics(t *testing.T) {
+	cfg := &config.Config{Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"}}
+	c := NewProxmoxCollector(cfg)
+	s := contentStorage{Node: "pve1", Storage: "nfs", Content: []string{"images", "backup", "iso"}, Shared: true}
+	items := []storageContentItem{
+		{VolID: "nfs:100/vm-100-disk-0.qcow2", Content: "images", Size: 100, VMID: 100},
+		{VolID: "nfs:backup/vzdump-qemu-100-2026_01_01.vma.zst", Content: "backup", Size: 50, VMID: 100},
+		{VolID: "nfs:backup/vzdump-qemu-100-2026_01_02.vma.zst", Content: "backup", Size: 60, VMID: 100},
+		{VolID: "nfs:iso/debian.iso", Content: "iso", Size: 700},
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	c.emitContentMetrics(ch, s, items, map[string]GuestInfo{"100": {Name: "web"}})
+	close(ch)
+
+	contentBytes := make(map[string]float64)
+	contentVolumes := make(map[string]float64)
+	guestBytes := make(map[string]float64)
+	for _, m := range collectMetrics(ch) {
+		labels := metricLabels(m)
+		switch m.Desc() {
+		case c.storageContentBytes:
+			contentBytes[labels["content"]] = getMetricValue(m)
+		case c.storageContentVolumes:
+			contentVolumes[labels["content"]] = getMetricValue(m)
+		case c.storageContentGuestBytes:
+			guestBytes[labels["vmid"]+"/"+labels["content"]] = getMetricValue(m)
+		}
+	}
+
+	if contentBytes["backup"] != 110 || contentVolumes["backup"] != 2 {
+		t.Errorf("unexpected backup totals: %f bytes, %f volumes", contentBytes["backup"], contentVolumes["backup"])
+	}
+	if contentBytes["iso"] != 700 || contentBytes["images"] != 100 {
+		t.Errorf("unexpected content bytes: %v", contentBytes)
+	}
+	if len(guestBytes) != 2 || guestBytes["100/backup"] != 110 || guestBytes["100/images"] != 100 {
+		t.Errorf("unexpected guest bytes: %v", guestBytes)
+	}
+}
+
+func TestEmitContentMetricsFiltered(t *testing.T) {
+	c := NewProxmoxCollector(&config.Config{
+		Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"},
+		Filters: config.FiltersConfig{Exclude: []config.GuestFilterRule{{Name: "^ci-"}}},
+	})
+	s := contentStorage{Node: "pve1", Storage: "local", Content: []string{"backup"}}
+	items := []storageContentItem{
+		{VolID: "local:backup/vzdump-qemu-100.vma.zst", Content: "backup", Size: 10, VMID: 100},
+		{VolID: "local:backup/vzdump-lxc-200.tar.zst", Content: "backup", Size: 20, VMID: 200},
+		{VolID: "local:backup/vzdump-qemu-300.vma.zst", Content: "backup", Size: 30, VMID: 300}, // guest deleted
+	}
+	guests := map[string]GuestInfo{"100": {Name: "web"}, "200": {Name: "ci-runner"}}
+
+	ch := make(chan prometheus.Metric, 100)
+	c.emitContentMetrics(ch, s, items, guests)
+	close(ch)
+
+	guestBytes := make(map[string]float64)
+	var total float64
+	for _, m := range collectMetrics(ch) {
+		switch m.Desc() {
+		case c.storageContentBytes:
+			total = getMetricValue(m)
+		case c.storageContentGuestBytes:
+			guestBytes[metricLabels(m)["vmid"]] = getMetricValue(m)
+		}
+	}
+
+	if total != 60 {
+		t.Errorf("expected totals to include excluded guests, got %v", total)
+	}
+	if len(guestBytes) != 2 || guestBytes["100"] != 10 || guestBytes["300"] != 30 {
+		t.Errorf("expected excluded guest 200 to be skipped, got %v", guestBytes)
+	}
+}
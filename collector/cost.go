@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const bytesPerGiB = 1 << 30
+
+// guestCost holds the hourly cost of a guest's allocated resources
+type guestCost struct {
+	VMID    string
+	Guest   GuestInfo
+	CPU     float64
+	Memory  float64
+	Storage float64
+}
+
+// Total returns the total hourly cost
+func (g guestCost) Total() float64 {
+	return g.CPU + g.Memory + g.Storage
+}
+
+// splitTags splits a PVE tag string; PVE accepts ";", "," and spaces as separators
+func splitTags(tags string) []string {
+	return strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ';' || r == ',' || r == ' '
+	})
+}
+
+// storagePrice returns the hourly price of the given bytes on a storage type, or 0 if the type has no price
+func (c *ProxmoxCollector) storagePrice(storageType string, bytes float64) float64 {
+	return c.cost.StorageGiBHour[storageType] * bytes / bytesPerGiB
+}
+
+// computeGuestCost prices a guest's allocation. CPU and memory are charged only while the
+// guest is running; disks are charged by the type of the storage they live on.
+func (c *ProxmoxCollector) computeGuestCost(vmid string, guest GuestInfo, disks []guestDisk, storageTypes map[string]string) guestCost {
+	cost := guestCost{VMID: vmid, Guest: guest}
+	if guest.Status == "running" {
+		cost.CPU = guest.MaxCPU * c.cost.CPUHour
+		cost.Memory = guest.MaxMem / bytesPerGiB * c.cost.MemoryGiBHour
+	}
+	for _, disk := range disks {
+		cost.Storage += c.storagePrice(storageTypes[disk.Storage], disk.Size)
+	}
+	return cost
+}
+
+// fetchStorageTypes maps storage IDs to their type using the cluster storage configuration
+func (c *ProxmoxCollector) fetchStorageTypes() (map[string]string, error) {
+	data, err := c.apiRequest("/storage")
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			Storage string `json:"storage"`
+			Type    string `json:"type"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string, len(result.Data))
+	for _, s := range result.Data {
+		types[s.Storage] = s.Type
+	}
+	return types, nil
+}
+
+// collectCostMetrics exports hourly cost by guest, pool and tag and accrues it into the chargeback ledger
+func (c *ProxmoxCollector) collectCostMetrics(ch chan<- prometheus.Metric, guests map[string]GuestInfo, configs *guestConfigCache) {
+	storageTypes, err := c.fetchStorageTypes()
+	if err != nil {
+		log.Printf("Error fetching storage types for cost metrics: %v", err)
+		storageTypes = map[string]string{}
+	}
+
+	var costs []guestCost
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for vmid, guest := range guests {
+		if guest.Template {
+			continue
+		}
+		wg.Add(1)
+		go func(vmid string, guest GuestInfo) {
+			defer wg.Done()
+			var disks []guestDisk
+			if cfg, err := configs.get(vmid, guest); err == nil {
+				disks = parseGuestDisks(cfg, guest.Type)
+			}
+			cost := c.computeGuestCost(vmid, guest, disks, storageTypes)
+			mu.Lock()
+			costs = append(costs, cost)
+			mu.Unlock()
+		}(vmid, guest)
+	}
+	wg.Wait()
+
+	c.emitCostMetrics(ch, costs)
+
+	if c.ledger != nil {
+		c.ledger.accrue(time.Now(), costs)
+	}
+}
+
+// emitCostMetrics emits per-guest costs along with pool and tag aggregates
+func (c *ProxmoxCollector) emitCostMetrics(ch chan<- prometheus.Metric, costs []guestCost) {
+	pools := make(map[string]float64)
+	tags := make(map[string]float64)
+
+	for _, cost := range costs {
+		labels := []string{cost.Guest.Node, cost.VMID, cost.Guest.Name, cost.Guest.Type, cost.Guest.Pool}
+		for resource, v := range map[string]float64{"cpu": cost.CPU, "memory": cost.Memory, "storage": cost.Storage} {
+			ch <- prometheus.MustNewConstMetric(c.costGuestHourly, prometheus.GaugeValue, v, append(labels, resource)...)
+		}
+
+		if cost.Guest.Pool != "" {
+			pools[cost.Guest.Pool] += cost.Total()
+		}
+		for _, tag := range splitTags(cost.Guest.Tags) {
+			tags[tag] += cost.Total()
+		}
+	}
+
+	for pool, v := range pools {
+		ch <- prometheus.MustNewConstMetric(c.costPoolHourly, prometheus.GaugeValue, v, pool)
+	}
+	for tag, v := range tags {
+		ch <- prometheus.MustNewConstMetric(c.costTagHourly, prometheus.GaugeValue, v, tag)
+	}
+}
@@ -0,0 +1,245 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newCostTestCollector(ledgerFile string) *ProxmoxCollector {
+	return NewProxmoxCollector(&config.Config{
+		Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"},
+		Cost: config.CostConfig{
+			Enabled:        true,
+			Currency:       "EUR",
+			CPUHour:        0.01,
+			MemoryGiBHour:  0.005,
+			StorageGiBHour: map[string]float64{"zfspool": 0.0001},
+			LedgerFile:     ledgerFile,
+		},
+	})
+}
+
+func TestSplitTags(t *testing.T) {
+	got := splitTags("team-a;prod, web")
+	want := []string{"team-a", "prod", "web"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitTags() = %v, want %v", got, want)
+	}
+	if len(splitTags("")) != 0 {
+		t.Error("expected no tags for empty string")
+	}
+}
+
+func TestComputeGuestCost(t *testing.T) {
+	c := newCostTestCollector("")
+	disks := []guestDisk{
+		{Storage: "local-zfs", Size: 100 * bytesPerGiB},
+		{Storage: "nfs", Size: 50 * bytesPerGiB}, // no price for nfs
+	}
+	types := map[string]string{"local-zfs": "zfspool", "nfs": "nfs"}
+
+	running := c.computeGuestCost("100", GuestInfo{Status: "running", MaxCPU: 4, MaxMem: 8 * bytesPerGiB}, disks, types)
+	if running.CPU != 0.04 || running.Memory != 0.04 || running.Storage != 0.01 {
+		t.Errorf("unexpected running guest cost: %+v", running)
+	}
+
+	stopped := c.computeGuestCost("101", GuestInfo{Status: "stopped", MaxCPU: 4, MaxMem: 8 * bytesPerGiB}, disks, types)
+	if stopped.CPU != 0 || stopped.Memory != 0 || stopped.Storage != 0.01 {
+		t.Errorf("stopped guest should only pay for storage, got %+v", stopped)
+	}
+}
+
+func TestEmitCostMetrics(t *testing.T) {
+	c := newCostTestCollector("")
+	costs := []guestCost{
+		{VMID: "100", Guest: GuestInfo{Pool: "web", Tags: "team-a;prod"}, CPU: 1, Memory: 2},
+		{VMID: "101", Guest: GuestInfo{Pool: "web", Tags: "team-b"}, Storage: 3},
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	c.emitCostMetrics(ch, costs)
+	close(ch)
+
+	pools := make(map[string]float64)
+	tags := make(map[string]float64)
+	for m := range ch {
+		labels := metricLabels(m)
+		switch m.Desc() {
+		case c.costPoolHourly:
+			pools[labels["pool"]] = getMetricValue(m)
+		case c.costTagHourly:
+			tags[labels["tag"]] = getMetricValue(m)
+		}
+	}
+
+	if pools["web"] != 6 {
+		t.Errorf("expected pool web cost 6, got %v", pools["web"])
+	}
+	if tags["team-a"] != 3 || tags["prod"] != 3 || tags["team-b"] != 3 {
+		t.Errorf("unexpected tag costs: %v", tags)
+	}
+}
+
+func TestCostLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := newCostLedger(path, 0)
+
+	costs := []guestCost{
+		{VMID: "100", Guest: GuestInfo{Name: "web", Status: "running", Pool: "web", Tags: "team-a"}, CPU: 1, Memory: 1},
+		{VMID: "101", Guest: GuestInfo{Name: "db", Status: "stopped"}, Storage: 2},
+	}
+
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	l.accrue(start, costs) // first call only sets the reference time
+	l.accrue(start.Add(6*time.Minute), costs)
+	l.accrue(start.Add(12*time.Minute), costs)
+	// A long gap is capped to maxAccrualGap
+	l.accrue(start.Add(12*time.Minute+2*time.Hour), costs)
+
+	hours := (12*time.Minute + maxAccrualGap).Hours()
+	rows := newCostLedger(path, 0).report("2026-10", "guest")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows from persisted ledger, got %d", len(rows))
+	}
+	if diff := rows[0].Total - 2*hours; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("expected guest 100 total %v, got %v", 2*hours, rows[0].Total)
+	}
+	if rows[1].RunningHours != 0 {
+		t.Errorf("stopped guest should have no running hours, got %v", rows[1].RunningHours)
+	}
+
+	pools := l.report("2026-10", "pool")
+	if len(pools) != 2 || pools[0].Key != "(none)" || pools[1].Key != "web" {
+		t.Errorf("unexpected pool rows: %+v", pools)
+	}
+}
+
+func TestCostLedgerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := newCostLedger(path, 2)
+	l.Months["2026-08"] = map[string]*chargebackEntry{"100": {VMID: "100", CPU: 1}}
+	l.Months["2026-09"] = map[string]*chargebackEntry{"100": {VMID: "100", CPU: 1}}
+
+	costs := []guestCost{{VMID: "100", Guest: GuestInfo{Status: "running"}, CPU: 1}}
+	start := time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)
+	l.accrue(start, costs)
+	l.accrue(start.Add(time.Minute), costs) // first save
+	l.accrue(start.Add(2*time.Minute), costs)
+
+	saved := newCostLedger(path, 0)
+	if _, ok := saved.Months["2026-08"]; ok {
+		t.Error("expected months beyond the retention to be pruned")
+	}
+	if _, ok := saved.Months["2026-09"]; !ok {
+		t.Error("expected the previous month to be kept")
+	}
+	if rows := saved.report("2026-10", "guest"); len(rows) != 1 || rows[0].CPU*60 > 1+1e-9 {
+		t.Errorf("expected only the first minute to be saved before the save interval, got %+v", rows)
+	}
+
+	l.flush()
+	if rows := newCostLedger(path, 0).report("2026-10", "guest"); len(rows) != 1 || rows[0].CPU*60 < 2-1e-9 {
+		t.Errorf("expected flush to save pending changes, got %+v", rows)
+	}
+}
+
+func TestChargebackHandler(t *testing.T) {
+	c := newCostTestCollector("")
+	start := time.Now()
+	costs := []guestCost{{VMID: "100", Guest: GuestInfo{Name: "web", Status: "running", Tags: "team-a"}, CPU: 1}}
+	c.ledger.accrue(start.Add(-time.Minute), costs)
+	c.ledger.accrue(start, costs)
+	month := start.UTC().Format("2006-01")
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"?format=csv&group=tag&month=" + month, 200, "tag,cpu,memory,storage,total\nteam-a,"},
+		{"?month=" + month, 200, `"currency":"EUR"`},
+		{"?month=2026-13", 400, "YYYY-MM"},
+		{"?group=node", 400, "group"},
+		{"?format=xml", 400, "format"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c.ChargebackHandler()(rec, httptest.NewRequest("GET", "/chargeback"+tt.query, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.query, tt.wantStatus, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("%s: expected body to contain %q, got %q", tt.query, tt.wantBody, rec.Body.String())
+		}
+	}
+}
+
+func TestEmitStorageCostMetrics(t *testing.T) {
+	c := newCostTestCollector("")
+	c.cost.StorageGiBHour["nfs"] = 0.0002
+	storages := func() []storageStatus {
+		return []storageStatus{
+			{Storage: "local-zfs", Type: "zfspool", Used: 10 * bytesPerGiB},
+			{Storage: "backup", Type: "nfs", Used: 100 * bytesPerGiB, Shared: 1},
+			{Storage: "local", Type: "dir", Used: 5 * bytesPerGiB}, // no price for dir
+		}
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	c.emitStorageCostMetrics(ch, []string{"pve1", "pve2"}, [][]storageStatus{storages(), storages()})
+	close(ch)
+
+	got := make(map[string]float64)
+	for _, m := range collectMetrics(ch) {
+		labels := metricLabels(m)
+		got[labels["node"]+"/"+labels["storage"]] = getMetricValue(m)
+	}
+
+	want := map[string]float64{"pve1/local-zfs": 0.001, "pve2/local-zfs": 0.001, "pve1/backup": 0.02}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d storage cost metrics, got %v", len(want), got)
+	}
+	for key, value := range want {
+		if diff := got[key] - value; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s: expected %v, got %v", key, value, got[key])
+		}
+	}
+}
+
+func TestCollectCostIgnoresFilters(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]interface{}{{"node": "pve1"}}))
+	mux.HandleFunc("/api2/json/cluster/resources", jsonHandler([]map[string]interface{}{
+		{"vmid": 100, "node": "pve1", "name": "web", "type": "qemu", "status": "running", "maxcpu": 2},
+		{"vmid": 200, "node": "pve1", "name": "ci-runner", "type": "lxc", "status": "running", "maxcpu": 1},
+	}))
+	mux.HandleFunc("/api2/json/storage", jsonHandler([]map[string]interface{}{}))
+	mux.HandleFunc("/api2/json/nodes/pve1/", jsonHandler(map[string]interface{}{}))
+
+	c := newTestCollector(t, mux)
+	c.cost = newCostTestCollector("").cost
+	c.ledger = newCostLedger("", 0)
+	c.filter = mustGuestFilter(t, config.FiltersConfig{Exclude: []config.GuestFilterRule{{Name: "^ci-"}}})
+
+	ch := make(chan prometheus.Metric, 100)
+	c.collect(func(string) chan<- prometheus.Metric { return ch }, map[string]bool{"cost": true}, false)
+	close(ch)
+
+	charged := make(map[string]bool)
+	for m := range ch {
+		if m.Desc() == c.costGuestHourly {
+			charged[metricLabels(m)["vmid"]] = true
+		}
+	}
+	if !charged["100"] || !charged["200"] {
+		t.Errorf("expected excluded guests to be charged too, got %v", charged)
+	}
+}
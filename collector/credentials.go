@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+// secretSource returns the current value of a secret
+type secretSource interface {
+	Get() (config.Secret, error)
+}
+
+// inlineSecret is a secret set directly in the config file or environment
+type inlineSecret config.Secret
+
+// Get returns the inline secret
+func (s inlineSecret) Get() (config.Secret, error) {
+	return config.Secret(s), nil
+}
+
+// newSecretSource picks the password or token secret source: a credential helper, then a secret file,
+// then the inline value. The helper provides the token secret if a token ID is set, else the password.
+func newSecretSource(cfg *config.ProxmoxConfig) secretSource {
+	if len(cfg.CredentialHelper.Command) > 0 {
+		return config.NewCredentialHelper(cfg.CredentialHelper)
+	}
+	if cfg.UsesToken() {
+		if cfg.TokenSecretFile != "" {
+			return config.NewSecretFile(cfg.TokenSecretFile)
+		}
+		return inlineSecret(cfg.TokenSecret)
+	}
+	if cfg.PasswordFile != "" {
+		return config.NewSecretFile(cfg.PasswordFile)
+	}
+	return inlineSecret(cfg.Password)
+}
@@ -144,6 +144,7 @@ func (c *ProxmoxCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.clusterNodesOnline
 	ch <- c.haResourcesTotal
 	ch <- c.haResourcesActive
+	ch <- c.haResourcesError
 
 	// Replication
 	ch <- c.replicationLastSync
@@ -152,4 +153,89 @@ func (c *ProxmoxCollector) Describe(ch chan<- *prometheus.Desc) {
 
 	// Certificate
 	ch <- c.certificateExpiry
+
+	// Guest configuration
+	ch <- c.guestInfo
+	ch <- c.guestPendingChanges
+	ch <- c.guestDiskSize
+	ch <- c.guestDiskInfo
+
+	// Orphaned volumes
+	ch <- c.storageOrphanedVolumes
+	ch <- c.storageOrphanedBytes
+
+	// Storage content
+	ch <- c.storageContentBytes
+	ch <- c.storageContentVolumes
+	ch <- c.storageContentGuestBytes
+
+	// RRD
+	ch <- c.nodeRRDCPU
+	ch <- c.nodeRRDIOWait
+	ch <- c.nodeRRDLoadAvg
+	ch <- c.nodeRRDMemoryUsed
+	ch <- c.nodeRRDRootfsUsed
+	ch <- c.nodeRRDSwapUsed
+	ch <- c.nodeRRDNetIn
+	ch <- c.nodeRRDNetOut
+	ch <- c.storageRRDTotal
+	ch <- c.storageRRDUsed
+
+	// Right-sizing
+	ch <- c.rightsizingCPUUsage
+	ch <- c.rightsizingMemoryUsage
+	ch <- c.rightsizingCPUAllocated
+	ch <- c.rightsizingMemoryAllocated
+	ch <- c.rightsizingRecommendedCPUs
+	ch <- c.rightsizingRecommendedMem
+	ch <- c.rightsizingIdle
+	ch <- c.rightsizingLastRefresh
+
+	// Capacity
+	ch <- c.nodeCPUAllocated
+	ch <- c.nodeMemoryAllocated
+	ch <- c.nodeCPUOvercommit
+	ch <- c.nodeMemoryOvercommit
+	ch <- c.clusterCPUAllocated
+	ch <- c.clusterMemoryAllocated
+	ch <- c.clusterCPUTotal
+	ch <- c.clusterMemoryTotal
+	ch <- c.clusterCPUOvercommit
+	ch <- c.clusterMemoryOvercommit
+	ch <- c.clusterN1Headroom
+	ch <- c.clusterN1Fits
+
+	// Cost
+	ch <- c.costGuestHourly
+	ch <- c.costPoolHourly
+	ch <- c.costTagHourly
+	ch <- c.costStorageHourly
+
+	// Unified guest metrics
+	ch <- c.guestStatus
+	ch <- c.guestUptime
+	ch <- c.guestCPU
+	ch <- c.guestCPUs
+	ch <- c.guestMemory
+	ch <- c.guestMaxMemory
+	ch <- c.guestMaxDisk
+	ch <- c.guestNetIn
+	ch <- c.guestNetOut
+	ch <- c.guestDiskRead
+	ch <- c.guestDiskWrite
+	ch <- c.guestHAManaged
+	ch <- c.guestPID
+	ch <- c.guestPressureCPUFull
+	ch <- c.guestPressureCPUSome
+	ch <- c.guestPressureIOFull
+	ch <- c.guestPressureIOSome
+	ch <- c.guestPressureMemoryFull
+	ch <- c.guestPressureMemorySome
+	ch <- c.guestLastBackup
+
+	// Exporter self metrics
+	ch <- c.exporterTLSInsecure
+	ch <- c.exporterEndpointActive
+	ch <- c.exporterEndpointSwitches
+	ch <- c.exporterPermissionOK
 }
@@ -36,7 +36,7 @@ func getMetricValue(m prometheus.Metric) float64 {
 }
 
 func TestParseNVMeSmartText(t *testing.T) {
-	cfg := &config.ProxmoxConfig{Host: "localhost", User: "root@pam"}
+	cfg := &config.Config{Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"}}
 	c := NewProxmoxCollector(cfg)
 
 	nvmeText := `
@@ -103,7 +103,7 @@ Temperature Sensor 2:               76 Celsius
 }
 
 func TestParseATASmartAttrs(t *testing.T) {
-	cfg := &config.ProxmoxConfig{Host: "localhost", User: "root@pam"}
+	cfg := &config.Config{Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"}}
 	c := NewProxmoxCollector(cfg)
 
 	attrs := []ataSmartAttr{
@@ -203,12 +203,14 @@ func TestCollectNodeDiskSMART(t *testing.T) {
 		fmt.Sscanf(parts[1], "%d", &port)
 	}
 
-	cfg := &config.ProxmoxConfig{
-		Host:               host,
-		Port:               port,
-		TokenID:            "test@pve!test",
-		TokenSecret:        "test-secret",
-		InsecureSkipVerify: true,
+	cfg := &config.Config{
+		Proxmox: config.ProxmoxConfig{
+			Host:               host,
+			Port:               port,
+			TokenID:            "test@pve!test",
+			TokenSecret:        "test-secret",
+			InsecureSkipVerify: true,
+		},
 	}
 	c := NewProxmoxCollector(cfg)
 	c.client = server.Client()
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CollectByName runs a single scrape of the named collectors, or of all enabled collectors if names is empty,
+// and returns the metrics grouped by collector. Background results such as right-sizing are computed before returning.
+func (c *ProxmoxCollector) CollectByName(names []string) (map[string][]prometheus.Metric, error) {
+	var selected map[string]bool
+	if len(names) > 0 {
+		known := CollectorNames()
+		selected = make(map[string]bool)
+		for _, name := range names {
+			if !slices.Contains(known, name) {
+				return nil, fmt.Errorf("unknown collector %q", name)
+			}
+			selected[name] = true
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	channels := make(map[string]chan prometheus.Metric)
+	metrics := make(map[string][]prometheus.Metric)
+
+	out := func(name string) chan<- prometheus.Metric {
+		mu.Lock()
+		defer mu.Unlock()
+		if ch, ok := channels[name]; ok {
+			return ch
+		}
+		ch := make(chan prometheus.Metric)
+		channels[name] = ch
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var collected []prometheus.Metric
+			for m := range ch {
+				collected = append(collected, m)
+			}
+			mu.Lock()
+			metrics[name] = collected
+			mu.Unlock()
+		}()
+		return ch
+	}
+
+	c.collect(out, selected, true)
+
+	mu.Lock()
+	for _, ch := range channels {
+		close(ch)
+	}
+	mu.Unlock()
+	wg.Wait()
+
+	// The exporter's own metrics are always collected, but only returned when asked for
+	if selected != nil && !selected[exporterCollector] {
+		delete(metrics, exporterCollector)
+	}
+	return metrics, nil
+}
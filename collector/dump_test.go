@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCollectByName(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]interface{}{
+		{"node": "pve1", "status": "online", "cpu": 0.25, "maxcpu": 8},
+	}))
+	c := newTestCollector(t, mux)
+
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single collector", names: []string{"node"}, want: []string{"node"}},
+		{name: "with exporter metrics", names: []string{"node", "exporter"}, want: []string{"exporter", "node"}},
+		{name: "unknown collector", names: []string{"nodes"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := c.CollectByName(tt.names)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CollectByName failed: %v", err)
+			}
+			if len(metrics) != len(tt.want) {
+				t.Errorf("got collectors %v, want %v", metrics, tt.want)
+			}
+			for _, name := range tt.want {
+				if len(metrics[name]) == 0 {
+					t.Errorf("no metrics for collector %s", name)
+				}
+			}
+			for _, m := range metrics["node"] {
+				if !strings.HasPrefix(m.Desc().String(), `Desc{fqName: "pve_node_`) {
+					t.Errorf("unexpected metric in node collector: %s", m.Desc())
+				}
+			}
+		})
+	}
+}
+
+func TestCollectByNameAll(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]interface{}{{"node": "pve1", "status": "online"}}))
+	c := newTestCollector(t, mux)
+
+	metrics, err := c.CollectByName(nil)
+	if err != nil {
+		t.Fatalf("CollectByName failed: %v", err)
+	}
+	for _, name := range []string{"exporter", "node"} {
+		if len(metrics[name]) == 0 {
+			t.Errorf("no metrics for collector %s", name)
+		}
+	}
+	if _, ok := metrics["rrd"]; ok {
+		t.Error("disabled rrd collector was run")
+	}
+}
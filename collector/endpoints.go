@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// endpointCheckTimeout bounds health checks so failover doesn't wait for the full API timeout
+const endpointCheckTimeout = 5 * time.Second
+
+// endpointError is returned when an API endpoint could not be reached at all,
+// as opposed to answering with an error status
+type endpointError struct {
+	err error
+}
+
+func (e *endpointError) Error() string { return e.err.Error() }
+func (e *endpointError) Unwrap() error { return e.err }
+
+// endpointPool tracks the PVE API endpoints and the one currently in use.
+// The first endpoint is the configured host, which is preferred whenever it is healthy.
+type endpointPool struct {
+	mu        sync.RWMutex
+	endpoints []string // "host:port", configured endpoints first, then discovered ones
+	active    string
+	switches  int
+
+	switching sync.Mutex  // serializes failovers
+	checking  atomic.Bool // a primary health check is running
+}
+
+// newEndpointPool creates a pool starting on the first endpoint
+func newEndpointPool(endpoints []string) *endpointPool {
+	p := &endpointPool{}
+	p.add(endpoints...)
+	p.active = p.endpoints[0]
+	return p
+}
+
+// current returns the endpoint in use
+func (p *endpointPool) current() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.active
+}
+
+// primary returns the preferred endpoint
+func (p *endpointPool) primary() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.endpoints[0]
+}
+
+// snapshot returns all known endpoints, the active one and the number of switches so far
+func (p *endpointPool) snapshot() ([]string, string, int) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.endpoints...), p.active, p.switches
+}
+
+// add appends endpoints that are not known yet
+func (p *endpointPool) add(endpoints ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, endpoint := range endpoints {
+		known := false
+		for _, e := range p.endpoints {
+			if e == endpoint {
+				known = true
+				break
+			}
+		}
+		if !known {
+			p.endpoints = append(p.endpoints, endpoint)
+		}
+	}
+}
+
+// setActive switches to the given endpoint
+func (p *endpointPool) setActive(endpoint string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active != endpoint {
+		p.active = endpoint
+		p.switches++
+	}
+}
+
+// checkEndpoint verifies that an endpoint answers API requests, re-authenticating against it for ticket auth
+func (c *ProxmoxCollector) checkEndpoint(endpoint string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), endpointCheckTimeout)
+	defer cancel()
+
+	if !c.config.UsesToken() {
+		if err := c.authenticateTo(ctx, endpoint); err != nil {
+			return err
+		}
+	}
+	_, err := c.apiRequestTo(ctx, endpoint, "/version")
+	return err
+}
+
+// failover switches away from an unreachable endpoint to the first healthy one and returns it
+func (c *ProxmoxCollector) failover(failed string) (string, error) {
+	c.endpoints.switching.Lock()
+	defer c.endpoints.switching.Unlock()
+
+	// Another request already failed over while we were waiting
+	if current := c.endpoints.current(); current != failed {
+		return current, nil
+	}
+
+	endpoints, _, _ := c.endpoints.snapshot()
+	for _, candidate := range endpoints {
+		if candidate == failed {
+			continue
+		}
+		if err := c.checkEndpoint(candidate); err != nil {
+			log.Printf("Error checking Proxmox API endpoint %s: %v", candidate, err)
+			continue
+		}
+		log.Printf("Proxmox API endpoint %s is unreachable, switching to %s", failed, candidate)
+		c.endpoints.setActive(candidate)
+		return candidate, nil
+	}
+	return "", fmt.Errorf("no reachable Proxmox API endpoint")
+}
+
+// checkPrimaryEndpoint switches back to the primary endpoint in the background once it is healthy again
+func (c *ProxmoxCollector) checkPrimaryEndpoint() {
+	primary := c.endpoints.primary()
+	if c.endpoints.current() == primary || !c.endpoints.checking.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer c.endpoints.checking.Store(false)
+
+		c.endpoints.switching.Lock()
+		defer c.endpoints.switching.Unlock()
+		if c.checkEndpoint(primary) == nil {
+			log.Printf("Proxmox API endpoint %s is reachable again, switching back", primary)
+			c.endpoints.setActive(primary)
+		}
+	}()
+}
+
+// collectEndpointMetrics exports which API endpoint is in use and how often it changed
+func (c *ProxmoxCollector) collectEndpointMetrics(ch chan<- prometheus.Metric) {
+	endpoints, active, switches := c.endpoints.snapshot()
+	for _, endpoint := range endpoints {
+		value := 0.0
+		if endpoint == active {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.exporterEndpointActive, prometheus.GaugeValue, value, endpoint)
+	}
+	ch <- prometheus.MustNewConstMetric(c.exporterEndpointSwitches, prometheus.CounterValue, float64(switches))
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newFailoverCollector returns a collector whose primary endpoint is down and whose fallback is served by mux
+func newFailoverCollector(t *testing.T, mux *http.ServeMux, proxmox config.ProxmoxConfig) *ProxmoxCollector {
+	t.Helper()
+
+	down := httptest.NewTLSServer(http.NotFoundHandler())
+	down.Close()
+	up := httptest.NewTLSServer(mux)
+	t.Cleanup(up.Close)
+
+	proxmox.Host = strings.TrimPrefix(down.URL, "https://")
+	proxmox.Hosts = []string{strings.TrimPrefix(up.URL, "https://")}
+	c := NewProxmoxCollector(&config.Config{Proxmox: proxmox})
+	c.client = up.Client()
+	return c
+}
+
+func TestAPIRequestFailover(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/version", jsonHandler(map[string]string{"version": "8.2"}))
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]string{{"node": "pve2"}}))
+
+	c := newFailoverCollector(t, mux, config.ProxmoxConfig{TokenID: "test@pve!test", TokenSecret: "test-secret"})
+	primary := c.endpoints.current()
+
+	_, nodes, err := c.fetchNodes()
+	if err != nil {
+		t.Fatalf("fetchNodes failed despite a healthy fallback: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0] != "pve2" {
+		t.Errorf("unexpected nodes %v", nodes)
+	}
+
+	endpoints, active, switches := c.endpoints.snapshot()
+	if active == primary || active != endpoints[1] {
+		t.Errorf("expected fallback endpoint to be active, got %s", active)
+	}
+	if switches != 1 {
+		t.Errorf("expected 1 switch, got %d", switches)
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	c.collectEndpointMetrics(ch)
+	close(ch)
+	activeSeries := 0
+	for m := range ch {
+		if getMetricValue(m) == 1 && metricLabels(m)["endpoint"] == active {
+			activeSeries++
+		}
+	}
+	if activeSeries != 1 {
+		t.Errorf("expected the fallback endpoint to be reported active")
+	}
+}
+
+func TestAuthenticateFailover(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/access/ticket", jsonHandler(map[string]string{"ticket": "fallback-ticket", "CSRFPreventionToken": "csrf"}))
+	mux.HandleFunc("/api2/json/version", jsonHandler(map[string]string{"version": "8.2"}))
+
+	c := newFailoverCollector(t, mux, config.ProxmoxConfig{User: "root@pam", Password: "secret"})
+
+	if err := c.authenticate(); err != nil {
+		t.Fatalf("authenticate failed despite a healthy fallback: %v", err)
+	}
+	if c.ticket != "fallback-ticket" {
+		t.Errorf("expected ticket from the fallback endpoint, got %q", c.ticket)
+	}
+}
+
+func TestAPIRequestNoFailoverOnHTTPError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/version", jsonHandler(map[string]string{"version": "8.2"}))
+
+	c := newFailoverCollector(t, mux, config.ProxmoxConfig{TokenID: "test@pve!test", TokenSecret: "test-secret"})
+	if _, err := c.apiRequest("/version"); err != nil {
+		t.Fatalf("request after failover failed: %v", err)
+	}
+
+	// A 404 from a reachable endpoint must not trigger another switch
+	if _, err := c.apiRequest("/missing"); err == nil {
+		t.Fatal("expected error for missing path")
+	}
+	if _, _, switches := c.endpoints.snapshot(); switches != 1 {
+		t.Errorf("expected 1 switch, got %d", switches)
+	}
+}
+
+func TestDiscoverEndpoints(t *testing.T) {
+	c := NewProxmoxCollector(&config.Config{Proxmox: config.ProxmoxConfig{Host: "pve1", Port: 8006, DiscoverHosts: true}})
+	c.discoverEndpoints([]clusterStatusEntry{
+		{Type: "cluster", Name: "prod"},
+		{Type: "node", Name: "pve1", Online: 1, IP: "10.0.0.1"},
+		{Type: "node", Name: "pve2", Online: 1, IP: "10.0.0.2"},
+		{Type: "node", Name: "pve3", Online: 0, IP: "10.0.0.3"},
+		{Type: "node", Name: "pve2", Online: 1, IP: "10.0.0.2"},
+	})
+
+	endpoints, active, _ := c.endpoints.snapshot()
+	want := []string{"pve1:8006", "10.0.0.1:8006", "10.0.0.2:8006"}
+	if strings.Join(endpoints, ",") != strings.Join(want, ",") {
+		t.Errorf("expected endpoints %v, got %v", want, endpoints)
+	}
+	if active != "pve1:8006" {
+		t.Errorf("expected configured host to stay active, got %s", active)
+	}
+}
@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+// filterAction is the outcome of matching a guest against the configured filters
+type filterAction int
+
+const (
+	filterFull    filterAction = iota // collect everything
+	filterLight                       // export status only, without per-guest API calls
+	filterExclude                     // export nothing
+)
+
+// vmidRange is an inclusive range of VMIDs
+type vmidRange struct {
+	from, to int64
+}
+
+// guestFilterRule is a compiled config.GuestFilterRule
+type guestFilterRule struct {
+	name  *regexp.Regexp
+	vmids []vmidRange
+	tags  map[string]bool
+	pools map[string]bool
+	nodes map[string]bool
+	typ   string
+}
+
+// guestFilter classifies guests using include, exclude and light rules
+type guestFilter struct {
+	include []guestFilterRule
+	exclude []guestFilterRule
+	light   []guestFilterRule
+}
+
+// toSet converts a list to a set, returning nil for an empty list
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// compileFilterRules compiles rules, failing on the first invalid one
+func compileFilterRules(rules []config.GuestFilterRule) ([]guestFilterRule, error) {
+	var compiled []guestFilterRule
+	for _, r := range rules {
+		rule, err := compileFilterRule(r)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// compileFilterRule compiles a single rule. Any invalid field fails the whole rule, since
+// dropping just that field would make the rule match more guests than intended.
+func compileFilterRule(r config.GuestFilterRule) (guestFilterRule, error) {
+	rule := guestFilterRule{
+		tags:  toSet(r.Tags),
+		pools: toSet(r.Pools),
+		nodes: toSet(r.Nodes),
+		typ:   r.Type,
+	}
+	if r.Name != "" {
+		re, err := regexp.Compile(r.Name)
+		if err != nil {
+			return rule, fmt.Errorf("invalid name pattern %q: %w", r.Name, err)
+		}
+		rule.name = re
+	}
+	for _, v := range r.VMIDs {
+		from, to, err := config.ParseVMIDRange(v)
+		if err != nil {
+			return rule, err
+		}
+		rule.vmids = append(rule.vmids, vmidRange{from, to})
+	}
+	return rule, nil
+}
+
+// newGuestFilter compiles the configured filter rules. An invalid rule is an error rather than
+// skipped, since a missing exclude or light rule would collect guests it was meant to filter.
+func newGuestFilter(cfg config.FiltersConfig) (*guestFilter, error) {
+	f := &guestFilter{}
+	for _, list := range []struct {
+		name  string
+		rules []config.GuestFilterRule
+		dst   *[]guestFilterRule
+	}{
+		{"include", cfg.Include, &f.include},
+		{"exclude", cfg.Exclude, &f.exclude},
+		{"light", cfg.Light, &f.light},
+	} {
+		compiled, err := compileFilterRules(list.rules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s filter: %w", list.name, err)
+		}
+		*list.dst = compiled
+	}
+	return f, nil
+}
+
+// matchesVMID reports whether the VMID falls in any of the rule's ranges
+func (r *guestFilterRule) matchesVMID(vmid string) bool {
+	id, err := strconv.ParseInt(vmid, 10, 64)
+	if err != nil {
+		return false
+	}
+	for _, rng := range r.vmids {
+		if id >= rng.from && id <= rng.to {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTags reports whether the guest carries any of the rule's tags
+func (r *guestFilterRule) matchesTags(tags string) bool {
+	for _, tag := range splitTags(tags) {
+		if r.tags[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a guest satisfies every non-empty field of the rule
+func (r *guestFilterRule) matches(vmid string, guest GuestInfo) bool {
+	switch {
+	case r.name != nil && !r.name.MatchString(guest.Name):
+		return false
+	case len(r.vmids) > 0 && !r.matchesVMID(vmid):
+		return false
+	case r.tags != nil && !r.matchesTags(guest.Tags):
+		return false
+	case r.pools != nil && !r.pools[guest.Pool]:
+		return false
+	case r.nodes != nil && !r.nodes[guest.Node]:
+		return false
+	case r.typ != "" && r.typ != guest.Type:
+		return false
+	}
+	return true
+}
+
+// matchesAny reports whether a guest matches any of the rules
+func matchesAny(rules []guestFilterRule, vmid string, guest GuestInfo) bool {
+	for i := range rules {
+		if rules[i].matches(vmid, guest) {
+			return true
+		}
+	}
+	return false
+}
+
+// classify decides how much of a guest is collected
+func (f *guestFilter) classify(vmid string, guest GuestInfo) filterAction {
+	if matchesAny(f.exclude, vmid, guest) {
+		return filterExclude
+	}
+	if len(f.include) > 0 && !matchesAny(f.include, vmid, guest) {
+		return filterExclude
+	}
+	if matchesAny(f.light, vmid, guest) {
+		return filterLight
+	}
+	return filterFull
+}
+
+// detailedGuests returns the guests that are fully collected
+func (f *guestFilter) detailedGuests(guests map[string]GuestInfo) map[string]GuestInfo {
+	if len(f.include) == 0 && len(f.exclude) == 0 && len(f.light) == 0 {
+		return guests
+	}
+	detailed := make(map[string]GuestInfo, len(guests))
+	for vmid, guest := range guests {
+		if f.classify(vmid, guest) == filterFull {
+			detailed[vmid] = guest
+		}
+	}
+	return detailed
+}
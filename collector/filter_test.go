@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestGuestFilterClassify(t *testing.T) {
+	f := mustGuestFilter(t, config.FiltersConfig{
+		Include: []config.GuestFilterRule{{Nodes: []string{"pve1", "pve2"}}},
+		Exclude: []config.GuestFilterRule{
+			{Name: "^ci-", Type: "lxc"},
+			{VMIDs: []string{"9000-9999"}},
+		},
+		Light: []config.GuestFilterRule{{Tags: []string{"ephemeral"}}, {Pools: []string{"lab"}}},
+	})
+
+	tests := []struct {
+		name  string
+		vmid  string
+		guest GuestInfo
+		want  filterAction
+	}{
+		{"regular guest", "100", GuestInfo{Node: "pve1", Name: "web", Type: "qemu"}, filterFull},
+		{"node not included", "101", GuestInfo{Node: "pve3", Name: "web", Type: "qemu"}, filterExclude},
+		{"ci container", "200", GuestInfo{Node: "pve1", Name: "ci-runner-1", Type: "lxc"}, filterExclude},
+		{"ci-named VM is not a container", "201", GuestInfo{Node: "pve1", Name: "ci-builder", Type: "qemu"}, filterFull},
+		{"template range", "9001", GuestInfo{Node: "pve2", Name: "tmpl", Type: "qemu"}, filterExclude},
+		{"ephemeral tag", "300", GuestInfo{Node: "pve2", Name: "test", Tags: "team-a;ephemeral"}, filterLight},
+		{"lab pool", "301", GuestInfo{Node: "pve2", Name: "lab", Pool: "lab"}, filterLight},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.classify(tt.vmid, tt.guest); got != tt.want {
+				t.Errorf("classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// mustGuestFilter compiles filter rules, failing the test on invalid ones
+func mustGuestFilter(t *testing.T, cfg config.FiltersConfig) *guestFilter {
+	t.Helper()
+	f, err := newGuestFilter(cfg)
+	if err != nil {
+		t.Fatalf("newGuestFilter failed: %v", err)
+	}
+	return f
+}
+
+func TestGuestFilterInvalidRule(t *testing.T) {
+	// Skipping an invalid exclude or light rule would collect the guests it was meant to filter
+	tests := []struct {
+		name string
+		cfg  config.FiltersConfig
+	}{
+		{"invalid vmid range", config.FiltersConfig{Exclude: []config.GuestFilterRule{{VMIDs: []string{"9000-x"}, Type: "qemu"}}}},
+		{"invalid name pattern", config.FiltersConfig{Light: []config.GuestFilterRule{{Name: "^ci-("}}}},
+		{"invalid include rule", config.FiltersConfig{Include: []config.GuestFilterRule{{VMIDs: []string{"x"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if f, err := newGuestFilter(tt.cfg); err == nil {
+				t.Errorf("expected an error, got filter %+v", f)
+			}
+		})
+	}
+}
+
+func TestGuestFilterNoRules(t *testing.T) {
+	f := mustGuestFilter(t, config.FiltersConfig{})
+	guests := map[string]GuestInfo{"100": {Name: "web"}}
+	if got := f.detailedGuests(guests); len(got) != 1 {
+		t.Errorf("expected all guests without rules, got %d", len(got))
+	}
+}
+
+func TestCollectResourceMetricsFiltered(t *testing.T) {
+	var detailCalls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes/pve1/lxc", jsonHandler([]map[string]interface{}{
+		{"vmid": 100, "name": "app", "status": "running"},
+		{"vmid": 200, "name": "ci-runner", "status": "running"},
+		{"vmid": 300, "name": "scratch", "status": "running", "tags": "ephemeral"},
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/lxc/", func(w http.ResponseWriter, r *http.Request) {
+		detailCalls.Add(1)
+		jsonHandler(map[string]interface{}{"swap": 0})(w, r)
+	})
+
+	c := newTestCollector(t, mux)
+	c.filter = mustGuestFilter(t, config.FiltersConfig{
+		Exclude: []config.GuestFilterRule{{Name: "^ci-"}},
+		Light:   []config.GuestFilterRule{{Tags: []string{"ephemeral"}}},
+	})
+
+	ch := make(chan prometheus.Metric, 100)
+	count := c.collectResourceMetrics(ch, "pve1", "lxc", map[string]GuestInfo{}, c.newGuestConfigCache())
+	close(ch)
+
+	series := make(map[string]int)
+	for m := range ch {
+		series[metricLabels(m)["vmid"]]++
+	}
+
+	if count != 3 {
+		t.Errorf("expected node count of 3 guests, got %d", count)
+	}
+	if series["200"] != 0 {
+		t.Errorf("excluded guest emitted %d series", series["200"])
+	}
+	if series["300"] != 1 {
+		t.Errorf("light guest should only emit status, got %d series", series["300"])
+	}
+	if series["100"] <= 1 {
+		t.Errorf("regular guest should emit full metrics, got %d series", series["100"])
+	}
+	if calls := detailCalls.Load(); calls != 1 {
+		t.Errorf("expected 1 /status/current call, got %d", calls)
+	}
+}
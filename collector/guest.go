@@ -0,0 +1,195 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// guestConfigValue converts a value from a guest /config response to a string.
+// PVE returns numeric options (onboot, protection, ...) as numbers and everything else as strings.
+func guestConfigValue(cfg map[string]interface{}, key string) string {
+	switch v := cfg[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return ""
+	}
+}
+
+// guestConfigFlag returns "1" or "0" for a boolean option that defaults to disabled
+func guestConfigFlag(cfg map[string]interface{}, key string) string {
+	if guestConfigValue(cfg, key) == "1" {
+		return "1"
+	}
+	return "0"
+}
+
+// parseCPUType extracts the CPU model from a QEMU "cpu" option like "host,flags=+aes" or "cputype=host"
+func parseCPUType(value string) string {
+	for _, part := range strings.Split(value, ",") {
+		if !strings.Contains(part, "=") {
+			return part
+		}
+		if strings.HasPrefix(part, "cputype=") {
+			return strings.TrimPrefix(part, "cputype=")
+		}
+	}
+	return ""
+}
+
+// parseAgentEnabled parses a QEMU "agent" option like "1" or "enabled=1,fstrim_cloned_disks=1"
+func parseAgentEnabled(value string) string {
+	for _, part := range strings.Split(value, ",") {
+		if part == "1" || part == "enabled=1" {
+			return "1"
+		}
+	}
+	return "0"
+}
+
+// guestConfigCache fetches each guest's /config at most once per scrape,
+// so collectors that need guest configs can share the same API calls
+type guestConfigCache struct {
+	c       *ProxmoxCollector
+	mu      sync.Mutex
+	entries map[string]*guestConfigEntry
+}
+
+// guestConfigEntry holds a single cached guest config
+type guestConfigEntry struct {
+	once sync.Once
+	cfg  map[string]interface{}
+	err  error
+}
+
+// newGuestConfigCache creates an empty per-scrape guest config cache
+func (c *ProxmoxCollector) newGuestConfigCache() *guestConfigCache {
+	return &guestConfigCache{c: c, entries: make(map[string]*guestConfigEntry)}
+}
+
+// get returns the config of a guest, fetching it on first use
+func (gc *guestConfigCache) get(vmid string, guest GuestInfo) (map[string]interface{}, error) {
+	gc.mu.Lock()
+	entry, ok := gc.entries[vmid]
+	if !ok {
+		entry = &guestConfigEntry{}
+		gc.entries[vmid] = entry
+	}
+	gc.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.cfg, entry.err = gc.c.fetchGuestConfig(vmid, guest)
+	})
+	return entry.cfg, entry.err
+}
+
+// fetchGuestConfig fetches the current config of a guest
+func (c *ProxmoxCollector) fetchGuestConfig(vmid string, guest GuestInfo) (map[string]interface{}, error) {
+	data, err := c.apiRequest(fmt.Sprintf("/nodes/%s/%s/%s/config", guest.Node, guest.Type, vmid))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
+	return result.Data, nil
+}
+
+// collectGuestInfoMetrics collects guest configuration info for all guests in parallel
+func (c *ProxmoxCollector) collectGuestInfoMetrics(ch chan<- prometheus.Metric, guests map[string]GuestInfo, configs *guestConfigCache) {
+	var wg sync.WaitGroup
+	for vmid, guest := range guests {
+		wg.Add(1)
+		go func(vmid string, guest GuestInfo) {
+			defer wg.Done()
+			c.collectGuestInfo(ch, vmid, guest, configs)
+		}(vmid, guest)
+	}
+	wg.Wait()
+}
+
+// collectGuestInfo emits info and disk metrics from a guest's config and fetches its /pending changes.
+// Only fully collected guests get here, so light and excluded guests cost no API calls.
+func (c *ProxmoxCollector) collectGuestInfo(ch chan<- prometheus.Metric, vmid string, guest GuestInfo, configs *guestConfigCache) {
+	cfg, err := configs.get(vmid, guest)
+	if err != nil {
+		log.Printf("Error fetching config for guest %s: %v", vmid, err)
+		cfg = make(map[string]interface{})
+	}
+
+	template := "0"
+	if guest.Template {
+		template = "1"
+	}
+
+	var machine, bios, cpu, agent string
+	if guest.Type == "qemu" {
+		machine = guestConfigValue(cfg, "machine")
+		bios = guestConfigValue(cfg, "bios")
+		cpu = parseCPUType(guestConfigValue(cfg, "cpu"))
+		agent = parseAgentEnabled(guestConfigValue(cfg, "agent"))
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.guestInfo, prometheus.GaugeValue, 1,
+		guest.Node, vmid, guest.Name, guest.Type,
+		guest.Tags, guest.Pool, template, guest.Lock, guest.HAState,
+		guestConfigValue(cfg, "ostype"), machine, bios, cpu,
+		guestConfigFlag(cfg, "onboot"), guestConfigFlag(cfg, "protection"), agent,
+	)
+
+	c.emitGuestDiskMetrics(ch, cfg, []string{guest.Node, vmid, guest.Name, guest.Type}, guest.Type)
+
+	// Templates never start, so their pending changes can't be applied
+	if c.collectors.Pending.Enabled && !guest.Template {
+		c.collectGuestPending(ch, vmid, guest)
+	}
+}
+
+// collectGuestPending emits the number of pending config changes of a guest
+func (c *ProxmoxCollector) collectGuestPending(ch chan<- prometheus.Metric, vmid string, guest GuestInfo) {
+	// Pending changes are config keys with a "pending" value or a pending delete
+	pendingData, err := c.apiRequest(fmt.Sprintf("/nodes/%s/%s/%s/pending", guest.Node, guest.Type, vmid))
+	if err != nil {
+		return
+	}
+
+	var pendingResult struct {
+		Data []struct {
+			Key     string      `json:"key"`
+			Pending interface{} `json:"pending"`
+			Delete  int         `json:"delete"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(pendingData, &pendingResult); err != nil {
+		log.Printf("Error unmarshaling pending config for guest %s: %v", vmid, err)
+		return
+	}
+
+	pending := 0
+	for _, item := range pendingResult.Data {
+		if item.Pending != nil || item.Delete > 0 {
+			pending++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.guestPendingChanges, prometheus.GaugeValue, float64(pending), guest.Node, vmid, guest.Name, guest.Type)
+}
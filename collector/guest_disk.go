@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// guestDiskKeyRe matches guest config keys that hold virtual disks
+var guestDiskKeyRe = regexp.MustCompile(`^(ide|sata|scsi|virtio|efidisk|tpmstate|mp)\d+$|^rootfs$`)
+
+// guestDisk represents a virtual disk parsed from a guest config entry
+type guestDisk struct {
+	Device   string // config key, matches the blockstat device for QEMU
+	Storage  string // empty for passthrough devices and bind mounts
+	Volume   string // full volume ID ("storage:volume") or path
+	Size     float64
+	Cache    string
+	IOThread string
+	Discard  string
+	SSD      string
+	Backup   string
+}
+
+// parseDiskSize parses a PVE size string like "32G", "512M" or "1T" into bytes
+func parseDiskSize(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	multiplier := 1.0
+	switch s[len(s)-1] {
+	case 'K', 'k':
+		multiplier = 1 << 10
+	case 'M', 'm':
+		multiplier = 1 << 20
+	case 'G', 'g':
+		multiplier = 1 << 30
+	case 'T', 't':
+		multiplier = 1 << 40
+	}
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v * multiplier
+}
+
+// parseGuestDisk parses a disk config entry like "local-lvm:vm-100-disk-0,cache=writeback,size=32G".
+// Returns false for entries that are not disks (CD-ROM drives, cloud-init images).
+func parseGuestDisk(key, value, guestType string) (guestDisk, bool) {
+	if !guestDiskKeyRe.MatchString(key) {
+		return guestDisk{}, false
+	}
+
+	parts := strings.Split(value, ",")
+	disk := guestDisk{Device: key, Volume: parts[0], IOThread: "0", SSD: "0", Discard: "ignore"}
+	if guestType == "qemu" {
+		disk.Cache = "none"
+	}
+
+	// QEMU disks are backed up unless backup=0, LXC mount points only with backup=1
+	disk.Backup = "1"
+	if strings.HasPrefix(key, "mp") {
+		disk.Backup = "0"
+	}
+
+	for _, opt := range parts[1:] {
+		k, v, _ := strings.Cut(opt, "=")
+		if k == "media" && v == "cdrom" {
+			return guestDisk{}, false
+		}
+		disk.setOption(k, v)
+	}
+
+	if storage, _, ok := strings.Cut(disk.Volume, ":"); ok && !strings.HasPrefix(disk.Volume, "/") {
+		disk.Storage = storage
+	}
+
+	return disk, true
+}
+
+// setOption applies a single "key=value" disk option
+func (d *guestDisk) setOption(key, value string) {
+	switch key {
+	case "volume", "file":
+		d.Volume = value
+	case "size":
+		d.Size = parseDiskSize(value)
+	case "cache":
+		d.Cache = value
+	case "iothread":
+		d.IOThread = value
+	case "discard":
+		d.Discard = value
+	case "ssd":
+		d.SSD = value
+	case "backup":
+		d.Backup = value
+	}
+}
+
+// parseGuestDisks returns all virtual disks defined in a guest config
+func parseGuestDisks(cfg map[string]interface{}, guestType string) []guestDisk {
+	var disks []guestDisk
+	for key := range cfg {
+		value := guestConfigValue(cfg, key)
+		if disk, ok := parseGuestDisk(key, value, guestType); ok {
+			disks = append(disks, disk)
+		}
+	}
+	return disks
+}
+
+// emitGuestDiskMetrics emits per-disk capacity and configuration metrics for a guest
+func (c *ProxmoxCollector) emitGuestDiskMetrics(ch chan<- prometheus.Metric, cfg map[string]interface{}, labels []string, guestType string) {
+	for _, disk := range parseGuestDisks(cfg, guestType) {
+		diskLabels := append(append([]string{}, labels...), disk.Device, disk.Storage, disk.Volume)
+		ch <- prometheus.MustNewConstMetric(c.guestDiskSize, prometheus.GaugeValue, disk.Size, diskLabels...)
+		infoLabels := append(diskLabels, disk.Cache, disk.IOThread, disk.Discard, disk.SSD, disk.Backup)
+		ch <- prometheus.MustNewConstMetric(c.guestDiskInfo, prometheus.GaugeValue, 1, infoLabels...)
+	}
+}
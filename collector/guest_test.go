@@ -0,0 +1,274 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newTestCollector starts a mock PVE API server and returns a collector pointed at it
+func newTestCollector(t *testing.T, mux *http.ServeMux) *ProxmoxCollector {
+	t.Helper()
+
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+
+	hostPort := strings.TrimPrefix(server.URL, "https://")
+	parts := strings.SplitN(hostPort, ":", 2)
+	port := 443
+	if len(parts) == 2 {
+		_, _ = fmt.Sscanf(parts[1], "%d", &port)
+	}
+
+	cfg := &config.Config{
+		Proxmox: config.ProxmoxConfig{
+			Host:               parts[0],
+			Port:               port,
+			TokenID:            "test@pve!test",
+			TokenSecret:        "test-secret",
+			InsecureSkipVerify: true,
+		},
+	}
+	c := NewProxmoxCollector(cfg)
+	c.client = server.Client()
+	return c
+}
+
+// jsonHandler returns a handler that writes v as the "data" field of a PVE API response
+func jsonHandler(v interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": v})
+	}
+}
+
+// metricLabels returns the label pairs of a prometheus.Metric as a map
+func metricLabels(m prometheus.Metric) map[string]string {
+	pb := &dto.Metric{}
+	_ = m.Write(pb)
+	labels := make(map[string]string)
+	for _, lp := range pb.GetLabel() {
+		labels[lp.GetName()] = lp.GetValue()
+	}
+	return labels
+}
+
+func TestParseCPUType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"host", "host"},
+		{"host,flags=+aes", "host"},
+		{"cputype=x86-64-v2-AES,flags=+pcid", "x86-64-v2-AES"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := parseCPUType(tt.in); got != tt.want {
+			t.Errorf("parseCPUType(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAgentEnabled(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1", "1"},
+		{"0", "0"},
+		{"enabled=1,fstrim_cloned_disks=1", "1"},
+		{"1,type=virtio", "1"},
+		{"enabled=0", "0"},
+		{"", "0"},
+	}
+	for _, tt := range tests {
+		if got := parseAgentEnabled(tt.in); got != tt.want {
+			t.Errorf("parseAgentEnabled(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCollectGuestInfo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/config", jsonHandler(map[string]interface{}{
+		"name":       "web",
+		"ostype":     "l26",
+		"machine":    "q35",
+		"bios":       "ovmf",
+		"cpu":        "host,flags=+aes",
+		"onboot":     1,
+		"protection": 0,
+		"agent":      "enabled=1,fstrim_cloned_disks=1",
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/pending", jsonHandler([]map[string]interface{}{
+		{"key": "memory", "value": 2048, "pending": 4096},
+		{"key": "cores", "value": 2},
+		{"key": "tags", "value": "old", "delete": 1},
+	}))
+
+	c := newTestCollector(t, mux)
+	c.collectors.Pending.Enabled = true
+	guest := GuestInfo{Node: "pve1", Name: "web", Type: "qemu", Tags: "team-a;prod", Pool: "web", HAState: "started"}
+
+	ch := make(chan prometheus.Metric, 10)
+	c.collectGuestInfo(ch, "100", guest, c.newGuestConfigCache())
+	close(ch)
+
+	metrics := collectMetrics(ch)
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(metrics))
+	}
+
+	want := map[string]string{
+		"node": "pve1", "vmid": "100", "name": "web", "type": "qemu",
+		"tags": "team-a;prod", "pool": "web", "template": "0", "lock": "", "hastate": "started",
+		"ostype": "l26", "machine": "q35", "bios": "ovmf", "cpu": "host",
+		"onboot": "1", "protection": "0", "agent": "1",
+	}
+	labels := metricLabels(metrics[0])
+	for k, v := range want {
+		if labels[k] != v {
+			t.Errorf("label %s = %q, want %q", k, labels[k], v)
+		}
+	}
+
+	if v := getMetricValue(metrics[1]); v != 2 {
+		t.Errorf("expected 2 pending changes, got %f", v)
+	}
+}
+
+func TestCollectGuestInfoSkipsPending(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		template bool
+	}{
+		{name: "disabled", enabled: false},
+		{name: "template", enabled: true, template: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pendingCalls := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/config", jsonHandler(map[string]interface{}{"name": "web"}))
+			mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/pending", func(w http.ResponseWriter, r *http.Request) {
+				pendingCalls++
+				jsonHandler([]map[string]interface{}{})(w, r)
+			})
+
+			c := newTestCollector(t, mux)
+			c.collectors.Pending.Enabled = tt.enabled
+			guest := GuestInfo{Node: "pve1", Name: "web", Type: "qemu", Template: tt.template}
+
+			ch := make(chan prometheus.Metric, 10)
+			c.collectGuestInfo(ch, "100", guest, c.newGuestConfigCache())
+			close(ch)
+
+			if metrics := collectMetrics(ch); len(metrics) != 1 || pendingCalls != 0 {
+				t.Errorf("got %d metrics and %d /pending calls, want only pve_guest_info", len(metrics), pendingCalls)
+			}
+		})
+	}
+}
+
+func TestParseDiskSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"32G", 32 << 30},
+		{"512M", 512 << 20},
+		{"528K", 528 << 10},
+		{"1T", 1 << 40},
+		{"4096", 4096},
+		{"", 0},
+		{"abc", 0},
+	}
+	for _, tt := range tests {
+		if got := parseDiskSize(tt.in); got != tt.want {
+			t.Errorf("parseDiskSize(%q) = %f, want %f", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseGuestDisk(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		value     string
+		guestType string
+		want      guestDisk
+		ok        bool
+	}{
+		{
+			name:      "qemu scsi disk",
+			key:       "scsi0",
+			value:     "local-lvm:vm-100-disk-0,cache=writeback,discard=on,iothread=1,size=32G,ssd=1",
+			guestType: "qemu",
+			want: guestDisk{Device: "scsi0", Storage: "local-lvm", Volume: "local-lvm:vm-100-disk-0", Size: 32 << 30,
+				Cache: "writeback", IOThread: "1", Discard: "on", SSD: "1", Backup: "1"},
+			ok: true,
+		},
+		{
+			name:      "qemu disk excluded from backup",
+			key:       "virtio1",
+			value:     "ceph:vm-100-disk-1,backup=0,size=100G",
+			guestType: "qemu",
+			want: guestDisk{Device: "virtio1", Storage: "ceph", Volume: "ceph:vm-100-disk-1", Size: 100 << 30,
+				Cache: "none", IOThread: "0", Discard: "ignore", SSD: "0", Backup: "0"},
+			ok: true,
+		},
+		{
+			name:      "cdrom is skipped",
+			key:       "ide2",
+			value:     "local:iso/debian.iso,media=cdrom",
+			guestType: "qemu",
+			ok:        false,
+		},
+		{
+			name:      "lxc mount point",
+			key:       "mp0",
+			value:     "local:101/vm-101-disk-1.raw,mp=/data,size=10G",
+			guestType: "lxc",
+			want: guestDisk{Device: "mp0", Storage: "local", Volume: "local:101/vm-101-disk-1.raw", Size: 10 << 30,
+				IOThread: "0", Discard: "ignore", SSD: "0", Backup: "0"},
+			ok: true,
+		},
+		{
+			name:      "lxc bind mount",
+			key:       "mp1",
+			value:     "/mnt/share,mp=/share",
+			guestType: "lxc",
+			want:      guestDisk{Device: "mp1", Volume: "/mnt/share", IOThread: "0", Discard: "ignore", SSD: "0", Backup: "0"},
+			ok:        true,
+		},
+		{
+			name:      "non-disk key",
+			key:       "net0",
+			value:     "virtio=AA:BB:CC:DD:EE:FF,bridge=vmbr0",
+			guestType: "qemu",
+			ok:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseGuestDisk(tt.key, tt.value, tt.guestType)
+			if ok != tt.ok {
+				t.Fatalf("parseGuestDisk() ok = %v, want %v", ok, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("parseGuestDisk() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+// labelEnricher derives extra labels for guest and node metrics from tags, guest notes and static node maps
+type labelEnricher struct {
+	cfg        config.LabelsConfig
+	guestNames []string // extra guest label names, in descriptor order
+	nodeNames  []string // extra node label names, in descriptor order
+}
+
+// newLabelEnricher computes the extra label names from the configuration
+func newLabelEnricher(cfg config.LabelsConfig) *labelEnricher {
+	e := &labelEnricher{cfg: cfg}
+
+	nodeSet := make(map[string]bool)
+	for _, labels := range cfg.Nodes {
+		for name := range labels {
+			nodeSet[name] = true
+		}
+	}
+	e.nodeNames = sortedKeys(nodeSet)
+
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			e.guestNames = append(e.guestNames, name)
+		}
+	}
+	tagNames := make(map[string]bool, len(cfg.Tags))
+	for name := range cfg.Tags {
+		tagNames[name] = true
+	}
+	for _, name := range sortedKeys(tagNames) {
+		add(name)
+	}
+	for _, name := range cfg.Description {
+		add(name)
+	}
+	// Guests inherit the static labels of their node
+	for _, name := range e.nodeNames {
+		add(name)
+	}
+	return e
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// needsDescription reports whether guest notes have to be fetched
+func (e *labelEnricher) needsDescription() bool {
+	return len(e.cfg.Description) > 0
+}
+
+// nodeValues returns the extra label values of a node, in nodeNames order
+func (e *labelEnricher) nodeValues(node string) []string {
+	if len(e.nodeNames) == 0 {
+		return nil
+	}
+	values := make([]string, len(e.nodeNames))
+	for i, name := range e.nodeNames {
+		values[i] = e.cfg.Nodes[node][name]
+	}
+	return values
+}
+
+// guestValues returns the extra label values of a guest, in guestNames order.
+// Tags take precedence over notes, which take precedence over node labels.
+func (e *labelEnricher) guestValues(guest GuestInfo, description string) []string {
+	if len(e.guestNames) == 0 {
+		return nil
+	}
+
+	values := make(map[string]string, len(e.guestNames))
+	for name, value := range e.cfg.Nodes[guest.Node] {
+		values[name] = value
+	}
+	for name, value := range parseDescriptionLabels(description, e.cfg.Description) {
+		values[name] = value
+	}
+	for _, tag := range splitTags(guest.Tags) {
+		for name, prefix := range e.cfg.Tags {
+			if strings.HasPrefix(tag, prefix) && len(tag) > len(prefix) {
+				values[name] = strings.TrimPrefix(tag, prefix)
+			}
+		}
+	}
+
+	result := make([]string, len(e.guestNames))
+	for i, name := range e.guestNames {
+		result[i] = values[name]
+	}
+	return result
+}
+
+// parseDescriptionLabels extracts "key: value" and "key=value" lines for the given keys from guest notes.
+// Keys are matched case-insensitively; Markdown list markers are ignored.
+func parseDescriptionLabels(description string, keys []string) map[string]string {
+	if description == "" || len(keys) == 0 {
+		return nil
+	}
+
+	wanted := make(map[string]string, len(keys))
+	for _, k := range keys {
+		wanted[strings.ToLower(k)] = k
+	}
+
+	labels := make(map[string]string)
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "-* ")
+		idx := strings.IndexAny(line, ":=")
+		if idx <= 0 {
+			continue
+		}
+		key, ok := wanted[strings.ToLower(strings.TrimSpace(line[:idx]))]
+		if !ok {
+			continue
+		}
+		if value := strings.TrimSpace(line[idx+1:]); value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// guestLabelValues returns the extra label values of a guest, fetching its notes when needed
+func (c *ProxmoxCollector) guestLabelValues(vmid string, guest GuestInfo, configs *guestConfigCache) []string {
+	description := ""
+	if c.labels.needsDescription() && configs != nil {
+		if cfg, err := configs.get(vmid, guest); err == nil {
+			description = guestConfigValue(cfg, "description")
+		}
+	}
+	return c.labels.guestValues(guest, description)
+}
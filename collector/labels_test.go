@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var testLabelsConfig = config.LabelsConfig{
+	Tags:        map[string]string{"env": "env-", "team": "team-"},
+	Description: []string{"owner", "team"},
+	Nodes: map[string]map[string]string{
+		"pve1": {"rack": "r1", "env": "lab"},
+	},
+}
+
+func TestParseDescriptionLabels(t *testing.T) {
+	description := "Web frontend\n\n- Owner: alice\n* team=platform\ncost: 12\nowner:\n"
+	got := parseDescriptionLabels(description, []string{"owner", "team"})
+
+	if got["owner"] != "alice" || got["team"] != "platform" {
+		t.Errorf("unexpected labels: %v", got)
+	}
+	if _, ok := got["cost"]; ok {
+		t.Error("unconfigured keys should be ignored")
+	}
+}
+
+func TestLabelEnricher(t *testing.T) {
+	e := newLabelEnricher(testLabelsConfig)
+
+	if got := strings.Join(e.guestNames, ","); got != "env,team,owner,rack" {
+		t.Errorf("unexpected guest label names: %s", got)
+	}
+	if got := strings.Join(e.nodeNames, ","); got != "env,rack" {
+		t.Errorf("unexpected node label names: %s", got)
+	}
+
+	tests := []struct {
+		name        string
+		guest       GuestInfo
+		description string
+		want        string
+	}{
+		{"node defaults", GuestInfo{Node: "pve1"}, "", "lab,,,r1"},
+		{"tags override node labels", GuestInfo{Node: "pve1", Tags: "env-prod;team-web"}, "", "prod,web,,r1"},
+		{"tags override notes", GuestInfo{Node: "pve2", Tags: "team-web"}, "team: ops\nowner: bob", ",web,bob,"},
+		{"bare prefix is ignored", GuestInfo{Node: "pve2", Tags: "env-"}, "", ",,,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(e.guestValues(tt.guest, tt.description), ","); got != tt.want {
+				t.Errorf("guestValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := strings.Join(e.nodeValues("pve1"), ","); got != "lab,r1" {
+		t.Errorf("unexpected node values: %s", got)
+	}
+}
+
+func TestEmitBackupMetricsExtraLabels(t *testing.T) {
+	c := NewProxmoxCollector(&config.Config{
+		Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"},
+		Labels:  testLabelsConfig,
+		Metrics: config.MetricsConfig{Naming: config.NamingBoth},
+	})
+
+	guests := map[string]GuestInfo{"100": {Node: "pve1", Name: "web", Type: "qemu", Tags: "env-prod"}}
+	ch := make(chan prometheus.Metric, 10)
+	c.emitBackupMetrics(ch, map[string]int64{"100": 1700000000}, guests, nil)
+	close(ch)
+
+	count := 0
+	for m := range ch {
+		count++
+		labels := metricLabels(m)
+		if labels["env"] != "prod" || labels["rack"] != "r1" {
+			t.Errorf("expected env=prod and rack=r1, got %v", labels)
+		}
+	}
+	if count != 2 {
+		t.Errorf("expected legacy and unified backup series, got %d", count)
+	}
+}
@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// guestDescs pairs the legacy per-type descriptors of a metric shared by VMs and containers
+// with its unified pve_guest_* descriptor
+type guestDescs struct {
+	qemu    *prometheus.Desc
+	lxc     *prometheus.Desc
+	unified *prometheus.Desc
+}
+
+// emitGuestMetric emits a shared guest metric under the configured naming scheme.
+// labels are node, vmid, name and any extra labels; the unified family adds the guest type.
+func (c *ProxmoxCollector) emitGuestMetric(ch chan<- prometheus.Metric, d guestDescs, valueType prometheus.ValueType, value float64, guestType string, labels []string) {
+	if c.naming != config.NamingUnified {
+		legacy := d.qemu
+		if guestType == "lxc" {
+			legacy = d.lxc
+		}
+		ch <- prometheus.MustNewConstMetric(legacy, valueType, value, labels...)
+	}
+	if c.naming == config.NamingUnified || c.naming == config.NamingBoth {
+		unifiedLabels := append(append([]string{}, labels...), guestType)
+		ch <- prometheus.MustNewConstMetric(d.unified, valueType, value, unifiedLabels...)
+	}
+}
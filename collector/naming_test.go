@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEmitGuestMetricNaming(t *testing.T) {
+	tests := []struct {
+		naming string
+		want   []string
+	}{
+		{config.NamingLegacy, []string{"pve_lxc_status"}},
+		{"", []string{"pve_lxc_status"}},
+		{config.NamingUnified, []string{"pve_guest_status"}},
+		{config.NamingBoth, []string{"pve_lxc_status", "pve_guest_status"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.naming, func(t *testing.T) {
+			c := NewProxmoxCollector(&config.Config{
+				Proxmox: config.ProxmoxConfig{Host: "localhost", User: "root@pam"},
+				Metrics: config.MetricsConfig{Naming: tt.naming},
+			})
+
+			ch := make(chan prometheus.Metric, 10)
+			d := guestDescs{c.vmStatus, c.lxcStatus, c.guestStatus}
+			c.emitGuestMetric(ch, d, prometheus.GaugeValue, 1, "lxc", []string{"pve1", "200", "ct"})
+			close(ch)
+
+			names := map[*prometheus.Desc]string{
+				c.vmStatus: "pve_vm_status", c.lxcStatus: "pve_lxc_status", c.guestStatus: "pve_guest_status",
+			}
+			var got []string
+			for m := range ch {
+				name := names[m.Desc()]
+				got = append(got, name)
+
+				labels := metricLabels(m)
+				if name == "pve_guest_status" && labels["type"] != "lxc" {
+					t.Errorf("expected type label lxc, got %q", labels["type"])
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("emitted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unusedDiskKeyRe matches guest config keys of detached disks
+var unusedDiskKeyRe = regexp.MustCompile(`^unused\d+$`)
+
+// Orphan reasons used as the "reason" label
+const (
+	orphanUnreferenced = "unreferenced"  // owning guest exists but does not reference the volume
+	orphanMissingGuest = "missing_guest" // owning VMID does not exist
+	orphanUnused       = "unused"        // still listed as unusedN in the owning guest config
+)
+
+// volumeReferences holds the volumes referenced by guest configs
+type volumeReferences struct {
+	mu      sync.Mutex
+	guests  map[string]GuestInfo
+	used    map[string]bool // volumes attached as disks, CD-ROMs or VM state
+	unused  map[string]bool // volumes listed as unusedN
+	unknown map[string]bool // VMIDs whose config could not be fetched
+}
+
+// volumeRefKeys returns the keys under which a volume ID is tracked.
+// Linked clones reference "base-100-disk-0/vm-101-disk-0" (or "100/base.qcow2/101/vm.qcow2"
+// on directory storages), while storage content may list either part separately.
+func volumeRefKeys(volid string) []string {
+	keys := []string{volid}
+	storage, volname, ok := strings.Cut(volid, ":")
+	if !ok {
+		return keys
+	}
+	segments := strings.Split(volname, "/")
+	switch {
+	case len(segments) == 2 && strings.HasPrefix(segments[0], "base-"):
+		keys = append(keys, storage+":"+segments[0], storage+":"+segments[1])
+	case len(segments) == 4:
+		keys = append(keys, storage+":"+segments[0]+"/"+segments[1], storage+":"+segments[2]+"/"+segments[3])
+	}
+	return keys
+}
+
+// configVolumeID extracts the volume ID from a disk config value like "local-lvm:vm-100-disk-0,size=32G"
+func configVolumeID(value string) string {
+	volid, _, _ := strings.Cut(value, ",")
+	if k, v, ok := strings.Cut(volid, "="); ok && (k == "volume" || k == "file") {
+		volid = v
+	}
+	if !strings.Contains(volid, ":") || strings.HasPrefix(volid, "/") {
+		return "" // passthrough device, bind mount or "none"
+	}
+	return volid
+}
+
+// buildVolumeReferences collects all volume IDs referenced by guest and snapshot configs
+func (c *ProxmoxCollector) buildVolumeReferences(guests map[string]GuestInfo, configs *guestConfigCache) *volumeReferences {
+	refs := &volumeReferences{
+		guests:  guests,
+		used:    make(map[string]bool),
+		unused:  make(map[string]bool),
+		unknown: make(map[string]bool),
+	}
+
+	var wg sync.WaitGroup
+	for vmid, guest := range guests {
+		wg.Add(1)
+		go func(vmid string, guest GuestInfo) {
+			defer wg.Done()
+
+			cfg, err := configs.get(vmid, guest)
+			if err != nil {
+				log.Printf("Error fetching config for guest %s: %v", vmid, err)
+				refs.markUnknown(vmid)
+				return
+			}
+			snapshots, err := c.fetchSnapshotConfigs(vmid, guest)
+			if err != nil {
+				log.Printf("Error fetching snapshots for guest %s: %v", vmid, err)
+				refs.markUnknown(vmid)
+				return
+			}
+			refs.addConfig(cfg, false)
+			for _, snapshot := range snapshots {
+				refs.addConfig(snapshot, true)
+			}
+		}(vmid, guest)
+	}
+	wg.Wait()
+
+	return refs
+}
+
+// fetchSnapshotConfigs fetches the configs of all snapshots of a guest. Snapshots keep disks that
+// were detached or replaced after they were taken, and their RAM state in a vmstate volume.
+func (c *ProxmoxCollector) fetchSnapshotConfigs(vmid string, guest GuestInfo) ([]map[string]interface{}, error) {
+	data, err := c.apiRequest(fmt.Sprintf("/nodes/%s/%s/%s/snapshot", guest.Node, guest.Type, vmid))
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data []struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	var configs []map[string]interface{}
+	for _, snapshot := range result.Data {
+		if snapshot.Name == "current" {
+			continue // the running state, already covered by /config
+		}
+		data, err := c.apiRequest(fmt.Sprintf("/nodes/%s/%s/%s/config?snapshot=%s", guest.Node, guest.Type, vmid, url.QueryEscape(snapshot.Name)))
+		if err != nil {
+			return nil, err
+		}
+		var cfg struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		configs = append(configs, cfg.Data)
+	}
+	return configs, nil
+}
+
+// markUnknown records a guest whose volume references could not be determined
+func (r *volumeReferences) markUnknown(vmid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unknown[vmid] = true
+}
+
+// addConfig records the volumes referenced by a guest or snapshot config.
+// Every volume of a snapshot is in use, including those listed as unusedN in it.
+func (r *volumeReferences) addConfig(cfg map[string]interface{}, snapshot bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range cfg {
+		var target map[string]bool
+		switch {
+		case unusedDiskKeyRe.MatchString(key) && snapshot:
+			target = r.used
+		case unusedDiskKeyRe.MatchString(key):
+			target = r.unused
+		case guestDiskKeyRe.MatchString(key), key == "vmstate":
+			target = r.used
+		default:
+			continue
+		}
+
+		volid := configVolumeID(guestConfigValue(cfg, key))
+		if volid == "" {
+			continue
+		}
+		for _, k := range volumeRefKeys(volid) {
+			target[k] = true
+		}
+	}
+}
+
+// classify returns the orphan reason for a volume, or an empty string if it is in use
+func (r *volumeReferences) classify(item storageContentItem) string {
+	for _, k := range volumeRefKeys(item.VolID) {
+		if r.unused[k] {
+			return orphanUnused
+		}
+		if r.used[k] {
+			return ""
+		}
+	}
+
+	owner := strconv.FormatInt(item.VMID, 10)
+	if r.unknown[owner] {
+		return "" // config unavailable, don't guess
+	}
+	if _, ok := r.guests[owner]; !ok && item.VMID > 0 {
+		return orphanMissingGuest
+	}
+	return orphanUnreferenced
+}
+
+// emitOrphanMetrics emits counts and sizes of orphaned guest volumes on a storage
+func (c *ProxmoxCollector) emitOrphanMetrics(ch chan<- prometheus.Metric, s contentStorage, items []storageContentItem, refs *volumeReferences) {
+	counts := map[string]float64{orphanUnreferenced: 0, orphanMissingGuest: 0, orphanUnused: 0}
+	bytes := map[string]float64{orphanUnreferenced: 0, orphanMissingGuest: 0, orphanUnused: 0}
+
+	for _, item := range items {
+		if item.Content != "images" && item.Content != "rootdir" {
+			continue
+		}
+		if reason := refs.classify(item); reason != "" {
+			counts[reason]++
+			bytes[reason] += item.Size
+		}
+	}
+
+	for reason, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.storageOrphanedVolumes, prometheus.GaugeValue, count, s.Node, s.Storage, reason)
+		ch <- prometheus.MustNewConstMetric(c.storageOrphanedBytes, prometheus.GaugeValue, bytes[reason], s.Node, s.Storage, reason)
+	}
+}
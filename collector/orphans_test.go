@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestConfigVolumeID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"local-lvm:vm-100-disk-0,size=32G", "local-lvm:vm-100-disk-0"},
+		{"volume=local:101/vm-101-disk-1.raw,mp=/data", "local:101/vm-101-disk-1.raw"},
+		{"/dev/disk/by-id/ata-XYZ,size=1T", ""},
+		{"none,media=cdrom", ""},
+	}
+	for _, tt := range tests {
+		if got := configVolumeID(tt.in); got != tt.want {
+			t.Errorf("configVolumeID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestVolumeRefKeys(t *testing.T) {
+	keys := volumeRefKeys("local-lvm:base-100-disk-0/vm-101-disk-0")
+	want := []string{"local-lvm:base-100-disk-0/vm-101-disk-0", "local-lvm:base-100-disk-0", "local-lvm:vm-101-disk-0"}
+	if len(keys) != len(want) {
+		t.Fatalf("expected %d keys, got %v", len(want), keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("key %d = %q, want %q", i, keys[i], want[i])
+		}
+	}
+
+	keys = volumeRefKeys("local:100/base-100-disk-0.qcow2/101/vm-101-disk-0.qcow2")
+	if len(keys) != 3 || keys[2] != "local:101/vm-101-disk-0.qcow2" {
+		t.Errorf("unexpected directory linked clone keys: %v", keys)
+	}
+}
+
+func TestCollectStorageContentOrphans(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes/pve1/storage", jsonHandler([]map[string]interface{}{
+		{"storage": "local-lvm", "content": "images,rootdir", "active": 1, "shared": 0},
+		{"storage": "local", "content": "iso,vztmpl,backup", "active": 1, "shared": 0},
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/storage/local-lvm/content", jsonHandler([]map[string]interface{}{
+		{"volid": "local-lvm:vm-100-disk-0", "content": "images", "size": 100, "vmid": 100},
+		{"volid": "local-lvm:vm-100-disk-1", "content": "images", "size": 200, "vmid": 100},
+		{"volid": "local-lvm:vm-100-disk-2", "content": "images", "size": 400, "vmid": 100},
+		{"volid": "local-lvm:vm-999-disk-0", "content": "images", "size": 800, "vmid": 999},
+		{"volid": "local-lvm:vm-101-disk-0", "content": "rootdir", "size": 1600, "vmid": 101},
+		{"volid": "local-lvm:vm-100-disk-3", "content": "images", "size": 3200, "vmid": 100},
+		{"volid": "local-lvm:vm-100-state-before-upgrade", "content": "images", "size": 6400, "vmid": 100},
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/snapshot", jsonHandler([]map[string]interface{}{
+		{"name": "before-upgrade", "vmstate": 1},
+		{"name": "current", "parent": "before-upgrade"},
+	}))
+	// vm-100-disk-3 was replaced by vm-100-disk-0 after the snapshot; only the snapshot references it
+	mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/config", func(w http.ResponseWriter, r *http.Request) {
+		cfg := map[string]interface{}{
+			"scsi0":   "local-lvm:vm-100-disk-0,size=100",
+			"unused0": "local-lvm:vm-100-disk-1",
+		}
+		if r.URL.Query().Get("snapshot") == "before-upgrade" {
+			cfg = map[string]interface{}{
+				"scsi0":   "local-lvm:vm-100-disk-3,size=3200",
+				"vmstate": "local-lvm:vm-100-state-before-upgrade",
+			}
+		}
+		jsonHandler(cfg)(w, r)
+	})
+	mux.HandleFunc("/api2/json/nodes/pve1/lxc/101/config", jsonHandler(map[string]interface{}{
+		"rootfs": "local-lvm:vm-101-disk-0,size=1600",
+	}))
+	mux.HandleFunc("/api2/json/nodes/pve1/lxc/101/snapshot", jsonHandler([]map[string]interface{}{
+		{"name": "current"},
+	}))
+
+	c := newTestCollector(t, mux)
+	c.collectors.Orphans.Enabled = true
+	guests := map[string]GuestInfo{
+		"100": {Node: "pve1", Name: "vm", Type: "qemu"},
+		"101": {Node: "pve1", Name: "ct", Type: "lxc"},
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	c.collectStorageContentMetrics(ch, []string{"pve1"}, guests, c.newGuestConfigCache())
+	close(ch)
+
+	got := make(map[string]float64)
+	for _, m := range collectMetrics(ch) {
+		if m.Desc() == c.storageOrphanedBytes {
+			labels := metricLabels(m)
+			got[labels["storage"]+"/"+labels["reason"]] = getMetricValue(m)
+		}
+	}
+
+	want := map[string]float64{
+		"local-lvm/unused":        200,
+		"local-lvm/unreferenced":  400,
+		"local-lvm/missing_guest": 800,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d orphan byte metrics, got %v", len(want), got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %f, want %f", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// permissionRequirement is a privilege a collector needs on an ACL path; "{node}" is expanded per node
+type permissionRequirement struct {
+	collector string
+	endpoint  string // API endpoint that fails without the privilege
+	path      string
+	privilege string
+	enabled   func(c *ProxmoxCollector) bool // nil for collectors that always run
+}
+
+// permissionRequirements lists what every collector needs. PVEAuditor on / grants all of them.
+var permissionRequirements = []permissionRequirement{
+	{collector: "node", endpoint: "/nodes/{node}/status", path: "/nodes/{node}", privilege: "Sys.Audit"},
+	{collector: "guest", endpoint: "/nodes/{node}/qemu", path: "/vms", privilege: "VM.Audit"},
+	{collector: "guest", endpoint: "/nodes/{node}/lxc", path: "/vms", privilege: "VM.Audit"},
+	{collector: "storage", endpoint: "/nodes/{node}/storage", path: "/storage", privilege: "Datastore.Audit"},
+	{collector: "zfs", endpoint: "/nodes/{node}/disks/zfs", path: "/nodes/{node}", privilege: "Sys.Audit"},
+	{collector: "disk", endpoint: "/nodes/{node}/disks/smart", path: "/nodes/{node}", privilege: "Sys.Audit"},
+	{collector: "backup", endpoint: "/nodes/{node}/tasks", path: "/nodes/{node}", privilege: "Sys.Audit"},
+	{collector: "cluster", endpoint: "/cluster/ha/resources", path: "/", privilege: "Sys.Audit"},
+	{collector: "replication", endpoint: "/cluster/replication", path: "/", privilege: "Sys.Audit"},
+	{collector: "certificate", endpoint: "/nodes/{node}/certificates/info", path: "/nodes/{node}", privilege: "Sys.Audit"},
+	{collector: "guest_info", endpoint: "/nodes/{node}/{type}/{vmid}/config", path: "/vms", privilege: "VM.Audit"},
+	{collector: "guest_info", endpoint: "/nodes/{node}/{type}/{vmid}/pending", path: "/vms", privilege: "VM.Audit",
+		enabled: func(c *ProxmoxCollector) bool { return c.collectors.Pending.Enabled }},
+	{collector: "capacity", endpoint: "/nodes", path: "/nodes/{node}", privilege: "Sys.Audit"},
+	{collector: "capacity", endpoint: "/cluster/resources", path: "/vms", privilege: "VM.Audit"},
+	{collector: "content", endpoint: "/nodes/{node}/storage/{storage}/content", path: "/storage", privilege: "Datastore.Audit",
+		enabled: func(c *ProxmoxCollector) bool { return c.collectors.Orphans.Enabled || c.collectors.Content.Enabled }},
+	{collector: "rrd", endpoint: "/nodes/{node}/rrddata", path: "/nodes/{node}", privilege: "Sys.Audit",
+		enabled: func(c *ProxmoxCollector) bool { return c.collectors.RRD.Enabled }},
+	{collector: "rrd", endpoint: "/nodes/{node}/storage/{storage}/rrddata", path: "/storage", privilege: "Datastore.Audit",
+		enabled: func(c *ProxmoxCollector) bool { return c.collectors.RRD.Enabled }},
+	{collector: "rightsizing", endpoint: "/nodes/{node}/{type}/{vmid}/rrddata", path: "/vms", privilege: "VM.Audit",
+		enabled: func(c *ProxmoxCollector) bool { return c.collectors.Rightsizing.Enabled }},
+	{collector: "cost", endpoint: "/storage", path: "/storage", privilege: "Datastore.Audit",
+		enabled: func(c *ProxmoxCollector) bool { return c.cost.Enabled }},
+}
+
+// PermissionCheck is the result of checking one privilege required by an enabled collector
+type PermissionCheck struct {
+	Collector string
+	Endpoint  string
+	Path      string
+	Privilege string
+	OK        bool
+}
+
+// permissionSet maps ACL paths to privileges and whether they propagate to sub-paths,
+// as returned by /access/permissions
+type permissionSet map[string]map[string]int
+
+// has reports whether privilege is granted on path, either directly or propagated from a parent path
+func (p permissionSet) has(path, privilege string) bool {
+	if _, ok := p[path][privilege]; ok {
+		return true
+	}
+	for path != "/" {
+		path = path[:strings.LastIndex(path, "/")]
+		if path == "" {
+			path = "/"
+		}
+		if p[path][privilege] == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchPermissions fetches the effective permissions of the configured user or token
+func (c *ProxmoxCollector) fetchPermissions() (permissionSet, error) {
+	data, err := c.apiRequest("/access/permissions")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data permissionSet `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal permissions: %w", err)
+	}
+	return result.Data, nil
+}
+
+// checkPermissions checks the requirements of all enabled collectors against the granted permissions
+func (c *ProxmoxCollector) checkPermissions(perms permissionSet, nodes []string) []PermissionCheck {
+	var checks []PermissionCheck
+	for _, req := range permissionRequirements {
+		if req.enabled != nil && !req.enabled(c) {
+			continue
+		}
+		paths := []string{req.path}
+		if strings.Contains(req.path, "{node}") {
+			paths = paths[:0]
+			for _, node := range nodes {
+				paths = append(paths, strings.ReplaceAll(req.path, "{node}", node))
+			}
+		}
+		for _, path := range paths {
+			checks = append(checks, PermissionCheck{
+				Collector: req.collector,
+				Endpoint:  req.endpoint,
+				Path:      path,
+				Privilege: req.privilege,
+				OK:        perms.has(path, req.privilege),
+			})
+		}
+	}
+	return checks
+}
+
+// CheckPermissions connects to the API and checks whether the configured user or token
+// has every privilege the enabled collectors need
+func (c *ProxmoxCollector) CheckPermissions() ([]PermissionCheck, error) {
+	if err := c.authenticate(); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	_, nodes, err := c.fetchNodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+	perms, err := c.fetchPermissions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch permissions: %w", err)
+	}
+	return c.checkPermissions(perms, nodes), nil
+}
+
+// MissingPrivileges groups the failed checks by ACL path, returning the sorted missing privileges per path
+func MissingPrivileges(checks []PermissionCheck) map[string][]string {
+	missing := make(map[string][]string)
+	for _, check := range checks {
+		if check.OK {
+			continue
+		}
+		privs := missing[check.Path]
+		if !slices.Contains(privs, check.Privilege) {
+			missing[check.Path] = append(privs, check.Privilege)
+		}
+	}
+	for path := range missing {
+		sort.Strings(missing[path])
+	}
+	return missing
+}
+
+// collectPermissionMetrics exports whether each enabled collector has the privileges it needs
+func (c *ProxmoxCollector) collectPermissionMetrics(ch chan<- prometheus.Metric, nodes []string) {
+	perms, err := c.fetchPermissions()
+	if err != nil {
+		log.Printf("Error fetching permissions: %v", err)
+		return
+	}
+
+	// Several requirements of a collector may share a path; it is only OK if all of them are met
+	var keys [][2]string
+	ok := make(map[[2]string]bool)
+	for _, check := range c.checkPermissions(perms, nodes) {
+		key := [2]string{check.Collector, check.Path}
+		if _, seen := ok[key]; !seen {
+			keys = append(keys, key)
+			ok[key] = true
+		}
+		ok[key] = ok[key] && check.OK
+	}
+
+	for _, key := range keys {
+		value := 0.0
+		if ok[key] {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.exporterPermissionOK, prometheus.GaugeValue, value, key[0], key[1])
+	}
+}
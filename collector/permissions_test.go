@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPermissionSetHas(t *testing.T) {
+	perms := permissionSet{
+		"/":           {"Sys.Audit": 0},
+		"/vms":        {"VM.Audit": 1},
+		"/nodes/pve1": {"Sys.Audit": 1},
+		"/storage":    {"Datastore.Audit": 0},
+	}
+
+	tests := []struct {
+		path, privilege string
+		want            bool
+	}{
+		{"/", "Sys.Audit", true},
+		{"/nodes/pve1", "Sys.Audit", true},
+		{"/nodes/pve2", "Sys.Audit", false}, // granted on / without propagation
+		{"/vms/100", "VM.Audit", true},      // propagated from /vms
+		{"/storage", "Datastore.Audit", true},
+		{"/storage/local", "Datastore.Audit", false},
+		{"/vms", "Datastore.Audit", false},
+	}
+	for _, tt := range tests {
+		if got := perms.has(tt.path, tt.privilege); got != tt.want {
+			t.Errorf("has(%q, %q) = %v, want %v", tt.path, tt.privilege, got, tt.want)
+		}
+	}
+}
+
+// permissionsMux serves two nodes and permissions that lack Sys.Audit on /nodes/pve2
+func permissionsMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes", jsonHandler([]map[string]string{{"node": "pve1"}, {"node": "pve2"}}))
+	mux.HandleFunc("/api2/json/access/permissions", jsonHandler(map[string]map[string]int{
+		"/":           {"Sys.Audit": 0},
+		"/nodes/pve1": {"Sys.Audit": 1},
+		"/vms":        {"VM.Audit": 1},
+		"/storage":    {"Datastore.Audit": 1},
+	}))
+	return mux
+}
+
+func TestCheckPermissions(t *testing.T) {
+	c := newTestCollector(t, permissionsMux())
+	checks, err := c.CheckPermissions()
+	if err != nil {
+		t.Fatalf("CheckPermissions failed: %v", err)
+	}
+
+	failed := make(map[string]bool)
+	for _, check := range checks {
+		if check.Collector == "rrd" || check.Collector == "cost" {
+			t.Errorf("disabled collector %s was checked", check.Collector)
+		}
+		if !check.OK {
+			failed[check.Collector+" "+check.Path] = true
+		}
+	}
+	for _, want := range []string{"disk /nodes/pve2", "certificate /nodes/pve2", "node /nodes/pve2"} {
+		if !failed[want] {
+			t.Errorf("expected %s to be missing privileges", want)
+		}
+	}
+	if failed["disk /nodes/pve1"] || failed["vm /vms"] || failed["cluster /"] {
+		t.Errorf("unexpected failures: %v", failed)
+	}
+
+	missing := MissingPrivileges(checks)
+	if len(missing) != 1 || len(missing["/nodes/pve2"]) != 1 || missing["/nodes/pve2"][0] != "Sys.Audit" {
+		t.Errorf("unexpected missing privileges: %v", missing)
+	}
+}
+
+func TestCollectPermissionMetrics(t *testing.T) {
+	c := newTestCollector(t, permissionsMux())
+
+	ch := make(chan prometheus.Metric, 100)
+	c.collectPermissionMetrics(ch, []string{"pve1", "pve2"})
+	close(ch)
+	count := 0
+	for m := range ch {
+		count++
+		labels := metricLabels(m)
+		want := 1.0
+		if labels["path"] == "/nodes/pve2" {
+			want = 0
+		}
+		if got := getMetricValue(m); got != want {
+			t.Errorf("pve_exporter_permission_ok%v = %v, want %v", labels, got, want)
+		}
+	}
+	if count == 0 {
+		t.Error("no permission metrics collected")
+	}
+}
+
+func TestPermissionRequirementCollectors(t *testing.T) {
+	names := CollectorNames()
+	for _, req := range permissionRequirements {
+		if !slices.Contains(names, req.collector) {
+			t.Errorf("requirement for %s names unknown collector %q", req.endpoint, req.collector)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reloadable is a prometheus.Collector delegating to a ProxmoxCollector that can be replaced at runtime.
+// It is registered unchecked, since a new configuration may change the exported label sets.
+type Reloadable struct {
+	current atomic.Pointer[ProxmoxCollector]
+}
+
+// NewReloadable creates a reloadable collector starting with c
+func NewReloadable(c *ProxmoxCollector) *Reloadable {
+	r := &Reloadable{}
+	r.current.Store(c)
+	return r
+}
+
+// Describe sends no descriptors, making this an unchecked collector
+func (r *Reloadable) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect collects metrics from the current collector
+func (r *Reloadable) Collect(ch chan<- prometheus.Metric) {
+	r.current.Load().Collect(ch)
+}
+
+// Current returns the collector in use
+func (r *Reloadable) Current() *ProxmoxCollector {
+	return r.current.Load()
+}
+
+// Swap replaces the collector. Scrapes already running finish with the old one.
+// Accumulated chargeback data is kept when the ledger file did not change, and saved otherwise.
+func (r *Reloadable) Swap(c *ProxmoxCollector) {
+	old := r.current.Load()
+	if old.ledger != nil && c.ledger != nil && old.ledger.path == c.ledger.path {
+		old.ledger.mu.Lock()
+		old.ledger.retention = c.ledger.retention
+		old.ledger.mu.Unlock()
+		c.ledger = old.ledger
+	} else if old.ledger != nil {
+		old.ledger.flush()
+	}
+	r.current.Store(c)
+	old.client.CloseIdleConnections()
+}
+
+// Close saves chargeback data accrued since the last periodic save
+func (r *Reloadable) Close() {
+	if ledger := r.current.Load().ledger; ledger != nil {
+		ledger.flush()
+	}
+}
+
+// ChargebackHandler serves the chargeback report of the current collector
+func (r *Reloadable) ChargebackHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r.current.Load().ChargebackHandler()(w, req)
+	}
+}
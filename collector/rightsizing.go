@@ -0,0 +1,231 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// rightsizingMemoryStep is the granularity of memory recommendations
+	rightsizingMemoryStep = 256 << 20
+	// rightsizingMinMemory is the smallest memory recommendation
+	rightsizingMinMemory = 512 << 20
+	// rightsizingMaxConcurrency limits parallel RRD fetches during a refresh
+	rightsizingMaxConcurrency = 8
+)
+
+// usageStats holds the distribution of a usage series over the right-sizing window
+type usageStats struct {
+	P50 float64
+	P95 float64
+	Max float64
+}
+
+// guestRightsizing holds the right-sizing result for a single guest
+type guestRightsizing struct {
+	Labels          []string // node, vmid, name, type
+	AllocatedCPUs   float64
+	AllocatedMemory float64
+	CPU             usageStats // in cores
+	Memory          usageStats // in bytes
+	RecommendedCPUs float64
+	RecommendedMem  float64
+	Idle            bool
+}
+
+// rightsizingCache holds the latest right-sizing results, refreshed in the background
+type rightsizingCache struct {
+	mu         sync.Mutex
+	results    []guestRightsizing
+	updated    time.Time
+	refreshing bool
+}
+
+// percentile returns the nearest-rank percentile (0-100) of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// computeUsageStats computes p50/p95 from average samples and the maximum from max samples,
+// scaling each sample by the given factor
+func computeUsageStats(avg, max []rrdSample, scale func(rrdSample) float64) usageStats {
+	values := make([]float64, 0, len(avg))
+	for _, s := range avg {
+		values = append(values, scale(s))
+	}
+	sort.Float64s(values)
+
+	stats := usageStats{P50: percentile(values, 50), P95: percentile(values, 95)}
+	for _, s := range max {
+		stats.Max = math.Max(stats.Max, scale(s))
+	}
+	if len(max) == 0 && len(values) > 0 {
+		stats.Max = values[len(values)-1]
+	}
+	return stats
+}
+
+// computeRightsizing derives usage statistics and recommendations from guest RRD history
+func computeRightsizing(avg, max map[string][]rrdSample, allocCPUs, allocMem float64, cfg rightsizingOptions) guestRightsizing {
+	// Guest RRD "cpu" is a fraction of the allocated vCPUs
+	cores := func(s rrdSample) float64 { return s.Value * allocCPUs }
+	bytes := func(s rrdSample) float64 { return s.Value }
+
+	r := guestRightsizing{
+		AllocatedCPUs:   allocCPUs,
+		AllocatedMemory: allocMem,
+		CPU:             computeUsageStats(avg["cpu"], max["cpu"], cores),
+		Memory:          computeUsageStats(avg["mem"], max["mem"], bytes),
+	}
+
+	r.RecommendedCPUs = math.Max(1, math.Ceil(r.CPU.P95*(1+cfg.Headroom)))
+	mem := math.Ceil(r.Memory.P95*(1+cfg.Headroom)/rightsizingMemoryStep) * rightsizingMemoryStep
+	r.RecommendedMem = math.Max(rightsizingMinMemory, mem)
+
+	r.Idle = len(avg["cpu"]) > 0 && allocCPUs > 0 && r.CPU.Max/allocCPUs < cfg.IdleCPUThreshold
+	return r
+}
+
+// rightsizingOptions holds the tunables used when computing recommendations
+type rightsizingOptions struct {
+	Headroom         float64
+	IdleCPUThreshold float64
+}
+
+// collectRightsizingMetrics emits cached right-sizing results and triggers a background
+// refresh when they are older than the refresh interval
+func (c *ProxmoxCollector) collectRightsizingMetrics(ch chan<- prometheus.Metric, guests map[string]GuestInfo) {
+	c.rightsizing.mu.Lock()
+	stale := time.Since(c.rightsizing.updated) >= c.collectors.Rightsizing.RefreshInterval
+	if stale && !c.rightsizing.refreshing {
+		c.rightsizing.refreshing = true
+		go c.refreshRightsizing(guests)
+	}
+	results := c.rightsizing.results
+	updated := c.rightsizing.updated
+	c.rightsizing.mu.Unlock()
+
+	if updated.IsZero() {
+		return // first refresh still running
+	}
+
+	for _, r := range results {
+		for stat, v := range map[string]float64{"p50": r.CPU.P50, "p95": r.CPU.P95, "max": r.CPU.Max} {
+			ch <- prometheus.MustNewConstMetric(c.rightsizingCPUUsage, prometheus.GaugeValue, v, append(r.Labels, stat)...)
+		}
+		for stat, v := range map[string]float64{"p50": r.Memory.P50, "p95": r.Memory.P95, "max": r.Memory.Max} {
+			ch <- prometheus.MustNewConstMetric(c.rightsizingMemoryUsage, prometheus.GaugeValue, v, append(r.Labels, stat)...)
+		}
+		ch <- prometheus.MustNewConstMetric(c.rightsizingCPUAllocated, prometheus.GaugeValue, r.AllocatedCPUs, r.Labels...)
+		ch <- prometheus.MustNewConstMetric(c.rightsizingMemoryAllocated, prometheus.GaugeValue, r.AllocatedMemory, r.Labels...)
+		ch <- prometheus.MustNewConstMetric(c.rightsizingRecommendedCPUs, prometheus.GaugeValue, r.RecommendedCPUs, r.Labels...)
+		ch <- prometheus.MustNewConstMetric(c.rightsizingRecommendedMem, prometheus.GaugeValue, r.RecommendedMem, r.Labels...)
+		idle := 0.0
+		if r.Idle {
+			idle = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(c.rightsizingIdle, prometheus.GaugeValue, idle, r.Labels...)
+	}
+	ch <- prometheus.MustNewConstMetric(c.rightsizingLastRefresh, prometheus.GaugeValue, float64(updated.Unix()))
+}
+
+// refreshRightsizingNow recomputes the recommendations synchronously, unless a background refresh is running
+func (c *ProxmoxCollector) refreshRightsizingNow(guests map[string]GuestInfo) {
+	c.rightsizing.mu.Lock()
+	if c.rightsizing.refreshing {
+		c.rightsizing.mu.Unlock()
+		return
+	}
+	c.rightsizing.refreshing = true
+	c.rightsizing.mu.Unlock()
+	c.refreshRightsizing(guests)
+}
+
+// refreshRightsizing recomputes right-sizing results for all guests from their RRD history
+func (c *ProxmoxCollector) refreshRightsizing(guests map[string]GuestInfo) {
+	opts := rightsizingOptions{
+		Headroom:         c.collectors.Rightsizing.Headroom,
+		IdleCPUThreshold: c.collectors.Rightsizing.IdleCPUThreshold,
+	}
+
+	var results []guestRightsizing
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, rightsizingMaxConcurrency)
+
+	for vmid, guest := range guests {
+		if guest.Template {
+			continue
+		}
+		wg.Add(1)
+		go func(vmid string, guest GuestInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			r, err := c.fetchGuestRightsizing(vmid, guest, opts)
+			if err != nil {
+				log.Printf("Error fetching right-sizing data for guest %s: %v", vmid, err)
+				return
+			}
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		}(vmid, guest)
+	}
+	wg.Wait()
+
+	c.rightsizing.mu.Lock()
+	c.rightsizing.results = results
+	c.rightsizing.updated = time.Now()
+	c.rightsizing.refreshing = false
+	c.rightsizing.mu.Unlock()
+}
+
+// fetchGuestRightsizing fetches average and maximum RRD history of a guest and computes its recommendations
+func (c *ProxmoxCollector) fetchGuestRightsizing(vmid string, guest GuestInfo, opts rightsizingOptions) (guestRightsizing, error) {
+	path := fmt.Sprintf("/nodes/%s/%s/%s/rrddata?timeframe=%s", guest.Node, guest.Type, vmid, c.collectors.Rightsizing.Timeframe)
+
+	avgData, err := c.apiRequest(path + "&cf=AVERAGE")
+	if err != nil {
+		return guestRightsizing{}, err
+	}
+	avg, err := parseRRDSeries(avgData)
+	if err != nil {
+		return guestRightsizing{}, err
+	}
+
+	maxData, err := c.apiRequest(path + "&cf=MAX")
+	if err != nil {
+		return guestRightsizing{}, err
+	}
+	max, err := parseRRDSeries(maxData)
+	if err != nil {
+		return guestRightsizing{}, err
+	}
+
+	r := computeRightsizing(avg, max, latestValue(avg["maxcpu"]), latestValue(avg["maxmem"]), opts)
+	r.Labels = []string{guest.Node, vmid, guest.Name, guest.Type}
+	return r, nil
+}
+
+// latestValue returns the value of the newest sample, or 0 if there are none
+func latestValue(samples []rrdSample) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	return samples[len(samples)-1].Value
+}
@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{50, 5},
+		{95, 10},
+		{100, 10},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of empty slice = %v, want 0", got)
+	}
+}
+
+func TestComputeRightsizing(t *testing.T) {
+	opts := rightsizingOptions{Headroom: 0.2, IdleCPUThreshold: 0.05}
+
+	var avg, max map[string][]rrdSample
+	avg = map[string][]rrdSample{"cpu": {}, "mem": {}}
+	max = map[string][]rrdSample{"cpu": {}}
+	for i := 0; i < 100; i++ {
+		// 4 vCPUs at 25% => 1 core; memory 1 GiB
+		avg["cpu"] = append(avg["cpu"], rrdSample{Value: 0.25, Time: float64(i)})
+		avg["mem"] = append(avg["mem"], rrdSample{Value: 1 << 30, Time: float64(i)})
+		max["cpu"] = append(max["cpu"], rrdSample{Value: 0.5, Time: float64(i)})
+	}
+
+	r := computeRightsizing(avg, max, 4, 8<<30, opts)
+	if r.CPU.P95 != 1 || r.CPU.Max != 2 {
+		t.Errorf("unexpected CPU stats: %+v", r.CPU)
+	}
+	if r.RecommendedCPUs != 2 {
+		t.Errorf("expected 2 recommended vCPUs, got %v", r.RecommendedCPUs)
+	}
+	// 1 GiB * 1.2 rounded up to 256 MiB = 1.25 GiB
+	if r.RecommendedMem != 1280<<20 {
+		t.Errorf("expected 1280 MiB recommended memory, got %v", r.RecommendedMem)
+	}
+	if r.Idle {
+		t.Error("guest using 50% CPU should not be idle")
+	}
+
+	idle := computeRightsizing(
+		map[string][]rrdSample{"cpu": {{Value: 0.01}}},
+		map[string][]rrdSample{"cpu": {{Value: 0.02}}},
+		2, 2<<30, opts,
+	)
+	if !idle.Idle {
+		t.Error("guest peaking at 2% CPU should be idle")
+	}
+	if idle.RecommendedCPUs != 1 || idle.RecommendedMem != rightsizingMinMemory {
+		t.Errorf("expected minimum recommendations, got %v cpus, %v bytes", idle.RecommendedCPUs, idle.RecommendedMem)
+	}
+}
+
+func TestCollectRightsizingMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api2/json/nodes/pve1/qemu/100/rrddata", jsonHandler([]map[string]interface{}{
+		{"time": 1700000000, "cpu": 0.5, "maxcpu": 2, "mem": 1 << 30, "maxmem": 4 << 30},
+	}))
+	c := newTestCollector(t, mux)
+	c.collectors.Rightsizing.Timeframe = "week"
+	c.collectors.Rightsizing.RefreshInterval = time.Hour
+	c.collectors.Rightsizing.Headroom = 0.2
+
+	guests := map[string]GuestInfo{
+		"100": {Node: "pve1", Name: "web", Type: "qemu"},
+		"900": {Node: "pve1", Name: "tmpl", Type: "qemu", Template: true},
+	}
+
+	// Refresh synchronously; Collect would run it in the background
+	c.refreshRightsizing(guests)
+
+	ch := make(chan prometheus.Metric, 100)
+	c.collectRightsizingMetrics(ch, guests)
+	close(ch)
+
+	var recommended float64
+	count := 0
+	for m := range ch {
+		count++
+		if m.Desc() == c.rightsizingRecommendedCPUs {
+			recommended = getMetricValue(m)
+		}
+	}
+	// 3 cpu stats + 3 memory stats + 5 gauges for one guest + last refresh
+	if count != 12 {
+		t.Errorf("expected 12 metrics, got %d", count)
+	}
+	if recommended != 2 {
+		t.Errorf("expected 2 recommended vCPUs, got %v", recommended)
+	}
+}
@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// rrdSample holds the latest value of a single RRD data source
+type rrdSample struct {
+	Value float64
+	Time  float64
+}
+
+// parseRRDSeries parses a PVE rrddata response into time-ordered samples per data source.
+// Null values (steps PVE has not aggregated yet) are skipped.
+func parseRRDSeries(data []byte) (map[string][]rrdSample, error) {
+	var result struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	series := make(map[string][]rrdSample)
+	for _, entry := range result.Data {
+		ts, _ := entry["time"].(float64)
+		for key, raw := range entry {
+			value, ok := raw.(float64)
+			if !ok || key == "time" {
+				continue
+			}
+			series[key] = append(series[key], rrdSample{Value: value, Time: ts})
+		}
+	}
+
+	for _, samples := range series {
+		sort.Slice(samples, func(i, j int) bool { return samples[i].Time < samples[j].Time })
+	}
+	return series, nil
+}
+
+// parseRRDData parses a PVE rrddata response and returns the latest non-null value of every data source.
+// The newest entries are often still empty while PVE aggregates the current step.
+func parseRRDData(data []byte) (map[string]rrdSample, error) {
+	series, err := parseRRDSeries(data)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]rrdSample, len(series))
+	for key, samples := range series {
+		latest[key] = samples[len(samples)-1]
+	}
+	return latest, nil
+}
+
+// collectRRDMetrics collects the latest RRD samples for all nodes and their storages
+func (c *ProxmoxCollector) collectRRDMetrics(ch chan<- prometheus.Metric, nodes []string) {
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(nodeName string) {
+			defer wg.Done()
+			c.collectNodeRRDMetrics(ch, nodeName)
+		}(node)
+	}
+
+	for _, s := range c.discoverContentStorages(nodes) {
+		wg.Add(1)
+		go func(s contentStorage) {
+			defer wg.Done()
+			c.collectStorageRRDMetrics(ch, s)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// collectNodeRRDMetrics emits the latest samples from /nodes/{node}/rrddata
+func (c *ProxmoxCollector) collectNodeRRDMetrics(ch chan<- prometheus.Metric, nodeName string) {
+	data, err := c.apiRequest(fmt.Sprintf("/nodes/%s/rrddata?timeframe=hour&cf=AVERAGE", nodeName))
+	if err != nil {
+		log.Printf("Error fetching RRD data for node %s: %v", nodeName, err)
+		return
+	}
+
+	samples, err := parseRRDData(data)
+	if err != nil {
+		log.Printf("Error unmarshaling RRD data for node %s: %v", nodeName, err)
+		return
+	}
+
+	metrics := map[string]*prometheus.Desc{
+		"cpu":      c.nodeRRDCPU,
+		"iowait":   c.nodeRRDIOWait,
+		"loadavg":  c.nodeRRDLoadAvg,
+		"memused":  c.nodeRRDMemoryUsed,
+		"rootused": c.nodeRRDRootfsUsed,
+		"swapused": c.nodeRRDSwapUsed,
+	}
+	// Network throughput is only exposed for the local host unless enabled for all nodes,
+	// matching the locally collected disk I/O and sensor metrics
+	if c.collectors.RRD.NetworkAllNodes || nodeName == getHostname() {
+		metrics["netin"] = c.nodeRRDNetIn
+		metrics["netout"] = c.nodeRRDNetOut
+	}
+
+	labels := append([]string{nodeName}, c.labels.nodeValues(nodeName)...)
+	for key, desc := range metrics {
+		if sample, ok := samples[key]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, sample.Value, labels...)
+		}
+	}
+}
+
+// collectStorageRRDMetrics emits the latest samples from /nodes/{node}/storage/{storage}/rrddata
+func (c *ProxmoxCollector) collectStorageRRDMetrics(ch chan<- prometheus.Metric, s contentStorage) {
+	data, err := c.apiRequest(fmt.Sprintf("/nodes/%s/storage/%s/rrddata?timeframe=hour&cf=AVERAGE", s.Node, url.PathEscape(s.Storage)))
+	if err != nil {
+		log.Printf("Error fetching RRD data for storage %s on node %s: %v", s.Storage, s.Node, err)
+		return
+	}
+
+	samples, err := parseRRDData(data)
+	if err != nil {
+		log.Printf("Error unmarshaling RRD data for storage %s on node %s: %v", s.Storage, s.Node, err)
+		return
+	}
+
+	if sample, ok := samples["total"]; ok {
+		ch <- prometheus.MustNewConstMetric(c.storageRRDTotal, prometheus.GaugeValue, sample.Value, s.Node, s.Storage)
+	}
+	if sample, ok := samples["used"]; ok {
+		ch <- prometheus.MustNewConstMetric(c.storageRRDUsed, prometheus.GaugeValue, sample.Value, s.Node, s.Storage)
+	}
+}
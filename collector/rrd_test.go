@@ -0,0 +1,30 @@
+package collector
+
+import "testing"
+
+func TestParseRRDData(t *testing.T) {
+	data := []byte(`{"data":[
+		{"time":1700000000,"cpu":0.10,"netin":1000,"iowait":0.01},
+		{"time":1700000060,"cpu":0.20,"netin":2000},
+		{"time":1700000120}
+	]}`)
+
+	samples, err := parseRRDData(data)
+	if err != nil {
+		t.Fatalf("parseRRDData failed: %v", err)
+	}
+
+	if s := samples["cpu"]; s.Value != 0.20 || s.Time != 1700000060 {
+		t.Errorf("expected latest cpu 0.20 at 1700000060, got %+v", s)
+	}
+	if s := samples["netin"]; s.Value != 2000 {
+		t.Errorf("expected latest netin 2000, got %+v", s)
+	}
+	// iowait is missing from newer entries, so the older value is the latest one
+	if s := samples["iowait"]; s.Value != 0.01 || s.Time != 1700000000 {
+		t.Errorf("expected iowait 0.01 at 1700000000, got %+v", s)
+	}
+	if _, ok := samples["time"]; ok {
+		t.Error("time should not be reported as a data source")
+	}
+}
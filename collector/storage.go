@@ -9,12 +9,26 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// storageStatus is a storage as listed by /nodes/{node}/storage
+type storageStatus struct {
+	Storage      string  `json:"storage"`
+	Type         string  `json:"type"`
+	Total        float64 `json:"total"`
+	Used         float64 `json:"used"`
+	Avail        float64 `json:"avail"`
+	Active       int     `json:"active"`
+	Enabled      int     `json:"enabled"`
+	Shared       int     `json:"shared"`
+	UsedFraction float64 `json:"used_fraction"`
+}
+
 // collectStorageMetrics collects storage metrics for all nodes in parallel
 func (c *ProxmoxCollector) collectStorageMetrics(ch chan<- prometheus.Metric, nodes []string) {
+	perNode := make([][]storageStatus, len(nodes))
 	var wg sync.WaitGroup
-	for _, node := range nodes {
+	for i, node := range nodes {
 		wg.Add(1)
-		go func(nodeName string) {
+		go func(i int, nodeName string) {
 			defer wg.Done()
 
 			path := fmt.Sprintf("/nodes/%s/storage", nodeName)
@@ -25,17 +39,7 @@ func (c *ProxmoxCollector) collectStorageMetrics(ch chan<- prometheus.Metric, no
 			}
 
 			var result struct {
-				Data []struct {
-					Storage      string  `json:"storage"`
-					Type         string  `json:"type"`
-					Total        float64 `json:"total"`
-					Used         float64 `json:"used"`
-					Avail        float64 `json:"avail"`
-					Active       int     `json:"active"`
-					Enabled      int     `json:"enabled"`
-					Shared       int     `json:"shared"`
-					UsedFraction float64 `json:"used_fraction"`
-				} `json:"data"`
+				Data []storageStatus `json:"data"`
 			}
 
 			if err := json.Unmarshal(storageData, &result); err != nil {
@@ -53,7 +57,33 @@ func (c *ProxmoxCollector) collectStorageMetrics(ch chan<- prometheus.Metric, no
 				ch <- prometheus.MustNewConstMetric(c.storageShared, prometheus.GaugeValue, float64(storage.Shared), labels...)
 				ch <- prometheus.MustNewConstMetric(c.storageUsedFraction, prometheus.GaugeValue, storage.UsedFraction, labels...)
 			}
-		}(node)
+			perNode[i] = result.Data
+		}(i, node)
 	}
 	wg.Wait()
+
+	if c.cost.Enabled {
+		c.emitStorageCostMetrics(ch, nodes, perNode)
+	}
+}
+
+// emitStorageCostMetrics emits the hourly cost of priced storages. Shared storages (Ceph, NFS, PBS, ...)
+// are listed by every node but priced once, on the first node (in nodes order) that lists them.
+func (c *ProxmoxCollector) emitStorageCostMetrics(ch chan<- prometheus.Metric, nodes []string, perNode [][]storageStatus) {
+	seenShared := make(map[string]bool)
+	for i, storages := range perNode {
+		for _, storage := range storages {
+			if _, priced := c.cost.StorageGiBHour[storage.Type]; !priced {
+				continue
+			}
+			if storage.Shared == 1 {
+				if seenShared[storage.Storage] {
+					continue
+				}
+				seenShared[storage.Storage] = true
+			}
+			ch <- prometheus.MustNewConstMetric(c.costStorageHourly, prometheus.GaugeValue, c.storagePrice(storage.Type, storage.Used),
+				nodes[i], storage.Storage, storage.Type)
+		}
+	}
 }
@@ -11,27 +11,57 @@ import (
 )
 
 // collectVMMetricsWithNodes collects VM and container metrics using pre-fetched nodes list
-func (c *ProxmoxCollector) collectVMMetricsWithNodes(ch chan<- prometheus.Metric, nodes []string) {
+func (c *ProxmoxCollector) collectVMMetricsWithNodes(ch chan<- prometheus.Metric, nodes []string, guests map[string]GuestInfo, configs *guestConfigCache) {
 	// Process all nodes in parallel for better performance
 	var wg sync.WaitGroup
 	for _, node := range nodes {
 		wg.Add(1)
 		go func(nodeName string) {
 			defer wg.Done()
+			nodeLabels := append([]string{nodeName}, c.labels.nodeValues(nodeName)...)
+
 			// QEMU VMs
-			vmCount := c.collectResourceMetrics(ch, nodeName, "qemu")
-			ch <- prometheus.MustNewConstMetric(c.nodeVMCount, prometheus.GaugeValue, float64(vmCount), nodeName)
+			vmCount := c.collectResourceMetrics(ch, nodeName, "qemu", guests, configs)
+			ch <- prometheus.MustNewConstMetric(c.nodeVMCount, prometheus.GaugeValue, float64(vmCount), nodeLabels...)
 
 			// LXC containers
-			lxcCount := c.collectResourceMetrics(ch, nodeName, "lxc")
-			ch <- prometheus.MustNewConstMetric(c.nodeLXCCount, prometheus.GaugeValue, float64(lxcCount), nodeName)
+			lxcCount := c.collectResourceMetrics(ch, nodeName, "lxc", guests, configs)
+			ch <- prometheus.MustNewConstMetric(c.nodeLXCCount, prometheus.GaugeValue, float64(lxcCount), nodeLabels...)
 		}(node)
 	}
 	wg.Wait()
 }
 
-// collectResourceMetrics collects metrics for VMs or containers and returns the count
-func (c *ProxmoxCollector) collectResourceMetrics(ch chan<- prometheus.Metric, node, resType string) int {
+// guestListEntry is a guest as returned by /nodes/{node}/qemu and /nodes/{node}/lxc
+type guestListEntry struct {
+	VMID      int64   `json:"vmid"`
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Uptime    float64 `json:"uptime"`
+	CPU       float64 `json:"cpu"`
+	CPUs      float64 `json:"cpus"`
+	Mem       float64 `json:"mem"`
+	MaxMem    float64 `json:"maxmem"`
+	Disk      float64 `json:"disk"`
+	MaxDisk   float64 `json:"maxdisk"`
+	NetIn     float64 `json:"netin"`
+	NetOut    float64 `json:"netout"`
+	DiskRead  float64 `json:"diskread"`
+	DiskWrite float64 `json:"diskwrite"`
+	Tags      string  `json:"tags"`
+}
+
+// guestInfoFor returns the cluster-wide info of a guest, falling back to the per-node list entry
+func guestInfoFor(guests map[string]GuestInfo, vmid, node, resType string, vm guestListEntry) GuestInfo {
+	if guest, ok := guests[vmid]; ok {
+		return guest
+	}
+	return GuestInfo{Node: node, Name: vm.Name, Type: resType, Status: vm.Status, Tags: vm.Tags}
+}
+
+// collectResourceMetrics collects metrics for VMs or containers and returns the count.
+// Guests are matched against the configured filters using their cluster-wide info.
+func (c *ProxmoxCollector) collectResourceMetrics(ch chan<- prometheus.Metric, node, resType string, guests map[string]GuestInfo, configs *guestConfigCache) int {
 	path := fmt.Sprintf("/nodes/%s/%s", node, resType)
 	data, err := c.apiRequest(path)
 	if err != nil {
@@ -40,22 +70,7 @@ func (c *ProxmoxCollector) collectResourceMetrics(ch chan<- prometheus.Metric, n
 	}
 
 	var result struct {
-		Data []struct {
-			VMID      int64   `json:"vmid"`
-			Name      string  `json:"name"`
-			Status    string  `json:"status"`
-			Uptime    float64 `json:"uptime"`
-			CPU       float64 `json:"cpu"`
-			CPUs      float64 `json:"cpus"`
-			Mem       float64 `json:"mem"`
-			MaxMem    float64 `json:"maxmem"`
-			Disk      float64 `json:"disk"`
-			MaxDisk   float64 `json:"maxdisk"`
-			NetIn     float64 `json:"netin"`
-			NetOut    float64 `json:"netout"`
-			DiskRead  float64 `json:"diskread"`
-			DiskWrite float64 `json:"diskwrite"`
-		} `json:"data"`
+		Data []guestListEntry `json:"data"`
 	}
 
 	if err := json.Unmarshal(data, &result); err != nil {
@@ -67,23 +82,15 @@ func (c *ProxmoxCollector) collectResourceMetrics(ch chan<- prometheus.Metric, n
 	var wg sync.WaitGroup
 
 	for _, vm := range result.Data {
+		vmid := strconv.FormatInt(vm.VMID, 10)
+		guest := guestInfoFor(guests, vmid, node, resType, vm)
+		action := c.filter.classify(vmid, guest)
+		if action == filterExclude {
+			continue
+		}
+
 		wg.Add(1)
-		go func(vm struct {
-			VMID      int64   `json:"vmid"`
-			Name      string  `json:"name"`
-			Status    string  `json:"status"`
-			Uptime    float64 `json:"uptime"`
-			CPU       float64 `json:"cpu"`
-			CPUs      float64 `json:"cpus"`
-			Mem       float64 `json:"mem"`
-			MaxMem    float64 `json:"maxmem"`
-			Disk      float64 `json:"disk"`
-			MaxDisk   float64 `json:"maxdisk"`
-			NetIn     float64 `json:"netin"`
-			NetOut    float64 `json:"netout"`
-			DiskRead  float64 `json:"diskread"`
-			DiskWrite float64 `json:"diskwrite"`
-		}) {
+		go func(vm guestListEntry) {
 			defer wg.Done()
 
 			status := 0.0
@@ -91,7 +98,13 @@ func (c *ProxmoxCollector) collectResourceMetrics(ch chan<- prometheus.Metric, n
 				status = 1.0
 			}
 
-			labels := []string{node, fmt.Sprintf("%d", vm.VMID), vm.Name}
+			// Light guests only export their status, without the /status/current or /config calls
+			if action == filterLight {
+				labels := append([]string{node, vmid, vm.Name}, c.labels.guestValues(guest, "")...)
+				c.emitGuestMetric(ch, guestDescs{c.vmStatus, c.lxcStatus, c.guestStatus}, prometheus.GaugeValue, status, resType, labels)
+				return
+			}
+			labels := append([]string{node, vmid, vm.Name}, c.guestLabelValues(vmid, guest, configs)...)
 
 			// Get detailed status ONCE for all metrics (disk I/O, balloon, pressure, etc.)
 			diskRead := vm.DiskRead
@@ -115,33 +128,27 @@ func (c *ProxmoxCollector) collectResourceMetrics(ch chan<- prometheus.Metric, n
 				}
 			}
 
+			// Metrics shared by VMs and containers follow the configured naming scheme
+			emit := func(d guestDescs, valueType prometheus.ValueType, value float64) {
+				c.emitGuestMetric(ch, d, valueType, value, resType, labels)
+			}
+			emit(guestDescs{c.vmStatus, c.lxcStatus, c.guestStatus}, prometheus.GaugeValue, status)
+			emit(guestDescs{c.vmUptime, c.lxcUptime, c.guestUptime}, prometheus.GaugeValue, vm.Uptime)
+			emit(guestDescs{c.vmCPU, c.lxcCPU, c.guestCPU}, prometheus.GaugeValue, vm.CPU)
+			emit(guestDescs{c.vmCPUs, c.lxcCPUs, c.guestCPUs}, prometheus.GaugeValue, vm.CPUs)
+			emit(guestDescs{c.vmMemory, c.lxcMemory, c.guestMemory}, prometheus.GaugeValue, vm.Mem)
+			emit(guestDescs{c.vmMaxMemory, c.lxcMaxMemory, c.guestMaxMemory}, prometheus.GaugeValue, vm.MaxMem)
+			emit(guestDescs{c.vmMaxDisk, c.lxcMaxDisk, c.guestMaxDisk}, prometheus.GaugeValue, vm.MaxDisk)
+			emit(guestDescs{c.vmNetIn, c.lxcNetIn, c.guestNetIn}, prometheus.CounterValue, vm.NetIn)
+			emit(guestDescs{c.vmNetOut, c.lxcNetOut, c.guestNetOut}, prometheus.CounterValue, vm.NetOut)
+			emit(guestDescs{c.vmDiskRead, c.lxcDiskRead, c.guestDiskRead}, prometheus.CounterValue, diskRead)
+			emit(guestDescs{c.vmDiskWrite, c.lxcDiskWrite, c.guestDiskWrite}, prometheus.CounterValue, diskWrite)
+
 			if resType == "lxc" {
-				ch <- prometheus.MustNewConstMetric(c.lxcStatus, prometheus.GaugeValue, status, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcUptime, prometheus.GaugeValue, vm.Uptime, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcCPU, prometheus.GaugeValue, vm.CPU, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcCPUs, prometheus.GaugeValue, vm.CPUs, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcMemory, prometheus.GaugeValue, vm.Mem, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcMaxMemory, prometheus.GaugeValue, vm.MaxMem, labels...)
 				ch <- prometheus.MustNewConstMetric(c.lxcDisk, prometheus.GaugeValue, vm.Disk, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcMaxDisk, prometheus.GaugeValue, vm.MaxDisk, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcNetIn, prometheus.CounterValue, vm.NetIn, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcNetOut, prometheus.CounterValue, vm.NetOut, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcDiskRead, prometheus.CounterValue, diskRead, labels...)
-				ch <- prometheus.MustNewConstMetric(c.lxcDiskWrite, prometheus.CounterValue, diskWrite, labels...)
 				// Get LXC swap - reuse detailData if available
 				c.collectLXCSwapMetricsFromData(ch, detailData, labels)
 			} else {
-				ch <- prometheus.MustNewConstMetric(c.vmStatus, prometheus.GaugeValue, status, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmUptime, prometheus.GaugeValue, vm.Uptime, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmCPU, prometheus.GaugeValue, vm.CPU, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmCPUs, prometheus.GaugeValue, vm.CPUs, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmMemory, prometheus.GaugeValue, vm.Mem, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmMaxMemory, prometheus.GaugeValue, vm.MaxMem, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmMaxDisk, prometheus.GaugeValue, vm.MaxDisk, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmNetIn, prometheus.CounterValue, vm.NetIn, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmNetOut, prometheus.CounterValue, vm.NetOut, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmDiskRead, prometheus.CounterValue, diskRead, labels...)
-				ch <- prometheus.MustNewConstMetric(c.vmDiskWrite, prometheus.CounterValue, diskWrite, labels...)
 				// Get VM detailed metrics - reuse detailData instead of making another API call
 				c.collectVMDetailedMetricsFromData(ch, detailData, labels)
 			}
@@ -181,26 +188,24 @@ func (c *ProxmoxCollector) collectLXCSwapMetricsFromData(ch chan<- prometheus.Me
 
 	ch <- prometheus.MustNewConstMetric(c.lxcSwap, prometheus.GaugeValue, result.Data.Swap, labels...)
 	ch <- prometheus.MustNewConstMetric(c.lxcMaxSwap, prometheus.GaugeValue, result.Data.MaxSwap, labels...)
-	ch <- prometheus.MustNewConstMetric(c.lxcHAManaged, prometheus.GaugeValue, float64(result.Data.HA.Managed), labels...)
-	ch <- prometheus.MustNewConstMetric(c.lxcPID, prometheus.GaugeValue, result.Data.PID, labels...)
+	c.emitGuestMetric(ch, guestDescs{c.vmHAManaged, c.lxcHAManaged, c.guestHAManaged}, prometheus.GaugeValue, float64(result.Data.HA.Managed), "lxc", labels)
+	c.emitGuestMetric(ch, guestDescs{c.vmPID, c.lxcPID, c.guestPID}, prometheus.GaugeValue, result.Data.PID, "lxc", labels)
 	// Pressure metrics (LXC returns strings)
-	if cpuFull, err := strconv.ParseFloat(result.Data.PressureCPUFull, 64); err == nil {
-		ch <- prometheus.MustNewConstMetric(c.lxcPressureCPUFull, prometheus.GaugeValue, cpuFull, labels...)
-	}
-	if cpuSome, err := strconv.ParseFloat(result.Data.PressureCPUSome, 64); err == nil {
-		ch <- prometheus.MustNewConstMetric(c.lxcPressureCPUSome, prometheus.GaugeValue, cpuSome, labels...)
-	}
-	if ioFull, err := strconv.ParseFloat(result.Data.PressureIOFull, 64); err == nil {
-		ch <- prometheus.MustNewConstMetric(c.lxcPressureIOFull, prometheus.GaugeValue, ioFull, labels...)
-	}
-	if ioSome, err := strconv.ParseFloat(result.Data.PressureIOSome, 64); err == nil {
-		ch <- prometheus.MustNewConstMetric(c.lxcPressureIOSome, prometheus.GaugeValue, ioSome, labels...)
-	}
-	if memFull, err := strconv.ParseFloat(result.Data.PressureMemoryFull, 64); err == nil {
-		ch <- prometheus.MustNewConstMetric(c.lxcPressureMemoryFull, prometheus.GaugeValue, memFull, labels...)
+	pressure := []struct {
+		d     guestDescs
+		value string
+	}{
+		{guestDescs{c.vmPressureCPUFull, c.lxcPressureCPUFull, c.guestPressureCPUFull}, result.Data.PressureCPUFull},
+		{guestDescs{c.vmPressureCPUSome, c.lxcPressureCPUSome, c.guestPressureCPUSome}, result.Data.PressureCPUSome},
+		{guestDescs{c.vmPressureIOFull, c.lxcPressureIOFull, c.guestPressureIOFull}, result.Data.PressureIOFull},
+		{guestDescs{c.vmPressureIOSome, c.lxcPressureIOSome, c.guestPressureIOSome}, result.Data.PressureIOSome},
+		{guestDescs{c.vmPressureMemoryFull, c.lxcPressureMemoryFull, c.guestPressureMemoryFull}, result.Data.PressureMemoryFull},
+		{guestDescs{c.vmPressureMemorySome, c.lxcPressureMemorySome, c.guestPressureMemorySome}, result.Data.PressureMemorySome},
 	}
-	if memSome, err := strconv.ParseFloat(result.Data.PressureMemorySome, 64); err == nil {
-		ch <- prometheus.MustNewConstMetric(c.lxcPressureMemorySome, prometheus.GaugeValue, memSome, labels...)
+	for _, p := range pressure {
+		if v, err := strconv.ParseFloat(p.value, 64); err == nil {
+			c.emitGuestMetric(ch, p.d, prometheus.GaugeValue, v, "lxc", labels)
+		}
 	}
 }
 
@@ -256,16 +261,16 @@ func (c *ProxmoxCollector) collectVMDetailedMetricsFromData(ch chan<- prometheus
 
 	ch <- prometheus.MustNewConstMetric(c.vmBalloon, prometheus.GaugeValue, result.Data.Balloon, labels...)
 	ch <- prometheus.MustNewConstMetric(c.vmFreeMem, prometheus.GaugeValue, result.Data.FreeMem, labels...)
-	ch <- prometheus.MustNewConstMetric(c.vmHAManaged, prometheus.GaugeValue, float64(result.Data.HA.Managed), labels...)
-	ch <- prometheus.MustNewConstMetric(c.vmPID, prometheus.GaugeValue, result.Data.PID, labels...)
+	c.emitGuestMetric(ch, guestDescs{c.vmHAManaged, c.lxcHAManaged, c.guestHAManaged}, prometheus.GaugeValue, float64(result.Data.HA.Managed), "qemu", labels)
+	c.emitGuestMetric(ch, guestDescs{c.vmPID, c.lxcPID, c.guestPID}, prometheus.GaugeValue, result.Data.PID, "qemu", labels)
 	ch <- prometheus.MustNewConstMetric(c.vmMemHost, prometheus.GaugeValue, result.Data.MemHost, labels...)
 	// Pressure metrics
-	ch <- prometheus.MustNewConstMetric(c.vmPressureCPUFull, prometheus.GaugeValue, result.Data.PressureCPUFull, labels...)
-	ch <- prometheus.MustNewConstMetric(c.vmPressureCPUSome, prometheus.GaugeValue, result.Data.PressureCPUSome, labels...)
-	ch <- prometheus.MustNewConstMetric(c.vmPressureIOFull, prometheus.GaugeValue, result.Data.PressureIOFull, labels...)
-	ch <- prometheus.MustNewConstMetric(c.vmPressureIOSome, prometheus.GaugeValue, result.Data.PressureIOSome, labels...)
-	ch <- prometheus.MustNewConstMetric(c.vmPressureMemoryFull, prometheus.GaugeValue, result.Data.PressureMemoryFull, labels...)
-	ch <- prometheus.MustNewConstMetric(c.vmPressureMemorySome, prometheus.GaugeValue, result.Data.PressureMemorySome, labels...)
+	c.emitGuestMetric(ch, guestDescs{c.vmPressureCPUFull, c.lxcPressureCPUFull, c.guestPressureCPUFull}, prometheus.GaugeValue, result.Data.PressureCPUFull, "qemu", labels)
+	c.emitGuestMetric(ch, guestDescs{c.vmPressureCPUSome, c.lxcPressureCPUSome, c.guestPressureCPUSome}, prometheus.GaugeValue, result.Data.PressureCPUSome, "qemu", labels)
+	c.emitGuestMetric(ch, guestDescs{c.vmPressureIOFull, c.lxcPressureIOFull, c.guestPressureIOFull}, prometheus.GaugeValue, result.Data.PressureIOFull, "qemu", labels)
+	c.emitGuestMetric(ch, guestDescs{c.vmPressureIOSome, c.lxcPressureIOSome, c.guestPressureIOSome}, prometheus.GaugeValue, result.Data.PressureIOSome, "qemu", labels)
+	c.emitGuestMetric(ch, guestDescs{c.vmPressureMemoryFull, c.lxcPressureMemoryFull, c.guestPressureMemoryFull}, prometheus.GaugeValue, result.Data.PressureMemoryFull, "qemu", labels)
+	c.emitGuestMetric(ch, guestDescs{c.vmPressureMemorySome, c.lxcPressureMemorySome, c.guestPressureMemorySome}, prometheus.GaugeValue, result.Data.PressureMemorySome, "qemu", labels)
 	// Balloon info
 	ch <- prometheus.MustNewConstMetric(c.vmBalloonActual, prometheus.GaugeValue, result.Data.BalloonInfo.Actual, labels...)
 	ch <- prometheus.MustNewConstMetric(c.vmBalloonMaxMem, prometheus.GaugeValue, result.Data.BalloonInfo.MaxMem, labels...)
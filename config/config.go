@@ -1,8 +1,14 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -10,8 +16,26 @@ import (
 
 // Config holds the application configuration
 type Config struct {
-	Proxmox ProxmoxConfig `yaml:"proxmox"`
-	Server  ServerConfig  `yaml:"server"`
+	Proxmox    ProxmoxConfig    `yaml:"proxmox"`
+	Server     ServerConfig     `yaml:"server"`
+	Collectors CollectorsConfig `yaml:"collectors"`
+	Cost       CostConfig       `yaml:"cost"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Filters    FiltersConfig    `yaml:"filters"`
+	Labels     LabelsConfig     `yaml:"labels"`
+	Push       PushConfig       `yaml:"push"`
+}
+
+// Metric naming schemes for metrics shared by VMs and containers
+const (
+	NamingLegacy  = "legacy"  // pve_vm_* and pve_lxc_* families
+	NamingUnified = "unified" // pve_guest_* families with a type label
+	NamingBoth    = "both"    // emit both, for migrating dashboards
+)
+
+// MetricsConfig holds settings for exported metric names
+type MetricsConfig struct {
+	Naming string `yaml:"naming"`
 }
 
 // ProxmoxConfig holds Proxmox API configuration
@@ -19,41 +43,357 @@ type ProxmoxConfig struct {
 	Host               string        `yaml:"host"`
 	Port               int           `yaml:"port"`
 	User               string        `yaml:"user"`
-	Password           string        `yaml:"password"`
+	Password           Secret        `yaml:"password"`
 	TokenID            string        `yaml:"token_id"`
-	TokenSecret        string        `yaml:"token_secret"`
+	TokenSecret        Secret        `yaml:"token_secret"`
 	Realm              string        `yaml:"realm"`
 	InsecureSkipVerify bool          `yaml:"insecure_skip_verify"`
 	Timeout            time.Duration `yaml:"timeout"`
+	// PasswordFile and TokenSecretFile are read instead of the inline values and re-read when they change.
+	// Relative paths are resolved against $CREDENTIALS_DIRECTORY (systemd LoadCredential=).
+	PasswordFile    string `yaml:"password_file"`
+	TokenSecretFile string `yaml:"token_secret_file"`
+	// CredentialHelper prints the token secret (if token_id is set) or the password on stdout
+	CredentialHelper CredentialHelperConfig `yaml:"credential_helper"`
+	// CAFile is a PEM bundle trusted for the PVE API, e.g. /etc/pve/pve-root-ca.pem
+	CAFile string `yaml:"ca_file"`
+	// Fingerprint pins the SHA-256 fingerprint of the PVE API certificate
+	Fingerprint string `yaml:"fingerprint"`
+	// CertFile and KeyFile are an optional client certificate presented to the PVE API
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// Hosts are fallback API endpoints ("host" or "host:port"), tried in order when the current one is unreachable
+	Hosts []string `yaml:"hosts"`
+	// DiscoverHosts adds the IPs of online cluster nodes from /cluster/status as fallback endpoints
+	DiscoverHosts bool `yaml:"discover_hosts"`
+}
+
+// Endpoint returns "host:port" for a host, using the configured port unless the host includes one
+func (p ProxmoxConfig) Endpoint(host string) string {
+	if h, port, err := net.SplitHostPort(host); err == nil && h != "" && port != "" {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(p.Port))
+}
+
+// Endpoints returns the primary API endpoint followed by the fallback endpoints
+func (p ProxmoxConfig) Endpoints() []string {
+	endpoints := []string{p.Endpoint(p.Host)}
+	for _, host := range p.Hosts {
+		endpoints = append(endpoints, p.Endpoint(strings.TrimSpace(host)))
+	}
+	return endpoints
+}
+
+// UsesToken reports whether API token authentication is configured
+func (p ProxmoxConfig) UsesToken() bool {
+	return p.TokenID != "" && (p.TokenSecret != "" || p.TokenSecretFile != "" || len(p.CredentialHelper.Command) > 0)
+}
+
+// hasPassword reports whether password authentication is configured
+func (p ProxmoxConfig) hasPassword() bool {
+	return p.Password != "" || p.PasswordFile != "" || len(p.CredentialHelper.Command) > 0
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
 	ListenAddress string `yaml:"listen_address"`
 	MetricsPath   string `yaml:"metrics_path"`
+	// WebConfigFile is a Prometheus exporter-toolkit web config (TLS, client certificates, basic auth)
+	WebConfigFile string `yaml:"web_config_file"`
+	// BearerToken, if set, must be sent as "Authorization: Bearer <token>" on every request
+	BearerToken Secret `yaml:"bearer_token"`
+	// BearerTokenFile is read instead of BearerToken and re-read when it changes
+	BearerTokenFile string `yaml:"bearer_token_file"`
+	// AllowedNetworks restricts clients to these IPs or CIDR ranges (empty allows everyone)
+	AllowedNetworks []string `yaml:"allowed_networks"`
 }
 
-// LoadFromFile loads configuration from file and environment variables
-func LoadFromFile(configFile string) (*Config, error) {
+// ParseNetworks parses a list of IPs and CIDR ranges; single IPs match only themselves
+func ParseNetworks(list []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(list))
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// CollectorsConfig holds settings for optional collectors
+type CollectorsConfig struct {
+	Orphans CollectorConfig `yaml:"orphans"`
+	Content CollectorConfig `yaml:"content"`
+	// Pending fetches each guest's /pending changes, one API call per fully collected guest and scrape
+	Pending     CollectorConfig   `yaml:"pending"`
+	RRD         RRDConfig         `yaml:"rrd"`
+	Rightsizing RightsizingConfig `yaml:"rightsizing"`
+}
+
+// CollectorConfig holds settings for an optional collector without extra options
+type CollectorConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// RRDConfig holds settings for the RRD collector
+type RRDConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// NetworkAllNodes exposes network throughput for every node, not only the local host
+	NetworkAllNodes bool `yaml:"network_all_nodes"`
+}
+
+// RightsizingConfig holds settings for the right-sizing collector
+type RightsizingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Timeframe is the PVE RRD timeframe to analyze ("week" or "month")
+	Timeframe       string        `yaml:"timeframe"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// Headroom is added on top of p95 usage for recommendations (0.2 = 20%)
+	Headroom float64 `yaml:"headroom"`
+	// IdleCPUThreshold flags guests whose peak CPU usage stayed below this ratio of allocated vCPUs
+	IdleCPUThreshold float64 `yaml:"idle_cpu_threshold"`
+}
+
+// CostConfig holds the price table used for cost and chargeback metrics.
+// All prices are per hour of allocation.
+type CostConfig struct {
+	Enabled       bool    `yaml:"enabled"`
+	Currency      string  `yaml:"currency"`
+	CPUHour       float64 `yaml:"cpu_hour"`
+	MemoryGiBHour float64 `yaml:"memory_gib_hour"`
+	// StorageGiBHour maps a PVE storage type (zfspool, lvmthin, nfs, ...) to its price per GiB-hour
+	StorageGiBHour map[string]float64 `yaml:"storage_gib_hour"`
+	// LedgerFile persists accumulated chargeback data across restarts (optional)
+	LedgerFile string `yaml:"ledger_file"`
+	// RetentionMonths is the number of months kept in the ledger, including the current one (0 keeps all)
+	RetentionMonths int `yaml:"retention_months"`
+}
+
+// FiltersConfig holds guest filter rules. A guest is excluded if it matches an exclude rule,
+// or if include rules exist and it matches none of them. Guests matching a light rule only
+// get their status exported, without per-guest API calls.
+type FiltersConfig struct {
+	Include []GuestFilterRule `yaml:"include"`
+	Exclude []GuestFilterRule `yaml:"exclude"`
+	Light   []GuestFilterRule `yaml:"light"`
+}
+
+// GuestFilterRule matches guests on all of its non-empty fields; list fields match if any entry matches
+type GuestFilterRule struct {
+	Name  string   `yaml:"name"`  // regular expression matched against the guest name
+	VMIDs []string `yaml:"vmids"` // single IDs ("100") or inclusive ranges ("9000-9999")
+	Tags  []string `yaml:"tags"`
+	Pools []string `yaml:"pools"`
+	Nodes []string `yaml:"nodes"`
+	Type  string   `yaml:"type"` // "qemu" or "lxc"
+}
+
+// ParseVMIDRange parses a single VMID ("100") or an inclusive range ("9000-9999")
+func ParseVMIDRange(s string) (int64, int64, error) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	from, err := strconv.ParseInt(strings.TrimSpace(lo), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid vmid %q", s)
+	}
+	if !isRange {
+		return from, from, nil
+	}
+	to, err := strconv.ParseInt(strings.TrimSpace(hi), 10, 64)
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("invalid vmid range %q", s)
+	}
+	return from, to, nil
+}
+
+// validate checks that the rule matches on something and that its regular expression and VMID ranges parse.
+// An empty rule would match every guest, e.g. excluding all of them.
+func (r GuestFilterRule) validate() error {
+	if r.Name == "" && len(r.VMIDs) == 0 && len(r.Tags) == 0 && len(r.Pools) == 0 && len(r.Nodes) == 0 && r.Type == "" {
+		return fmt.Errorf("rule must set at least one of name, vmids, tags, pools, nodes or type")
+	}
+	if _, err := regexp.Compile(r.Name); err != nil {
+		return fmt.Errorf("invalid name pattern %q: %w", r.Name, err)
+	}
+	for _, v := range r.VMIDs {
+		if _, _, err := ParseVMIDRange(v); err != nil {
+			return err
+		}
+	}
+	if r.Type != "" && r.Type != "qemu" && r.Type != "lxc" {
+		return fmt.Errorf("invalid guest type %q", r.Type)
+	}
+	return nil
+}
+
+// LabelsConfig holds extra labels attached to guest and node metrics
+type LabelsConfig struct {
+	// Tags maps a label name to a tag prefix: with {env: "env-"}, tag "env-prod" sets env="prod"
+	Tags map[string]string `yaml:"tags"`
+	// Description lists keys read from "key: value" or "key=value" lines in guest notes
+	Description []string `yaml:"description"`
+	// Nodes maps a node name to static labels, applied to the node and its guests
+	Nodes map[string]map[string]string `yaml:"nodes"`
+}
+
+// labelNameRe matches valid Prometheus label names
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are label names already used by guest and node metrics
+var reservedLabels = map[string]bool{"node": true, "vmid": true, "name": true, "type": true, "device": true, "interface": true}
+
+// validateLabelName checks that an extra label name is valid and does not shadow a built-in label
+func validateLabelName(name string) error {
+	if !labelNameRe.MatchString(name) || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("invalid label name %q", name)
+	}
+	if reservedLabels[name] {
+		return fmt.Errorf("label name %q is reserved", name)
+	}
+	return nil
+}
 
-	// Default configuration
-	cfg := &Config{
+// validate checks every configured extra label name
+func (l LabelsConfig) validate() error {
+	for name := range l.Tags {
+		if err := validateLabelName(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range l.Description {
+		if err := validateLabelName(name); err != nil {
+			return err
+		}
+	}
+	for _, labels := range l.Nodes {
+		for name := range labels {
+			if err := validateLabelName(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// defaultConfig returns the built-in default configuration
+func defaultConfig() *Config {
+	return &Config{
 		Proxmox: ProxmoxConfig{
-			Host:               getEnv("PVE_HOST", "localhost"),
+			Host:               "localhost",
 			Port:               8006,
-			User:               getEnv("PVE_USER", "root@pam"),
-			Password:           getEnv("PVE_PASSWORD", ""),
-			TokenID:            getEnv("PVE_TOKEN_ID", ""),
-			TokenSecret:        getEnv("PVE_TOKEN_SECRET", ""),
-			Realm:              getEnv("PVE_REALM", "pam"),
-			InsecureSkipVerify: getEnvBool("PVE_INSECURE_SKIP_VERIFY", true),
+			User:               "root@pam",
+			Realm:              "pam",
+			InsecureSkipVerify: true,
 			Timeout:            30 * time.Second,
+			CredentialHelper: CredentialHelperConfig{
+				RefreshInterval: 5 * time.Minute,
+				Timeout:         30 * time.Second,
+			},
 		},
 		Server: ServerConfig{
-			ListenAddress: getEnv("LISTEN_ADDRESS", ":9221"),
-			MetricsPath:   getEnv("METRICS_PATH", "/metrics"),
+			ListenAddress: ":9221",
+			MetricsPath:   "/metrics",
+		},
+		Collectors: CollectorsConfig{
+			Pending: CollectorConfig{Enabled: true},
+			Rightsizing: RightsizingConfig{
+				Timeframe:        "week",
+				RefreshInterval:  time.Hour,
+				Headroom:         0.2,
+				IdleCPUThreshold: 0.05,
+			},
 		},
+		Cost: CostConfig{
+			Currency:        "USD",
+			RetentionMonths: 24,
+		},
+		Metrics: MetricsConfig{
+			Naming: NamingLegacy,
+		},
+		Push: defaultPushConfig(),
+	}
+}
+
+// applyEnv overrides settings with the environment variables that are set, so they take precedence
+// over both the defaults and the config file
+func applyEnv(cfg *Config, env *envReader) {
+	p := &cfg.Proxmox
+	p.Host = getEnv("PVE_HOST", p.Host)
+	p.Port = env.int("PVE_PORT", p.Port)
+	p.User = getEnv("PVE_USER", p.User)
+	p.Password = Secret(getEnv("PVE_PASSWORD", string(p.Password)))
+	p.TokenID = getEnv("PVE_TOKEN_ID", p.TokenID)
+	p.TokenSecret = Secret(getEnv("PVE_TOKEN_SECRET", string(p.TokenSecret)))
+	p.Realm = getEnv("PVE_REALM", p.Realm)
+	p.InsecureSkipVerify = getEnvBool("PVE_INSECURE_SKIP_VERIFY", p.InsecureSkipVerify)
+	p.Timeout = env.duration("PVE_TIMEOUT", p.Timeout)
+	p.PasswordFile = getEnv("PVE_PASSWORD_FILE", p.PasswordFile)
+	p.TokenSecretFile = getEnv("PVE_TOKEN_SECRET_FILE", p.TokenSecretFile)
+	if command := os.Getenv("PVE_CREDENTIAL_HELPER"); command != "" {
+		p.CredentialHelper.Command = strings.Fields(command)
 	}
+	p.CredentialHelper.RefreshInterval = env.duration("PVE_CREDENTIAL_HELPER_REFRESH_INTERVAL", p.CredentialHelper.RefreshInterval)
+	p.CredentialHelper.Timeout = env.duration("PVE_CREDENTIAL_HELPER_TIMEOUT", p.CredentialHelper.Timeout)
+	p.CAFile = getEnv("PVE_CA_FILE", p.CAFile)
+	p.Fingerprint = getEnv("PVE_FINGERPRINT", p.Fingerprint)
+	p.CertFile = getEnv("PVE_CERT_FILE", p.CertFile)
+	p.KeyFile = getEnv("PVE_KEY_FILE", p.KeyFile)
+	p.Hosts = getEnvList("PVE_HOSTS", p.Hosts)
+	p.DiscoverHosts = getEnvBool("PVE_DISCOVER_HOSTS", p.DiscoverHosts)
+
+	s := &cfg.Server
+	s.ListenAddress = getEnv("LISTEN_ADDRESS", s.ListenAddress)
+	s.MetricsPath = getEnv("METRICS_PATH", s.MetricsPath)
+	s.WebConfigFile = getEnv("WEB_CONFIG_FILE", s.WebConfigFile)
+	s.BearerToken = Secret(getEnv("EXPORTER_BEARER_TOKEN", string(s.BearerToken)))
+	s.BearerTokenFile = getEnv("EXPORTER_BEARER_TOKEN_FILE", s.BearerTokenFile)
+	s.AllowedNetworks = getEnvList("EXPORTER_ALLOWED_NETWORKS", s.AllowedNetworks)
+
+	c := &cfg.Collectors
+	c.Orphans.Enabled = getEnvBool("PVE_COLLECTOR_ORPHANS", c.Orphans.Enabled)
+	c.Content.Enabled = getEnvBool("PVE_COLLECTOR_CONTENT", c.Content.Enabled)
+	c.Pending.Enabled = getEnvBool("PVE_COLLECTOR_PENDING", c.Pending.Enabled)
+	c.RRD.Enabled = getEnvBool("PVE_COLLECTOR_RRD", c.RRD.Enabled)
+	c.RRD.NetworkAllNodes = getEnvBool("PVE_RRD_NETWORK_ALL_NODES", c.RRD.NetworkAllNodes)
+	c.Rightsizing.Enabled = getEnvBool("PVE_COLLECTOR_RIGHTSIZING", c.Rightsizing.Enabled)
+	c.Rightsizing.Timeframe = getEnv("PVE_RIGHTSIZING_TIMEFRAME", c.Rightsizing.Timeframe)
+	c.Rightsizing.RefreshInterval = env.duration("PVE_RIGHTSIZING_REFRESH_INTERVAL", c.Rightsizing.RefreshInterval)
+	c.Rightsizing.Headroom = env.float("PVE_RIGHTSIZING_HEADROOM", c.Rightsizing.Headroom)
+	c.Rightsizing.IdleCPUThreshold = env.float("PVE_RIGHTSIZING_IDLE_CPU_THRESHOLD", c.Rightsizing.IdleCPUThreshold)
+
+	cost := &cfg.Cost
+	cost.Enabled = getEnvBool("PVE_COST_ENABLED", cost.Enabled)
+	cost.Currency = getEnv("PVE_COST_CURRENCY", cost.Currency)
+	cost.CPUHour = env.float("PVE_COST_CPU_HOUR", cost.CPUHour)
+	cost.MemoryGiBHour = env.float("PVE_COST_MEMORY_GIB_HOUR", cost.MemoryGiBHour)
+	cost.StorageGiBHour = envYAML(env, "PVE_COST_STORAGE_GIB_HOUR", cost.StorageGiBHour)
+	cost.LedgerFile = getEnv("PVE_COST_LEDGER_FILE", cost.LedgerFile)
+	cost.RetentionMonths = env.int("PVE_COST_RETENTION_MONTHS", cost.RetentionMonths)
+
+	cfg.Metrics.Naming = getEnv("PVE_METRICS_NAMING", cfg.Metrics.Naming)
+	cfg.Filters = envYAML(env, "PVE_FILTERS", cfg.Filters)
+	cfg.Labels = envYAML(env, "PVE_LABELS", cfg.Labels)
+	applyPushEnv(&cfg.Push, env)
+}
+
+// LoadFromFile loads configuration from file and environment variables.
+// Unknown keys in the file are rejected with their line number.
+func LoadFromFile(configFile string) (*Config, error) {
+	cfg := defaultConfig()
 
 	// Load from file if specified
 	if configFile != "" {
@@ -62,33 +402,190 @@ func LoadFromFile(configFile string) (*Config, error) {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
 
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && err != io.EOF {
 			return nil, fmt.Errorf("failed to parse config file: %w", err)
 		}
 	}
 
+	// Environment variables override the file
+	env := &envReader{}
+	applyEnv(cfg, env)
+	if env.err != nil {
+		return nil, env.err
+	}
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 
+	// Fail early on unreadable CA, certificate or secret files instead of on the first scrape
+	if _, err := cfg.Proxmox.TLSConfig(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Push.checkFiles(); err != nil {
+		return nil, err
+	}
+	secretFiles := []string{cfg.Proxmox.PasswordFile, cfg.Proxmox.TokenSecretFile, cfg.Server.BearerTokenFile,
+		cfg.Push.RemoteWrite.Auth.PasswordFile, cfg.Push.RemoteWrite.Auth.BearerTokenFile, cfg.Push.InfluxDB.TokenFile,
+		cfg.Push.MQTT.PasswordFile}
+	for _, path := range secretFiles {
+		if path == "" {
+			continue
+		}
+		if _, err := NewSecretFile(path).Get(); err != nil {
+			return nil, err
+		}
+	}
+
 	return cfg, nil
 }
 
-// Validate validates the configuration
-func (c *Config) Validate() error {
-	if c.Proxmox.Host == "" {
+// validate checks the API host, authentication and TLS settings
+func (p ProxmoxConfig) validate() error {
+	if p.Host == "" {
 		return fmt.Errorf("proxmox host is required")
 	}
 
 	// Check authentication method
-	hasPassword := c.Proxmox.Password != ""
-	hasToken := c.Proxmox.TokenID != "" && c.Proxmox.TokenSecret != ""
-
-	if !hasPassword && !hasToken {
+	if !p.hasPassword() && !p.UsesToken() {
 		return fmt.Errorf("either password or token authentication must be configured")
 	}
 
+	for _, host := range p.Hosts {
+		if strings.TrimSpace(host) == "" {
+			return fmt.Errorf("proxmox hosts must not contain empty entries")
+		}
+	}
+
+	if p.Fingerprint != "" {
+		if _, err := ParseFingerprint(p.Fingerprint); err != nil {
+			return err
+		}
+		// Every node has its own certificate, so a single pin would break failover
+		if len(p.Hosts) > 0 || p.DiscoverHosts {
+			return fmt.Errorf("proxmox fingerprint pins a single node certificate and can't be combined with hosts or discover_hosts; use ca_file with /etc/pve/pve-root-ca.pem instead")
+		}
+	}
+
+	if (p.CertFile == "") != (p.KeyFile == "") {
+		return fmt.Errorf("proxmox cert_file and key_file must be set together")
+	}
+	return p.validateRanges()
+}
+
+// validateRanges checks the API port and timeouts
+func (p ProxmoxConfig) validateRanges() error {
+	if p.Port < 1 || p.Port > 65535 {
+		return fmt.Errorf("proxmox port must be between 1 and 65535, got %d", p.Port)
+	}
+	if p.Timeout <= 0 {
+		return fmt.Errorf("proxmox timeout must be positive, got %s", p.Timeout)
+	}
+	if p.CredentialHelper.RefreshInterval < 0 || p.CredentialHelper.Timeout < 0 {
+		return fmt.Errorf("proxmox credential_helper refresh_interval and timeout must not be negative")
+	}
+	return nil
+}
+
+// reservedPaths are HTTP paths served by the exporter besides the metrics path
+var reservedPaths = map[string]bool{"/": true, "/health": true, "/chargeback": true, "/-/reload": true}
+
+// validate checks the listen address, metrics path and allowed networks
+func (s ServerConfig) validate() error {
+	if _, _, err := net.SplitHostPort(s.ListenAddress); err != nil {
+		return fmt.Errorf("invalid server listen_address %q: %w", s.ListenAddress, err)
+	}
+	if !strings.HasPrefix(s.MetricsPath, "/") {
+		return fmt.Errorf("server metrics_path must start with \"/\", got %q", s.MetricsPath)
+	}
+	if reservedPaths[s.MetricsPath] {
+		return fmt.Errorf("server metrics_path %q is already used by the exporter", s.MetricsPath)
+	}
+	if _, err := ParseNetworks(s.AllowedNetworks); err != nil {
+		return fmt.Errorf("invalid allowed_networks: %w", err)
+	}
+	return nil
+}
+
+// validate checks the right-sizing settings when the collector is enabled
+func (r RightsizingConfig) validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.Timeframe != "week" && r.Timeframe != "month" {
+		return fmt.Errorf("rightsizing timeframe must be \"week\" or \"month\", got %q", r.Timeframe)
+	}
+	if r.RefreshInterval <= 0 {
+		return fmt.Errorf("rightsizing refresh_interval must be positive, got %s", r.RefreshInterval)
+	}
+	if r.Headroom < 0 {
+		return fmt.Errorf("rightsizing headroom must not be negative, got %g", r.Headroom)
+	}
+	if r.IdleCPUThreshold < 0 || r.IdleCPUThreshold > 1 {
+		return fmt.Errorf("rightsizing idle_cpu_threshold must be between 0 and 1, got %g", r.IdleCPUThreshold)
+	}
+	return nil
+}
+
+// validate checks that no price or retention is negative
+func (c CostConfig) validate() error {
+	if c.CPUHour < 0 || c.MemoryGiBHour < 0 {
+		return fmt.Errorf("cost prices must not be negative")
+	}
+	if c.RetentionMonths < 0 {
+		return fmt.Errorf("cost retention_months must not be negative")
+	}
+	for storageType, price := range c.StorageGiBHour {
+		if price < 0 {
+			return fmt.Errorf("cost price for storage type %q must not be negative", storageType)
+		}
+	}
+	return nil
+}
+
+// Validate validates the configuration
+func (c *Config) Validate() error {
+	if err := c.Proxmox.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Server.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Collectors.Rightsizing.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Cost.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Push.validate(); err != nil {
+		return err
+	}
+
+	for _, rules := range [][]GuestFilterRule{c.Filters.Include, c.Filters.Exclude, c.Filters.Light} {
+		for _, rule := range rules {
+			if err := rule.validate(); err != nil {
+				return fmt.Errorf("invalid guest filter: %w", err)
+			}
+		}
+	}
+
+	if err := c.Labels.validate(); err != nil {
+		return fmt.Errorf("invalid extra labels: %w", err)
+	}
+
+	switch c.Metrics.Naming {
+	case "", NamingLegacy, NamingUnified, NamingBoth:
+	default:
+		return fmt.Errorf("metrics naming must be %q, %q or %q, got %q", NamingLegacy, NamingUnified, NamingBoth, c.Metrics.Naming)
+	}
+
 	return nil
 }
 
@@ -100,6 +597,92 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// envReader reads typed environment variables, remembering the first value that fails to parse
+type envReader struct {
+	err error
+}
+
+// fail records a parse error for key
+func (e *envReader) fail(key, value string, err error) {
+	if e.err == nil {
+		e.err = fmt.Errorf("invalid value %q for environment variable %s: %w", value, key, err)
+	}
+}
+
+// int gets an integer environment variable or returns a default value
+func (e *envReader) int(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.fail(key, value, err)
+		return defaultValue
+	}
+	return n
+}
+
+// float gets a float environment variable or returns a default value
+func (e *envReader) float(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.fail(key, value, err)
+		return defaultValue
+	}
+	return f
+}
+
+// duration gets a duration environment variable (e.g. "30s") or returns a default value
+func (e *envReader) duration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.fail(key, value, err)
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvList gets a comma-separated environment variable as a list or returns a default value
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// envYAML decodes a YAML environment variable (e.g. a flow mapping like "{zfspool: 0.0001}") for settings
+// that are not a single value, or returns a default value. Unknown keys are rejected as in the config file.
+func envYAML[T any](e *envReader, key string, defaultValue T) T {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var decoded T
+	dec := yaml.NewDecoder(strings.NewReader(value))
+	dec.KnownFields(true)
+	if err := dec.Decode(&decoded); err != nil {
+		e.fail(key, value, err)
+		return defaultValue
+	}
+	return decoded
+}
+
 // getEnvBool gets a boolean environment variable or returns a default value
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
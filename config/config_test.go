@@ -2,7 +2,11 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadFromFile(t *testing.T) {
@@ -75,6 +79,118 @@ func TestLoadFromEnv(t *testing.T) {
 	}
 }
 
+func TestEnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := []byte("proxmox:\n  host: file.proxmox.com\n  port: 8006\n  password: secret\ncost:\n  storage_gib_hour:\n    zfspool: 0.1\n")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PVE_HOST", "env.proxmox.com")
+	t.Setenv("PVE_FILTERS", `{exclude: [{name: "^ci-"}], light: [{tags: [ephemeral]}]}`)
+	t.Setenv("PVE_COST_STORAGE_GIB_HOUR", "{nfs: 0.05}")
+	t.Setenv("PVE_INFLUXDB_TAG_MAP", `{node: host, id: ""}`)
+	t.Setenv("PVE_MQTT_TLS_INSECURE_SKIP_VERIFY", "true")
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if cfg.Proxmox.Host != "env.proxmox.com" || cfg.Proxmox.Port != 8006 {
+		t.Errorf("expected env host and file port, got %s:%d", cfg.Proxmox.Host, cfg.Proxmox.Port)
+	}
+	if len(cfg.Filters.Exclude) != 1 || cfg.Filters.Exclude[0].Name != "^ci-" || cfg.Filters.Light[0].Tags[0] != "ephemeral" {
+		t.Errorf("unexpected filters from environment: %+v", cfg.Filters)
+	}
+	if !reflect.DeepEqual(cfg.Cost.StorageGiBHour, map[string]float64{"nfs": 0.05}) {
+		t.Errorf("expected environment prices to replace the file, got %v", cfg.Cost.StorageGiBHour)
+	}
+	if cfg.Push.InfluxDB.Naming.TagMap["node"] != "host" || !cfg.Push.MQTT.TLS.InsecureSkipVerify {
+		t.Errorf("push overrides not applied: %+v %+v", cfg.Push.InfluxDB.Naming, cfg.Push.MQTT.TLS)
+	}
+
+	t.Setenv("PVE_FILTERS", "{exclude: [{nam: x}]}")
+	if _, err := LoadFromFile(path); err == nil || !strings.Contains(err.Error(), "PVE_FILTERS") {
+		t.Errorf("expected error naming PVE_FILTERS, got %v", err)
+	}
+}
+
+// validServer is a server configuration that passes validation
+var validServer = ServerConfig{ListenAddress: ":9221", MetricsPath: "/metrics"}
+
+func TestLoadFromFileUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := []byte("proxmox:\n  host: pve1\n  password: secret\nserver:\n  metrics-path: /metrics\n")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFromFile(path)
+	if err == nil || !strings.Contains(err.Error(), "line 5: field metrics-path not found") {
+		t.Errorf("expected unknown field error with line number, got %v", err)
+	}
+}
+
+func TestLoadFromEnvTyped(t *testing.T) {
+	t.Setenv("PVE_PASSWORD", "envpass")
+	t.Setenv("PVE_PORT", "8443")
+	t.Setenv("PVE_TIMEOUT", "5s")
+	t.Setenv("PVE_RIGHTSIZING_HEADROOM", "0.5")
+
+	cfg, err := LoadFromFile("")
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if cfg.Proxmox.Port != 8443 || cfg.Proxmox.Timeout != 5*time.Second || cfg.Collectors.Rightsizing.Headroom != 0.5 {
+		t.Errorf("environment overrides not applied: port=%d timeout=%s headroom=%g",
+			cfg.Proxmox.Port, cfg.Proxmox.Timeout, cfg.Collectors.Rightsizing.Headroom)
+	}
+
+	t.Setenv("PVE_PORT", "eighty")
+	if _, err := LoadFromFile(""); err == nil || !strings.Contains(err.Error(), "PVE_PORT") {
+		t.Errorf("expected error naming PVE_PORT, got %v", err)
+	}
+}
+
+func TestValidateRanges(t *testing.T) {
+	valid := func() Config {
+		return Config{
+			Proxmox: ProxmoxConfig{Host: "localhost", Port: 8006, Timeout: 30 * time.Second, User: "root@pam", Password: "password"},
+			Server:  validServer,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+	}{
+		{"port zero", func(c *Config) { c.Proxmox.Port = 0 }},
+		{"port too large", func(c *Config) { c.Proxmox.Port = 70000 }},
+		{"zero timeout", func(c *Config) { c.Proxmox.Timeout = 0 }},
+		{"metrics path without slash", func(c *Config) { c.Server.MetricsPath = "metrics" }},
+		{"metrics path reserved", func(c *Config) { c.Server.MetricsPath = "/health" }},
+		{"listen address without port", func(c *Config) { c.Server.ListenAddress = "localhost" }},
+		{"negative cpu price", func(c *Config) { c.Cost.CPUHour = -1 }},
+		{"negative storage price", func(c *Config) { c.Cost.StorageGiBHour = map[string]float64{"nfs": -0.1} }},
+		{"idle threshold above one", func(c *Config) {
+			c.Collectors.Rightsizing = RightsizingConfig{Enabled: true, Timeframe: "week", RefreshInterval: time.Hour, IdleCPUThreshold: 2}
+		}},
+	}
+
+	base := valid()
+	if err := base.Validate(); err != nil {
+		t.Fatalf("base config should be valid: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg)
+			if err := cfg.Validate(); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
 func TestValidate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -84,8 +200,11 @@ func TestValidate(t *testing.T) {
 		{
 			name: "valid password auth",
 			cfg: Config{
+				Server: validServer,
 				Proxmox: ProxmoxConfig{
 					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
 					User:     "root@pam",
 					Password: "password",
 				},
@@ -95,19 +214,39 @@ func TestValidate(t *testing.T) {
 		{
 			name: "valid token auth",
 			cfg: Config{
+				Server: validServer,
 				Proxmox: ProxmoxConfig{
 					Host:        "localhost",
+					Port:        8006,
+					Timeout:     30 * time.Second,
 					TokenID:     "user@pam!token",
 					TokenSecret: "secret",
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid token secret file",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:            "localhost",
+					Port:            8006,
+					Timeout:         30 * time.Second,
+					TokenID:         "root@pam!test",
+					TokenSecretFile: "pve-token",
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "missing host",
 			cfg: Config{
+				Server: validServer,
 				Proxmox: ProxmoxConfig{
 					Host:     "",
+					Port:     8006,
+					Timeout:  30 * time.Second,
 					User:     "root@pam",
 					Password: "password",
 				},
@@ -117,12 +256,202 @@ func TestValidate(t *testing.T) {
 		{
 			name: "missing auth",
 			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:    "localhost",
+					Port:    8006,
+					Timeout: 30 * time.Second,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid rightsizing timeframe",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
+					User:     "root@pam",
+					Password: "password",
+				},
+				Collectors: CollectorsConfig{
+					Rightsizing: RightsizingConfig{Enabled: true, Timeframe: "day"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unified metric naming",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
+					User:     "root@pam",
+					Password: "password",
+				},
+				Metrics: MetricsConfig{Naming: NamingUnified},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid guest filter pattern",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
+					User:     "root@pam",
+					Password: "password",
+				},
+				Filters: FiltersConfig{
+					Exclude: []GuestFilterRule{{Name: "ci-(["}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty guest filter rule",
+			cfg: Config{
+				Server: validServer,
 				Proxmox: ProxmoxConfig{
-					Host: "localhost",
+					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
+					User:     "root@pam",
+					Password: "password",
+				},
+				Filters: FiltersConfig{
+					Exclude: []GuestFilterRule{{Name: "^ci-"}, {}},
 				},
 			},
 			wantErr: true,
 		},
+		{
+			name: "reserved extra label",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
+					User:     "root@pam",
+					Password: "password",
+				},
+				Labels: LabelsConfig{Tags: map[string]string{"vmid": "id-"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid fingerprint",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:        "localhost",
+					Port:        8006,
+					Timeout:     30 * time.Second,
+					User:        "root@pam",
+					Password:    "password",
+					Fingerprint: "AB:CD",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fingerprint with fallback hosts",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:        "localhost",
+					Port:        8006,
+					Timeout:     30 * time.Second,
+					User:        "root@pam",
+					Password:    "password",
+					Fingerprint: "AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB",
+					Hosts:       []string{"pve2"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fingerprint with discovered hosts",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:          "localhost",
+					Port:          8006,
+					Timeout:       30 * time.Second,
+					User:          "root@pam",
+					Password:      "password",
+					Fingerprint:   "AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB",
+					DiscoverHosts: true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fingerprint with a single host",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:        "localhost",
+					Port:        8006,
+					Timeout:     30 * time.Second,
+					User:        "root@pam",
+					Password:    "password",
+					Fingerprint: "AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "client certificate without key",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
+					User:     "root@pam",
+					Password: "password",
+					CertFile: "client.pem",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid allowed network",
+			cfg: Config{
+				Proxmox: ProxmoxConfig{
+					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
+					User:     "root@pam",
+					Password: "password",
+				},
+				Server: ServerConfig{ListenAddress: ":9221", MetricsPath: "/metrics", AllowedNetworks: []string{"10.0.0.0/33"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid metric naming",
+			cfg: Config{
+				Server: validServer,
+				Proxmox: ProxmoxConfig{
+					Host:     "localhost",
+					Port:     8006,
+					Timeout:  30 * time.Second,
+					User:     "root@pam",
+					Password: "password",
+				},
+				Metrics: MetricsConfig{Naming: "short"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -157,3 +486,39 @@ func TestGetEnvBool(t *testing.T) {
 		t.Error("expected default value true")
 	}
 }
+
+func TestParseVMIDRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		from, to int64
+		wantErr  bool
+	}{
+		{"100", 100, 100, false},
+		{"9000-9999", 9000, 9999, false},
+		{" 200 - 300 ", 200, 300, false},
+		{"300-200", 0, 0, true},
+		{"abc", 0, 0, true},
+		{"100-", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		from, to, err := ParseVMIDRange(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVMIDRange(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if from != tt.from || to != tt.to {
+			t.Errorf("ParseVMIDRange(%q) = %d-%d, want %d-%d", tt.in, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	p := ProxmoxConfig{Host: "pve1", Port: 8006, Hosts: []string{"pve2", "10.0.0.3:8443", "fd00::4", "[fd00::5]:8006"}}
+	want := []string{"pve1:8006", "pve2:8006", "10.0.0.3:8443", "[fd00::4]:8006", "[fd00::5]:8006"}
+
+	got := p.Endpoints()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Endpoints() = %v, want %v", got, want)
+	}
+}
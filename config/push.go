@@ -0,0 +1,475 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// PushConfig holds settings for pushing metrics to remote systems, alongside the /metrics endpoint
+type PushConfig struct {
+	// Interval is how often the collectors run and samples are pushed
+	Interval    time.Duration     `yaml:"interval"`
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+	OTLP        OTLPConfig        `yaml:"otlp"`
+	InfluxDB    InfluxDBConfig    `yaml:"influxdb"`
+	Graphite    GraphiteConfig    `yaml:"graphite"`
+	MQTT        MQTTConfig        `yaml:"mqtt"`
+}
+
+// Enabled reports whether any push target is configured
+func (p PushConfig) Enabled() bool {
+	return p.RemoteWrite.URL != "" || p.OTLP.Endpoint != "" || p.InfluxDB.URL != "" || p.Graphite.Address != "" || p.MQTT.Broker != ""
+}
+
+// RemoteWriteConfig holds settings for pushing samples with the Prometheus remote write protocol
+type RemoteWriteConfig struct {
+	// URL is the remote write endpoint, e.g. https://prometheus.example.com/api/v1/write (empty disables it)
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+	Auth    PushAuth      `yaml:"auth"`
+	TLS     PushTLSConfig `yaml:"tls"`
+	// MaxRetries is how often a failed request is retried before the batch is buffered
+	MaxRetries int           `yaml:"max_retries"`
+	MinBackoff time.Duration `yaml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// BufferDir stores batches that could not be sent, to be resent in order once the endpoint is back
+	BufferDir string `yaml:"buffer_dir"`
+	// BufferMaxBatches limits the buffer; the oldest batches are dropped first
+	BufferMaxBatches int `yaml:"buffer_max_batches"`
+}
+
+// PushAuth holds basic or bearer token authentication for a push target
+type PushAuth struct {
+	Username string `yaml:"username"`
+	Password Secret `yaml:"password"`
+	// PasswordFile is read instead of Password and re-read when it changes
+	PasswordFile string `yaml:"password_file"`
+	BearerToken  Secret `yaml:"bearer_token"`
+	// BearerTokenFile is read instead of BearerToken and re-read when it changes
+	BearerTokenFile string `yaml:"bearer_token_file"`
+}
+
+// validate checks that at most one authentication method is configured
+func (a PushAuth) validate() error {
+	basic := a.Username != "" || a.Password != "" || a.PasswordFile != ""
+	bearer := a.BearerToken != "" || a.BearerTokenFile != ""
+	if basic && bearer {
+		return fmt.Errorf("basic auth and bearer token are mutually exclusive")
+	}
+	return nil
+}
+
+// PushTLSConfig holds TLS settings for a push target
+type PushTLSConfig struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// TLSConfig builds the TLS client configuration for a push target
+func (t PushTLSConfig) TLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+	if err := loadCertificates(cfg, t.CAFile, t.CertFile, t.KeyFile); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate checks the remote write URL, retry and buffer settings when remote write is enabled
+func (r RemoteWriteConfig) validate() error {
+	if r.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("remote_write url must be an http or https URL, got %q", r.URL)
+	}
+	if r.Timeout <= 0 {
+		return fmt.Errorf("remote_write timeout must be positive, got %s", r.Timeout)
+	}
+	if r.MaxRetries < 0 {
+		return fmt.Errorf("remote_write max_retries must not be negative, got %d", r.MaxRetries)
+	}
+	if r.MinBackoff <= 0 || r.MaxBackoff < r.MinBackoff {
+		return fmt.Errorf("remote_write min_backoff must be positive and not above max_backoff")
+	}
+	if r.BufferDir != "" && r.BufferMaxBatches < 1 {
+		return fmt.Errorf("remote_write buffer_max_batches must be positive, got %d", r.BufferMaxBatches)
+	}
+	if (r.TLS.CertFile == "") != (r.TLS.KeyFile == "") {
+		return fmt.Errorf("remote_write tls cert_file and key_file must be set together")
+	}
+	if err := r.Auth.validate(); err != nil {
+		return fmt.Errorf("remote_write auth: %w", err)
+	}
+	return nil
+}
+
+// OTLP protocols
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+)
+
+// OTLPConfig holds settings for exporting metrics to an OpenTelemetry collector
+type OTLPConfig struct {
+	// Endpoint is the collector URL, e.g. http://otel-collector:4318 or, for gRPC, https://otel-collector:4317
+	// (empty disables it). With http/protobuf, /v1/metrics is appended if the URL has no path.
+	Endpoint string `yaml:"endpoint"`
+	Protocol string `yaml:"protocol"`
+	// Headers are sent with every request, e.g. an API key
+	Headers map[string]Secret `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+	TLS     PushTLSConfig     `yaml:"tls"`
+}
+
+// validate checks the OTLP endpoint, protocol and headers when OTLP export is enabled
+func (o OTLPConfig) validate() error {
+	if o.Endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(o.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("otlp endpoint must be an http or https URL, got %q", o.Endpoint)
+	}
+	if o.Protocol != OTLPProtocolHTTP && o.Protocol != OTLPProtocolGRPC {
+		return fmt.Errorf("otlp protocol must be %q or %q, got %q", OTLPProtocolHTTP, OTLPProtocolGRPC, o.Protocol)
+	}
+	if o.Timeout <= 0 {
+		return fmt.Errorf("otlp timeout must be positive, got %s", o.Timeout)
+	}
+	for name := range o.Headers {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("otlp header names must not be empty")
+		}
+	}
+	if (o.TLS.CertFile == "") != (o.TLS.KeyFile == "") {
+		return fmt.Errorf("otlp tls cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// parseHeaders parses "key=value" pairs separated by commas, as in OTEL_EXPORTER_OTLP_HEADERS
+func parseHeaders(s string) map[string]Secret {
+	var headers map[string]Secret
+	for _, pair := range strings.Split(s, ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			if headers == nil {
+				headers = make(map[string]Secret)
+			}
+			headers[strings.TrimSpace(key)] = Secret(strings.TrimSpace(value))
+		}
+	}
+	return headers
+}
+
+// Measurement naming schemes for InfluxDB and Graphite
+const (
+	// MeasurementMetric uses one measurement per metric with a "value" field, e.g. pve_guest_cpu_usage_ratio
+	MeasurementMetric = "metric"
+	// MeasurementSubsystem uses one measurement per subsystem with a field per metric, e.g. pve_guest with cpu_usage_ratio
+	MeasurementSubsystem = "subsystem"
+	// MeasurementSingle uses a single "pve" measurement with a field per metric
+	MeasurementSingle = "single"
+)
+
+// NamingConfig controls how metric names and labels map to measurements, fields and tags
+type NamingConfig struct {
+	Measurement string `yaml:"measurement"`
+	// TagMap renames labels to tags; mapping a label to "" drops it
+	TagMap map[string]string `yaml:"tag_map"`
+}
+
+// validate checks the measurement naming scheme
+func (n NamingConfig) validate(target string) error {
+	switch n.Measurement {
+	case MeasurementMetric, MeasurementSubsystem, MeasurementSingle:
+		return nil
+	}
+	return fmt.Errorf("%s measurement must be %q, %q or %q, got %q",
+		target, MeasurementMetric, MeasurementSubsystem, MeasurementSingle, n.Measurement)
+}
+
+// InfluxDBConfig holds settings for writing line protocol to the InfluxDB v2 HTTP API
+type InfluxDBConfig struct {
+	// URL is the InfluxDB base URL, e.g. http://influxdb:8086 (empty disables it)
+	URL    string `yaml:"url"`
+	Org    string `yaml:"org"`
+	Bucket string `yaml:"bucket"`
+	Token  Secret `yaml:"token"`
+	// TokenFile is read instead of Token and re-read when it changes
+	TokenFile string        `yaml:"token_file"`
+	Timeout   time.Duration `yaml:"timeout"`
+	TLS       PushTLSConfig `yaml:"tls"`
+	Naming    NamingConfig  `yaml:",inline"`
+}
+
+// validate checks the InfluxDB URL, org and bucket when InfluxDB output is enabled
+func (i InfluxDBConfig) validate() error {
+	if i.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(i.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("influxdb url must be an http or https URL, got %q", i.URL)
+	}
+	if i.Org == "" || i.Bucket == "" {
+		return fmt.Errorf("influxdb org and bucket are required")
+	}
+	if i.Timeout <= 0 {
+		return fmt.Errorf("influxdb timeout must be positive, got %s", i.Timeout)
+	}
+	if i.Token != "" && i.TokenFile != "" {
+		return fmt.Errorf("influxdb token and token_file are mutually exclusive")
+	}
+	if (i.TLS.CertFile == "") != (i.TLS.KeyFile == "") {
+		return fmt.Errorf("influxdb tls cert_file and key_file must be set together")
+	}
+	return i.Naming.validate("influxdb")
+}
+
+// GraphiteConfig holds settings for sending metrics with the Graphite plaintext protocol
+type GraphiteConfig struct {
+	// Address is the host:port of the Carbon plaintext listener, e.g. graphite:2003 (empty disables it)
+	Address string `yaml:"address"`
+	// Prefix is prepended to every metric path, e.g. "proxmox.cluster1"
+	Prefix string `yaml:"prefix"`
+	// Tagged sends labels as Graphite 1.1 tags instead of appending their values to the path
+	Tagged  bool          `yaml:"tagged"`
+	Timeout time.Duration `yaml:"timeout"`
+	Naming  NamingConfig  `yaml:",inline"`
+}
+
+// validate checks the Graphite address when Graphite output is enabled
+func (g GraphiteConfig) validate() error {
+	if g.Address == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(g.Address); err != nil {
+		return fmt.Errorf("graphite address must be host:port, got %q", g.Address)
+	}
+	if g.Timeout <= 0 {
+		return fmt.Errorf("graphite timeout must be positive, got %s", g.Timeout)
+	}
+	return g.Naming.validate("graphite")
+}
+
+// MQTTConfig holds settings for publishing node and guest state to an MQTT broker
+type MQTTConfig struct {
+	// Broker is the broker URL, e.g. tcp://mosquitto:1883 or ssl://mosquitto:8883 (empty disables it)
+	Broker string `yaml:"broker"`
+	// ClientID must be unique per broker; it also prefixes the Home Assistant unique IDs
+	ClientID string `yaml:"client_id"`
+	Username string `yaml:"username"`
+	Password Secret `yaml:"password"`
+	// PasswordFile is read instead of Password and re-read when it changes
+	PasswordFile string `yaml:"password_file"`
+	// TopicPrefix is the root of the state topics, e.g. pve/guest/100/state
+	TopicPrefix string `yaml:"topic_prefix"`
+	QoS         int    `yaml:"qos"`
+	// Retain publishes state messages retained, so subscribers get the last state right away
+	Retain bool `yaml:"retain"`
+	// Discovery publishes Home Assistant MQTT discovery configs under DiscoveryPrefix
+	Discovery       bool          `yaml:"discovery"`
+	DiscoveryPrefix string        `yaml:"discovery_prefix"`
+	Timeout         time.Duration `yaml:"timeout"`
+	TLS             PushTLSConfig `yaml:"tls"`
+}
+
+// validate checks the MQTT broker URL, topics and QoS when MQTT output is enabled
+func (m MQTTConfig) validate() error {
+	if m.Broker == "" {
+		return nil
+	}
+	u, err := url.Parse(m.Broker)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("mqtt broker must be a URL like tcp://host:1883, got %q", m.Broker)
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+	default:
+		return fmt.Errorf("mqtt broker scheme must be tcp, mqtt, ssl, tls, mqtts, ws or wss, got %q", u.Scheme)
+	}
+	if m.ClientID == "" {
+		return fmt.Errorf("mqtt client_id is required")
+	}
+	if m.QoS < 0 || m.QoS > 2 {
+		return fmt.Errorf("mqtt qos must be 0, 1 or 2, got %d", m.QoS)
+	}
+	if m.Timeout <= 0 {
+		return fmt.Errorf("mqtt timeout must be positive, got %s", m.Timeout)
+	}
+	if m.Password != "" && m.PasswordFile != "" {
+		return fmt.Errorf("mqtt password and password_file are mutually exclusive")
+	}
+	if (m.TLS.CertFile == "") != (m.TLS.KeyFile == "") {
+		return fmt.Errorf("mqtt tls cert_file and key_file must be set together")
+	}
+	return validateTopicPrefixes(m)
+}
+
+// validateTopicPrefixes checks that the topic prefixes are set and free of wildcards
+func validateTopicPrefixes(m MQTTConfig) error {
+	prefixes := map[string]string{"topic_prefix": m.TopicPrefix}
+	if m.Discovery {
+		prefixes["discovery_prefix"] = m.DiscoveryPrefix
+	}
+	for key, prefix := range prefixes {
+		if prefix == "" || strings.ContainsAny(prefix, "+#") || strings.HasSuffix(prefix, "/") {
+			return fmt.Errorf("mqtt %s must be a topic without wildcards or trailing slash, got %q", key, prefix)
+		}
+	}
+	return nil
+}
+
+// validate checks the push interval and every enabled target
+func (p PushConfig) validate() error {
+	if !p.Enabled() {
+		return nil
+	}
+	if p.Interval <= 0 {
+		return fmt.Errorf("push interval must be positive, got %s", p.Interval)
+	}
+	for _, validate := range []func() error{p.RemoteWrite.validate, p.OTLP.validate, p.InfluxDB.validate, p.Graphite.validate, p.MQTT.validate} {
+		if err := validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFiles loads the TLS files of every enabled target
+func (p PushConfig) checkFiles() error {
+	if p.RemoteWrite.URL != "" {
+		if _, err := p.RemoteWrite.TLS.TLSConfig(); err != nil {
+			return fmt.Errorf("remote_write tls: %w", err)
+		}
+	}
+	if p.OTLP.Endpoint != "" {
+		if _, err := p.OTLP.TLS.TLSConfig(); err != nil {
+			return fmt.Errorf("otlp tls: %w", err)
+		}
+	}
+	if p.InfluxDB.URL != "" {
+		if _, err := p.InfluxDB.TLS.TLSConfig(); err != nil {
+			return fmt.Errorf("influxdb tls: %w", err)
+		}
+	}
+	if p.MQTT.Broker != "" {
+		if _, err := p.MQTT.TLS.TLSConfig(); err != nil {
+			return fmt.Errorf("mqtt tls: %w", err)
+		}
+	}
+	return nil
+}
+
+// defaultPushConfig returns the built-in default push configuration
+func defaultPushConfig() PushConfig {
+	return PushConfig{
+		Interval: time.Minute,
+		RemoteWrite: RemoteWriteConfig{
+			Timeout:          30 * time.Second,
+			MaxRetries:       3,
+			MinBackoff:       time.Second,
+			MaxBackoff:       10 * time.Second,
+			BufferMaxBatches: 1440,
+		},
+		OTLP: OTLPConfig{
+			Protocol: OTLPProtocolHTTP,
+			Timeout:  10 * time.Second,
+		},
+		InfluxDB: InfluxDBConfig{
+			Timeout: 10 * time.Second,
+			Naming:  NamingConfig{Measurement: MeasurementMetric},
+		},
+		Graphite: GraphiteConfig{
+			Timeout: 10 * time.Second,
+			Naming:  NamingConfig{Measurement: MeasurementMetric},
+		},
+		MQTT: MQTTConfig{
+			ClientID:        "pve-exporter",
+			TopicPrefix:     "pve",
+			QoS:             1,
+			Retain:          true,
+			Discovery:       true,
+			DiscoveryPrefix: "homeassistant",
+			Timeout:         10 * time.Second,
+		},
+	}
+}
+
+// applyPushEnv overrides push settings with the environment variables that are set
+func applyPushEnv(p *PushConfig, env *envReader) {
+	p.Interval = env.duration("PVE_PUSH_INTERVAL", p.Interval)
+
+	rw := &p.RemoteWrite
+	rw.URL = getEnv("PVE_REMOTE_WRITE_URL", rw.URL)
+	rw.Timeout = env.duration("PVE_REMOTE_WRITE_TIMEOUT", rw.Timeout)
+	rw.Auth.Username = getEnv("PVE_REMOTE_WRITE_USERNAME", rw.Auth.Username)
+	rw.Auth.Password = Secret(getEnv("PVE_REMOTE_WRITE_PASSWORD", string(rw.Auth.Password)))
+	rw.Auth.PasswordFile = getEnv("PVE_REMOTE_WRITE_PASSWORD_FILE", rw.Auth.PasswordFile)
+	rw.Auth.BearerToken = Secret(getEnv("PVE_REMOTE_WRITE_BEARER_TOKEN", string(rw.Auth.BearerToken)))
+	rw.Auth.BearerTokenFile = getEnv("PVE_REMOTE_WRITE_BEARER_TOKEN_FILE", rw.Auth.BearerTokenFile)
+	applyTLSEnv(&rw.TLS, "PVE_REMOTE_WRITE_TLS")
+	rw.MaxRetries = env.int("PVE_REMOTE_WRITE_MAX_RETRIES", rw.MaxRetries)
+	rw.MinBackoff = env.duration("PVE_REMOTE_WRITE_MIN_BACKOFF", rw.MinBackoff)
+	rw.MaxBackoff = env.duration("PVE_REMOTE_WRITE_MAX_BACKOFF", rw.MaxBackoff)
+	rw.BufferDir = getEnv("PVE_REMOTE_WRITE_BUFFER_DIR", rw.BufferDir)
+	rw.BufferMaxBatches = env.int("PVE_REMOTE_WRITE_BUFFER_MAX_BATCHES", rw.BufferMaxBatches)
+
+	otlp := &p.OTLP
+	otlp.Endpoint = getEnv("PVE_OTLP_ENDPOINT", otlp.Endpoint)
+	otlp.Protocol = getEnv("PVE_OTLP_PROTOCOL", otlp.Protocol)
+	if headers := os.Getenv("PVE_OTLP_HEADERS"); headers != "" {
+		otlp.Headers = parseHeaders(headers)
+	}
+	otlp.Timeout = env.duration("PVE_OTLP_TIMEOUT", otlp.Timeout)
+	applyTLSEnv(&otlp.TLS, "PVE_OTLP_TLS")
+
+	influx := &p.InfluxDB
+	influx.URL = getEnv("PVE_INFLUXDB_URL", influx.URL)
+	influx.Org = getEnv("PVE_INFLUXDB_ORG", influx.Org)
+	influx.Bucket = getEnv("PVE_INFLUXDB_BUCKET", influx.Bucket)
+	influx.Token = Secret(getEnv("PVE_INFLUXDB_TOKEN", string(influx.Token)))
+	influx.TokenFile = getEnv("PVE_INFLUXDB_TOKEN_FILE", influx.TokenFile)
+	influx.Timeout = env.duration("PVE_INFLUXDB_TIMEOUT", influx.Timeout)
+	applyTLSEnv(&influx.TLS, "PVE_INFLUXDB_TLS")
+	influx.Naming.Measurement = getEnv("PVE_INFLUXDB_MEASUREMENT", influx.Naming.Measurement)
+	influx.Naming.TagMap = envYAML(env, "PVE_INFLUXDB_TAG_MAP", influx.Naming.TagMap)
+
+	graphite := &p.Graphite
+	graphite.Address = getEnv("PVE_GRAPHITE_ADDRESS", graphite.Address)
+	graphite.Prefix = getEnv("PVE_GRAPHITE_PREFIX", graphite.Prefix)
+	graphite.Tagged = getEnvBool("PVE_GRAPHITE_TAGGED", graphite.Tagged)
+	graphite.Timeout = env.duration("PVE_GRAPHITE_TIMEOUT", graphite.Timeout)
+	graphite.Naming.Measurement = getEnv("PVE_GRAPHITE_MEASUREMENT", graphite.Naming.Measurement)
+	graphite.Naming.TagMap = envYAML(env, "PVE_GRAPHITE_TAG_MAP", graphite.Naming.TagMap)
+
+	mqtt := &p.MQTT
+	mqtt.Broker = getEnv("PVE_MQTT_BROKER", mqtt.Broker)
+	mqtt.ClientID = getEnv("PVE_MQTT_CLIENT_ID", mqtt.ClientID)
+	mqtt.Username = getEnv("PVE_MQTT_USERNAME", mqtt.Username)
+	mqtt.Password = Secret(getEnv("PVE_MQTT_PASSWORD", string(mqtt.Password)))
+	mqtt.PasswordFile = getEnv("PVE_MQTT_PASSWORD_FILE", mqtt.PasswordFile)
+	mqtt.TopicPrefix = getEnv("PVE_MQTT_TOPIC_PREFIX", mqtt.TopicPrefix)
+	mqtt.QoS = env.int("PVE_MQTT_QOS", mqtt.QoS)
+	mqtt.Retain = getEnvBool("PVE_MQTT_RETAIN", mqtt.Retain)
+	mqtt.Discovery = getEnvBool("PVE_MQTT_DISCOVERY", mqtt.Discovery)
+	mqtt.DiscoveryPrefix = getEnv("PVE_MQTT_DISCOVERY_PREFIX", mqtt.DiscoveryPrefix)
+	mqtt.Timeout = env.duration("PVE_MQTT_TIMEOUT", mqtt.Timeout)
+	applyTLSEnv(&mqtt.TLS, "PVE_MQTT_TLS")
+}
+
+// applyTLSEnv overrides the TLS settings of a sink with <prefix>_CA_FILE, _CERT_FILE, _KEY_FILE and _INSECURE_SKIP_VERIFY
+func applyTLSEnv(t *PushTLSConfig, prefix string) {
+	t.CAFile = getEnv(prefix+"_CA_FILE", t.CAFile)
+	t.CertFile = getEnv(prefix+"_CERT_FILE", t.CertFile)
+	t.KeyFile = getEnv(prefix+"_KEY_FILE", t.KeyFile)
+	t.InsecureSkipVerify = getEnvBool(prefix+"_INSECURE_SKIP_VERIFY", t.InsecureSkipVerify)
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPushValidate(t *testing.T) {
+	valid := func() PushConfig {
+		return PushConfig{
+			Interval: time.Minute,
+			RemoteWrite: RemoteWriteConfig{
+				URL:              "https://prometheus.example.com/api/v1/write",
+				Timeout:          30 * time.Second,
+				MaxRetries:       3,
+				MinBackoff:       time.Second,
+				MaxBackoff:       10 * time.Second,
+				BufferDir:        "/var/lib/pve-exporter/remote-write",
+				BufferMaxBatches: 100,
+			},
+			OTLP: OTLPConfig{
+				Endpoint: "http://otel-collector:4318",
+				Protocol: OTLPProtocolHTTP,
+				Headers:  map[string]Secret{"X-Api-Key": "key"},
+				Timeout:  10 * time.Second,
+			},
+			InfluxDB: InfluxDBConfig{
+				URL:     "http://influxdb:8086",
+				Org:     "homelab",
+				Bucket:  "pve",
+				Timeout: 10 * time.Second,
+				Naming:  NamingConfig{Measurement: MeasurementMetric},
+			},
+			Graphite: GraphiteConfig{
+				Address: "graphite:2003",
+				Timeout: 10 * time.Second,
+				Naming:  NamingConfig{Measurement: MeasurementSubsystem},
+			},
+			MQTT: MQTTConfig{
+				Broker:          "tcp://mosquitto:1883",
+				ClientID:        "pve-exporter",
+				TopicPrefix:     "pve",
+				QoS:             1,
+				Discovery:       true,
+				DiscoveryPrefix: "homeassistant",
+				Timeout:         10 * time.Second,
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(p *PushConfig)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(p *PushConfig) {}},
+		{name: "disabled ignores other settings", modify: func(p *PushConfig) { *p = PushConfig{} }},
+		{name: "basic auth", modify: func(p *PushConfig) { p.RemoteWrite.Auth = PushAuth{Username: "pve", PasswordFile: "pw"} }},
+		{name: "zero interval", modify: func(p *PushConfig) { p.Interval = 0 }, wantErr: true},
+		{name: "invalid url", modify: func(p *PushConfig) { p.RemoteWrite.URL = "prometheus:9090/api/v1/write" }, wantErr: true},
+		{name: "zero timeout", modify: func(p *PushConfig) { p.RemoteWrite.Timeout = 0 }, wantErr: true},
+		{name: "negative retries", modify: func(p *PushConfig) { p.RemoteWrite.MaxRetries = -1 }, wantErr: true},
+		{name: "backoff range", modify: func(p *PushConfig) { p.RemoteWrite.MaxBackoff = time.Millisecond }, wantErr: true},
+		{name: "empty buffer", modify: func(p *PushConfig) { p.RemoteWrite.BufferMaxBatches = 0 }, wantErr: true},
+		{name: "cert without key", modify: func(p *PushConfig) { p.RemoteWrite.TLS.CertFile = "client.pem" }, wantErr: true},
+		{name: "basic and bearer auth", modify: func(p *PushConfig) {
+			p.RemoteWrite.Auth = PushAuth{Username: "pve", Password: "pw", BearerToken: "token"}
+		}, wantErr: true},
+		{name: "otlp only", modify: func(p *PushConfig) { p.RemoteWrite = RemoteWriteConfig{} }},
+		{name: "otlp grpc", modify: func(p *PushConfig) { p.OTLP.Protocol = OTLPProtocolGRPC }},
+		{name: "otlp invalid endpoint", modify: func(p *PushConfig) { p.OTLP.Endpoint = "otel-collector:4317" }, wantErr: true},
+		{name: "otlp unknown protocol", modify: func(p *PushConfig) { p.OTLP.Protocol = "http/json" }, wantErr: true},
+		{name: "otlp zero timeout", modify: func(p *PushConfig) { p.OTLP.Timeout = 0 }, wantErr: true},
+		{name: "influxdb only", modify: func(p *PushConfig) { *p = PushConfig{Interval: time.Minute, InfluxDB: p.InfluxDB} }},
+		{name: "influxdb invalid url", modify: func(p *PushConfig) { p.InfluxDB.URL = "influxdb:8086" }, wantErr: true},
+		{name: "influxdb missing bucket", modify: func(p *PushConfig) { p.InfluxDB.Bucket = "" }, wantErr: true},
+		{name: "influxdb token and token file", modify: func(p *PushConfig) { p.InfluxDB.Token, p.InfluxDB.TokenFile = "t", "token" }, wantErr: true},
+		{name: "influxdb unknown measurement", modify: func(p *PushConfig) { p.InfluxDB.Naming.Measurement = "family" }, wantErr: true},
+		{name: "graphite only", modify: func(p *PushConfig) { *p = PushConfig{Interval: time.Minute, Graphite: p.Graphite} }},
+		{name: "graphite missing port", modify: func(p *PushConfig) { p.Graphite.Address = "graphite" }, wantErr: true},
+		{name: "graphite zero timeout", modify: func(p *PushConfig) { p.Graphite.Timeout = 0 }, wantErr: true},
+		{name: "mqtt only", modify: func(p *PushConfig) { *p = PushConfig{Interval: time.Minute, MQTT: p.MQTT} }},
+		{name: "mqtt websocket", modify: func(p *PushConfig) { p.MQTT.Broker = "wss://broker.example.com/mqtt" }},
+		{name: "mqtt discovery disabled", modify: func(p *PushConfig) { p.MQTT.Discovery, p.MQTT.DiscoveryPrefix = false, "" }},
+		{name: "mqtt http broker", modify: func(p *PushConfig) { p.MQTT.Broker = "http://mosquitto:1883" }, wantErr: true},
+		{name: "mqtt missing client id", modify: func(p *PushConfig) { p.MQTT.ClientID = "" }, wantErr: true},
+		{name: "mqtt qos", modify: func(p *PushConfig) { p.MQTT.QoS = 3 }, wantErr: true},
+		{name: "mqtt wildcard topic prefix", modify: func(p *PushConfig) { p.MQTT.TopicPrefix = "pve/#" }, wantErr: true},
+		{name: "mqtt empty discovery prefix", modify: func(p *PushConfig) { p.MQTT.DiscoveryPrefix = "" }, wantErr: true},
+		{name: "mqtt password and password file", modify: func(p *PushConfig) { p.MQTT.Password, p.MQTT.PasswordFile = "pw", "pw.txt" }, wantErr: true},
+		{name: "otlp empty header name", modify: func(p *PushConfig) { p.OTLP.Headers = map[string]Secret{" ": "x"} }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid()
+			tt.modify(&p)
+			if err := p.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]Secret
+	}{
+		{in: "", want: nil},
+		{in: "api-key=secret", want: map[string]Secret{"api-key": "secret"}},
+		{in: "a=1, b = 2,invalid", want: map[string]Secret{"a": "1", "b": "2"}},
+		{in: "auth=Basic dXNlcjpwdz0=", want: map[string]Secret{"auth": "Basic dXNlcjpwdz0="}},
+	}
+
+	for _, tt := range tests {
+		if got := parseHeaders(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseHeaders(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
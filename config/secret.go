@@ -0,0 +1,144 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces secret values in any printed or marshaled configuration
+const redacted = "<secret>"
+
+// Secret is a string that is redacted when formatted, logged or marshaled.
+// Use string(s) to get the actual value.
+type Secret string
+
+// String returns a placeholder instead of the secret
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString redacts the secret for %#v
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+// MarshalYAML redacts the secret in YAML config dumps
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// MarshalJSON redacts the secret in JSON config dumps
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", s.String())), nil
+}
+
+// ResolveCredentialPath resolves relative secret file paths against the systemd credentials
+// directory ($CREDENTIALS_DIRECTORY, set by LoadCredential=) when it is available
+func ResolveCredentialPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+		return filepath.Join(dir, path)
+	}
+	return path
+}
+
+// SecretFile is a secret read from a file, re-read whenever the file's modification time or size changes
+type SecretFile struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	value   Secret
+}
+
+// NewSecretFile creates a secret file reader; relative paths are resolved with ResolveCredentialPath
+func NewSecretFile(path string) *SecretFile {
+	return &SecretFile{path: ResolveCredentialPath(path)}
+}
+
+// Get returns the file's contents without surrounding whitespace
+func (f *SecretFile) Get() (Secret, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.value != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.value, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	value := Secret(strings.TrimSpace(string(data)))
+	if value == "" {
+		return "", fmt.Errorf("secret file %s is empty", f.path)
+	}
+	f.value, f.modTime, f.size = value, info.ModTime(), info.Size()
+	return f.value, nil
+}
+
+// CredentialHelperConfig configures an external command that prints a secret on stdout
+type CredentialHelperConfig struct {
+	Command []string `yaml:"command"`
+	// RefreshInterval is how long the helper's output is cached before running it again
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	Timeout         time.Duration `yaml:"timeout"`
+}
+
+// CredentialHelper runs a credential helper command and caches its output
+type CredentialHelper struct {
+	cfg     CredentialHelperConfig
+	mu      sync.Mutex
+	fetched time.Time
+	value   Secret
+}
+
+// NewCredentialHelper creates a credential helper runner
+func NewCredentialHelper(cfg CredentialHelperConfig) *CredentialHelper {
+	return &CredentialHelper{cfg: cfg}
+}
+
+// Get returns the cached secret, running the helper if it has never run or the cache expired
+func (h *CredentialHelper) Get() (Secret, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.value != "" && time.Since(h.fetched) < h.cfg.RefreshInterval {
+		return h.value, nil
+	}
+
+	timeout := h.cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// The helper's output is never included in errors, it may contain the secret
+	out, err := exec.CommandContext(ctx, h.cfg.Command[0], h.cfg.Command[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("credential helper %s failed: %w", h.cfg.Command[0], err)
+	}
+	value := Secret(strings.TrimSpace(string(out)))
+	if value == "" {
+		return "", fmt.Errorf("credential helper %s returned an empty secret", h.cfg.Command[0])
+	}
+	h.value, h.fetched = value, time.Now()
+	return h.value, nil
+}
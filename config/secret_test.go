@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	cfg := ProxmoxConfig{Password: "hunter2", TokenID: "root@pam!exporter"}
+
+	outputs := map[string]string{
+		"%v":  fmt.Sprintf("%v", cfg),
+		"%+v": fmt.Sprintf("%+v", cfg),
+		"%#v": fmt.Sprintf("%#v", cfg),
+		"%s":  fmt.Sprintf("%s", cfg.Password),
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("yaml.Marshal failed: %v", err)
+	}
+	outputs["yaml"] = string(data)
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(cfg); err != nil {
+		t.Fatalf("json encoding failed: %v", err)
+	}
+	outputs["json"] = buf.String()
+
+	for format, out := range outputs {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("%s output leaks the secret: %s", format, out)
+		}
+		if !strings.Contains(out, redacted) {
+			t.Errorf("%s output has no redaction placeholder: %s", format, out)
+		}
+	}
+
+	if Secret("").String() != "" {
+		t.Error("expected empty secret to print as empty")
+	}
+}
+
+func TestSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewSecretFile(path)
+	if got, err := f.Get(); err != nil || got != "first" {
+		t.Fatalf("Get() = %q, %v; want first", string(got), err)
+	}
+
+	// Rotated secret with a new modification time is picked up
+	if err := os.WriteFile(path, []byte("second-value\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.Get(); err != nil || got != "second-value" {
+		t.Fatalf("Get() = %q, %v; want second-value", string(got), err)
+	}
+
+	if _, err := NewSecretFile(filepath.Join(t.TempDir(), "missing")).Get(); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestResolveCredentialPath(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", "/run/credentials/pve-exporter.service")
+
+	tests := map[string]string{
+		"":                  "",
+		"pve-token":         "/run/credentials/pve-exporter.service/pve-token",
+		"/etc/pve/password": "/etc/pve/password",
+	}
+	for path, want := range tests {
+		if got := ResolveCredentialPath(path); got != want {
+			t.Errorf("ResolveCredentialPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestCredentialHelper(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	h := NewCredentialHelper(CredentialHelperConfig{
+		Command:         []string{"sh", "-c", "echo run >> " + counter + "; echo s3cret"},
+		RefreshInterval: time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		if got, err := h.Get(); err != nil || got != "s3cret" {
+			t.Fatalf("Get() = %q, %v; want s3cret", string(got), err)
+		}
+	}
+	data, _ := os.ReadFile(counter)
+	if runs := strings.Count(string(data), "run"); runs != 1 {
+		t.Errorf("expected helper to run once within the refresh interval, ran %d times", runs)
+	}
+
+	failing := NewCredentialHelper(CredentialHelperConfig{Command: []string{"sh", "-c", "echo leaked; exit 1"}})
+	if _, err := failing.Get(); err == nil || strings.Contains(err.Error(), "leaked") {
+		t.Errorf("expected error without helper output, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFingerprint(t *testing.T) {
+	valid := strings.Repeat("AB:", 31) + "AB"
+	if _, err := ParseFingerprint(valid); err != nil {
+		t.Errorf("expected colon-separated fingerprint to parse: %v", err)
+	}
+	if _, err := ParseFingerprint(strings.ReplaceAll(valid, ":", "")); err != nil {
+		t.Errorf("expected plain hex fingerprint to parse: %v", err)
+	}
+	for _, invalid := range []string{"", "AB:CD", strings.Repeat("ZZ", 32)} {
+		if _, err := ParseFingerprint(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cert := server.Certificate()
+	sum := sha256.Sum256(cert.Raw)
+	caFile := filepath.Join(t.TempDir(), "pve-root-ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      ProxmoxConfig
+		insecure bool
+		wantErr  bool
+	}{
+		{"verification without trusted CA", ProxmoxConfig{}, false, true},
+		{"insecure skip verify", ProxmoxConfig{InsecureSkipVerify: true}, true, false},
+		{"ca file", ProxmoxConfig{CAFile: caFile, InsecureSkipVerify: true}, false, false},
+		{"pinned fingerprint", ProxmoxConfig{Fingerprint: fmt.Sprintf("%X", sum)}, false, false},
+		{"pinned fingerprint with ca file", ProxmoxConfig{Fingerprint: fmt.Sprintf("%x", sum), CAFile: caFile}, false, false},
+		{"wrong fingerprint", ProxmoxConfig{Fingerprint: strings.Repeat("00", 32), InsecureSkipVerify: true}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.TLSInsecure(); got != tt.insecure {
+				t.Errorf("TLSInsecure() = %v, want %v", got, tt.insecure)
+			}
+			tlsConfig, err := tt.cfg.TLSConfig()
+			if err != nil {
+				t.Fatalf("TLSConfig() failed: %v", err)
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("request error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSConfigInvalidCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (ProxmoxConfig{CAFile: caFile}).TLSConfig(); err == nil {
+		t.Error("expected error for CA file without certificates")
+	}
+}
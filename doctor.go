@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bigtcze/pve-exporter/collector"
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+// runDoctor implements the "doctor" subcommand, which checks that the configured user or token
+// has the privileges every enabled collector needs
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadFromFile(*configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	checks, err := collector.NewProxmoxCollector(cfg).CheckPermissions()
+	if err != nil {
+		return err
+	}
+	return writeDoctorReport(os.Stdout, cfg.Proxmox, checks)
+}
+
+// writeDoctorReport prints every check, then the degraded collectors and the privileges to grant.
+// It returns an error if any collector is degraded.
+func writeDoctorReport(w io.Writer, proxmox config.ProxmoxConfig, checks []collector.PermissionCheck) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "COLLECTOR\tENDPOINT\tPATH\tPRIVILEGE\tSTATUS")
+	degraded := make(map[string]bool)
+	for _, check := range checks {
+		status := "ok"
+		if !check.OK {
+			status = "MISSING"
+			degraded[check.Collector] = true
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", check.Collector, check.Endpoint, check.Path, check.Privilege, status)
+	}
+	_ = tw.Flush()
+
+	if len(degraded) == 0 {
+		_, _ = fmt.Fprintln(w, "\nAll enabled collectors have the privileges they need.")
+		return nil
+	}
+
+	names := make([]string, 0, len(degraded))
+	for name := range degraded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	_, _ = fmt.Fprintf(w, "\nDegraded collectors: %s\n", strings.Join(names, ", "))
+
+	principal := fmt.Sprintf("--users '%s'", proxmox.User)
+	if proxmox.UsesToken() {
+		principal = fmt.Sprintf("--tokens '%s'", proxmox.TokenID)
+	}
+	_, _ = fmt.Fprintf(w, "\nGrant the read-only PVEAuditor role, which includes all of them:\n  pveum acl modify / --roles PVEAuditor %s\n", principal)
+	_, _ = fmt.Fprintln(w, "\nOr grant the missing privileges individually:")
+	missing := collector.MissingPrivileges(checks)
+	paths := make([]string, 0, len(missing))
+	for path := range missing {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(w, "  %s: %s\n", path, strings.Join(missing[path], ", "))
+	}
+	if proxmox.UsesToken() {
+		_, _ = fmt.Fprintln(w, "\nTokens with privilege separation need the privileges on the token itself, not only on its user.")
+	}
+
+	return fmt.Errorf("%d collector(s) degraded by missing privileges", len(degraded))
+}
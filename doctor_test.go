@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/collector"
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+func TestWriteDoctorReport(t *testing.T) {
+	proxmox := config.ProxmoxConfig{TokenID: "monitoring@pve!exporter", TokenSecret: "secret"}
+	checks := []collector.PermissionCheck{
+		{Collector: "node", Endpoint: "/nodes/{node}/status", Path: "/nodes/pve1", Privilege: "Sys.Audit", OK: true},
+		{Collector: "disk", Endpoint: "/nodes/{node}/disks/smart", Path: "/nodes/pve2", Privilege: "Sys.Audit"},
+		{Collector: "storage", Endpoint: "/nodes/{node}/storage", Path: "/storage", Privilege: "Datastore.Audit"},
+	}
+
+	var out strings.Builder
+	if err := writeDoctorReport(&out, proxmox, checks); err == nil {
+		t.Error("expected error when collectors are degraded")
+	}
+	for _, want := range []string{
+		"Degraded collectors: disk, storage",
+		"pveum acl modify / --roles PVEAuditor --tokens 'monitoring@pve!exporter'",
+		"/nodes/pve2: Sys.Audit",
+		"/storage: Datastore.Audit",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected report to contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := writeDoctorReport(&out, proxmox, checks[:1]); err != nil {
+		t.Errorf("expected no error when all privileges are granted, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bigtcze/pve-exporter/collector"
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// dumpFormats are the output formats of the dump subcommand
+var dumpFormats = []string{"text", "openmetrics", "json", "table"}
+
+// runDump implements the "dump" subcommand, which runs a single collection and prints the results
+func runDump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	format := fs.String("format", "text", "Output format: "+strings.Join(dumpFormats, ", "))
+	collectors := fs.String("collector", "", "Comma-separated collectors to run (default all enabled): "+
+		strings.Join(collector.CollectorNames(), ", "))
+	outFile := fs.String("out", "-", "Output file (- for stdout), replaced atomically")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadFromFile(*configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	var names []string
+	for _, name := range strings.Split(*collectors, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	metrics, err := collector.NewProxmoxCollector(cfg).CollectByName(names)
+	if err != nil {
+		return err
+	}
+
+	if *outFile == "-" {
+		return writeDump(os.Stdout, *format, metrics)
+	}
+	return writeFileAtomic(*outFile, func(w io.Writer) error {
+		return writeDump(w, *format, metrics)
+	})
+}
+
+// writeDump writes the collected metrics in the given format
+func writeDump(w io.Writer, format string, metrics map[string][]prometheus.Metric) error {
+	switch format {
+	case "text":
+		return writeExposition(w, expfmt.NewFormat(expfmt.TypeTextPlain), metrics)
+	case "openmetrics":
+		return writeExposition(w, expfmt.NewFormat(expfmt.TypeOpenMetrics), metrics)
+	case "json":
+		return writeDumpJSON(w, metrics)
+	case "table":
+		return writeDumpTable(w, metrics)
+	default:
+		return fmt.Errorf("unknown format %q, expected one of: %s", format, strings.Join(dumpFormats, ", "))
+	}
+}
+
+// staticCollector replays already collected metrics
+type staticCollector []prometheus.Metric
+
+// Describe sends no descriptors, making this an unchecked collector
+func (s staticCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect sends the collected metrics
+func (s staticCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range s {
+		ch <- m
+	}
+}
+
+// gather converts metrics to sorted metric families
+func gather(metrics []prometheus.Metric) ([]*dto.MetricFamily, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(staticCollector(metrics)); err != nil {
+		return nil, err
+	}
+	return registry.Gather()
+}
+
+// writeExposition writes all metrics in a Prometheus exposition format
+func writeExposition(w io.Writer, format expfmt.Format, metrics map[string][]prometheus.Metric) error {
+	var all []prometheus.Metric
+	for _, m := range metrics {
+		all = append(all, m...)
+	}
+	families, err := gather(all)
+	if err != nil {
+		return err
+	}
+
+	enc := expfmt.NewEncoder(w, format)
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// dumpSample is a single metric value in the JSON and table output
+type dumpSample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// dumpSamples returns the samples of each collector, sorted by name and labels
+func dumpSamples(metrics map[string][]prometheus.Metric) (map[string][]dumpSample, error) {
+	samples := make(map[string][]dumpSample)
+	for name, m := range metrics {
+		families, err := gather(m)
+		if err != nil {
+			return nil, fmt.Errorf("collector %s: %w", name, err)
+		}
+		list := []dumpSample{}
+		for _, mf := range families {
+			for _, metric := range mf.GetMetric() {
+				sample := dumpSample{Name: mf.GetName(), Value: metricValue(metric)}
+				if len(metric.GetLabel()) > 0 {
+					sample.Labels = make(map[string]string)
+					for _, lp := range metric.GetLabel() {
+						sample.Labels[lp.GetName()] = lp.GetValue()
+					}
+				}
+				list = append(list, sample)
+			}
+		}
+		samples[name] = list
+	}
+	return samples, nil
+}
+
+// metricValue returns the value of a gauge, counter or untyped metric
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	default:
+		return m.GetUntyped().GetValue()
+	}
+}
+
+// writeDumpJSON writes the samples as a JSON object keyed by collector
+func writeDumpJSON(w io.Writer, metrics map[string][]prometheus.Metric) error {
+	samples, err := dumpSamples(metrics)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(samples)
+}
+
+// writeDumpTable writes the samples as a human-readable table, grouped by collector
+func writeDumpTable(w io.Writer, metrics map[string][]prometheus.Metric) error {
+	samples, err := dumpSamples(metrics)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, name := range names {
+		if i > 0 {
+			_, _ = fmt.Fprintln(tw)
+		}
+		_, _ = fmt.Fprintf(tw, "== %s (%d)\n", name, len(samples[name]))
+		for _, s := range samples[name] {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%g\n", s.Name, formatLabels(s.Labels), s.Value)
+		}
+	}
+	return tw.Flush()
+}
+
+// formatLabels formats labels as sorted key="value" pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// writeFileAtomic writes a file through a temporary file in the same directory, so readers
+// such as the node_exporter textfile collector never see a partial file
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testDumpMetrics returns metrics of two collectors
+func testDumpMetrics() map[string][]prometheus.Metric {
+	up := prometheus.NewDesc("pve_node_up", "Node status", []string{"node"}, nil)
+	insecure := prometheus.NewDesc("pve_exporter_tls_insecure", "TLS verification disabled", nil, nil)
+	return map[string][]prometheus.Metric{
+		"node": {
+			prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 1, "pve1"),
+			prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 0, "pve2"),
+		},
+		"exporter": {prometheus.MustNewConstMetric(insecure, prometheus.GaugeValue, 0)},
+	}
+}
+
+func TestWriteDump(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{format: "text", want: []string{"# TYPE pve_node_up gauge", `pve_node_up{node="pve1"} 1`, "pve_exporter_tls_insecure 0"}},
+		{format: "openmetrics", want: []string{`pve_node_up{node="pve2"} 0`, "# EOF"}},
+		{format: "table", want: []string{"== exporter (1)", "== node (2)", `node="pve1"`}},
+		{format: "json", want: []string{`"node": [`, `"name": "pve_node_up"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out strings.Builder
+			if err := writeDump(&out, tt.format, testDumpMetrics()); err != nil {
+				t.Fatalf("writeDump failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected output to contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+
+	if err := writeDump(&strings.Builder{}, "xml", testDumpMetrics()); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestWriteDumpJSON(t *testing.T) {
+	var out strings.Builder
+	if err := writeDumpJSON(&out, testDumpMetrics()); err != nil {
+		t.Fatalf("writeDumpJSON failed: %v", err)
+	}
+
+	var got map[string][]dumpSample
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got["node"]) != 2 || got["node"][0].Labels["node"] != "pve1" || got["node"][0].Value != 1 {
+		t.Errorf("unexpected node samples: %+v", got["node"])
+	}
+	if len(got["exporter"]) != 1 || got["exporter"][0].Labels != nil {
+		t.Errorf("unexpected exporter samples: %+v", got["exporter"])
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pve.prom")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		return writeDump(w, "text", testDumpMetrics())
+	})
+	if err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "pve_node_up") {
+		t.Errorf("unexpected file content: %s", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary file left behind: %v", entries)
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,6 +15,7 @@ import (
 	"github.com/bigtcze/pve-exporter/config"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 )
 
 var (
@@ -21,11 +24,31 @@ var (
 	date    = "unknown"
 )
 
+// subcommands maps subcommand names to their implementation
+var subcommands = map[string]func(args []string) error{
+	"backfill":     runBackfill,
+	"check":        runCheck,
+	"check-config": runCheckConfig,
+	"doctor":       runDoctor,
+	"dump":         runDump,
+}
+
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				log.Fatalf("%s failed: %v", os.Args[1], err)
+			}
+			os.Exit(0)
+		}
+	}
+
 	// CLI flags
 	showVersion := flag.Bool("version", false, "Print version and exit")
 	selfUpdate := flag.Bool("selfupdate", false, "Update to latest version and restart")
 	configFile := flag.String("config", "", "Path to configuration file")
+	webConfigFile := flag.String("web.config.file", "", "Path to exporter-toolkit web configuration (TLS, basic auth)")
 	flag.Parse()
 
 	// Handle --version
@@ -50,24 +73,56 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	if *webConfigFile != "" {
+		cfg.Server.WebConfigFile = *webConfigFile
+	}
+	if err := web.Validate(cfg.Server.WebConfigFile); err != nil {
+		log.Fatalf("Invalid web configuration: %v", err)
+	}
+
 	log.Printf("Connecting to Proxmox at %s:%d", cfg.Proxmox.Host, cfg.Proxmox.Port)
+	if cfg.Proxmox.TLSInsecure() {
+		log.Printf("WARNING: TLS certificate verification of the Proxmox API is disabled; set proxmox.ca_file or proxmox.fingerprint to enable it")
+	}
 
 	// Create Prometheus registry
 	registry := prometheus.NewRegistry()
 
-	// Register Proxmox collector
-	proxmoxCollector := collector.NewProxmoxCollector(&cfg.Proxmox)
+	// Register Proxmox collector; it is replaced on configuration reload
+	proxmoxCollector := collector.NewReloadable(collector.NewProxmoxCollector(cfg))
 	registry.MustRegister(proxmoxCollector)
 
+	// Push mode runs the collectors on an interval, alongside the metrics endpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := startPush(ctx, cfg.Push, registry); err != nil {
+		log.Fatalf("Invalid push configuration: %v", err)
+	}
+
 	// Setup HTTP server
 	mux := http.NewServeMux()
 
+	// Access control wraps every endpoint, and is also updated on reload
+	access, err := newAccessControl(mux, cfg.Server)
+	if err != nil {
+		log.Fatalf("Invalid access control configuration: %v", err)
+	}
+
+	// Configuration reload via SIGHUP and POST /-/reload
+	reload := newReloader(*configFile, cfg, proxmoxCollector, access)
+	registry.MustRegister(reload.success, reload.timestamp)
+	reload.watchSignals()
+	mux.HandleFunc("/-/reload", reload.handler())
+
 	// Metrics endpoint
 	mux.Handle(cfg.Server.MetricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{
 		ErrorLog:      log.New(os.Stderr, "", log.LstdFlags),
 		ErrorHandling: promhttp.ContinueOnError,
 	}))
 
+	// Chargeback report (404 while cost collection is disabled)
+	mux.HandleFunc("/chargeback", proxmoxCollector.ChargebackHandler())
+
 	// Health endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -92,8 +147,7 @@ func main() {
 
 	// Start HTTP server
 	server := &http.Server{
-		Addr:    cfg.Server.ListenAddress,
-		Handler: mux,
+		Handler: access,
 	}
 
 	// Handle graceful shutdown
@@ -102,15 +156,25 @@ func main() {
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
 		log.Println("Shutting down...")
+		cancel()
 		_ = server.Close()
 	}()
 
 	log.Printf("Starting HTTP server on %s", cfg.Server.ListenAddress)
 	log.Printf("Metrics available at %s", cfg.Server.MetricsPath)
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	listenAddresses := []string{cfg.Server.ListenAddress}
+	systemdSocket := false
+	flags := &web.FlagConfig{
+		WebListenAddresses: &listenAddresses,
+		WebSystemdSocket:   &systemdSocket,
+		WebConfigFile:      &cfg.Server.WebConfigFile,
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := web.ListenAndServe(server, flags, logger); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("HTTP server failed: %v", err)
 	}
+	proxmoxCollector.Close()
 
 	log.Println("Exporter stopped")
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/bigtcze/pve-exporter/push"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newPusher creates a pusher for every configured push target
+func newPusher(cfg config.PushConfig, gatherer prometheus.Gatherer) (*push.Pusher, error) {
+	userAgent := fmt.Sprintf("pve-exporter/%s", version)
+
+	var sinks []push.Sink
+	if cfg.RemoteWrite.URL != "" {
+		sink, err := push.NewRemoteWrite(cfg.RemoteWrite, userAgent)
+		if err != nil {
+			return nil, fmt.Errorf("remote_write: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.OTLP.Endpoint != "" {
+		sink, err := push.NewOTLP(cfg.OTLP, version)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.InfluxDB.URL != "" {
+		sink, err := push.NewInfluxDB(cfg.InfluxDB, userAgent)
+		if err != nil {
+			return nil, fmt.Errorf("influxdb: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.Graphite.Address != "" {
+		sinks = append(sinks, push.NewGraphite(cfg.Graphite))
+	}
+	if cfg.MQTT.Broker != "" {
+		sink, err := push.NewMQTT(cfg.MQTT, version)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return push.NewPusher(gatherer, cfg.Interval, sinks), nil
+}
+
+// startPush registers the pusher's metrics and starts pushing, if any push target is configured
+func startPush(ctx context.Context, cfg config.PushConfig, registry *prometheus.Registry) error {
+	if !cfg.Enabled() {
+		return nil
+	}
+	pusher, err := newPusher(cfg, registry)
+	if err != nil {
+		return err
+	}
+	registry.MustRegister(pusher)
+	log.Printf("Pushing metrics every %s", cfg.Interval)
+	go pusher.Run(ctx)
+	return nil
+}
@@ -0,0 +1,109 @@
+package push
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bufferSuffix marks complete batch files; partially written files have a different name
+const bufferSuffix = ".batch"
+
+// diskBuffer keeps encoded batches as files named by creation time, so they are resent in order
+type diskBuffer struct {
+	dir string
+	max int
+
+	mu sync.Mutex
+}
+
+// newDiskBuffer creates the buffer directory if needed
+func newDiskBuffer(dir string, max int) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create buffer directory: %w", err)
+	}
+	return &diskBuffer{dir: dir, max: max}, nil
+}
+
+// add stores a batch, dropping the oldest batches if the buffer is full
+func (b *diskBuffer) add(payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f, err := os.CreateTemp(b.dir, ".pending-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(payload); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%020d%s", time.Now().UnixNano(), bufferSuffix)
+	if err := os.Rename(f.Name(), filepath.Join(b.dir, name)); err != nil {
+		return err
+	}
+
+	names, err := b.names()
+	if err != nil {
+		return err
+	}
+	for len(names) > b.max {
+		if err := os.Remove(filepath.Join(b.dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// names returns the buffered batch files, oldest first
+func (b *diskBuffer) names() ([]string, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), bufferSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// oldest returns the oldest batch and its name, or an empty name if the buffer is empty
+func (b *diskBuffer) oldest() (string, []byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	names, err := b.names()
+	if err != nil || len(names) == 0 {
+		return "", nil, err
+	}
+	payload, err := os.ReadFile(filepath.Join(b.dir, names[0]))
+	return names[0], payload, err
+}
+
+// remove deletes a batch once it has been sent
+func (b *diskBuffer) remove(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return os.Remove(filepath.Join(b.dir, name))
+}
+
+// len returns the number of buffered batches
+func (b *diskBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names, _ := b.names()
+	return len(names)
+}
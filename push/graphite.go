@@ -0,0 +1,94 @@
+package push
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+var (
+	// pathEscaper replaces characters that are not allowed in a Graphite path node
+	pathEscaper = strings.NewReplacer(".", "_", " ", "_", "/", "_", ";", "_", "\n", "_")
+	// tagEscaper replaces characters that are not allowed in Graphite tag names and values
+	tagEscaper = strings.NewReplacer(";", "_", "~", "_", "=", "_", "!", "_", "^", "_", " ", "_", "\n", "_")
+)
+
+// Graphite sends samples with the Graphite plaintext protocol over TCP
+type Graphite struct {
+	cfg config.GraphiteConfig
+}
+
+// NewGraphite creates a Graphite sink
+func NewGraphite(cfg config.GraphiteConfig) *Graphite {
+	return &Graphite{cfg: cfg}
+}
+
+// Name implements Sink
+func (g *Graphite) Name() string {
+	return "graphite"
+}
+
+// Push implements Sink. A new connection is opened for every push, so Carbon restarts need no reconnect logic.
+func (g *Graphite) Push(ctx context.Context, samples []Sample, timestamp time.Time) error {
+	dialer := net.Dialer{Timeout: g.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", g.cfg.Address)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	if err := conn.SetDeadline(time.Now().Add(g.cfg.Timeout)); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(conn)
+	ts := timestamp.Unix()
+	for _, s := range samples {
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s %s %d\n", g.path(s), strconv.FormatFloat(s.Value, 'g', -1, 64), ts); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// path builds the metric path of a sample: prefix, measurement and field, followed by the tags, either
+// as Graphite 1.1 tags or as path nodes holding the tag values in tag name order. Path nodes keep a "_"
+// placeholder for empty values, so every tag stays at the same position.
+func (g *Graphite) path(s Sample) string {
+	measurement, field := measurementName(g.cfg.Naming.Measurement, s.Name)
+	nodes := make([]string, 0, 3+len(s.Labels))
+	if g.cfg.Prefix != "" {
+		nodes = append(nodes, g.cfg.Prefix)
+	}
+	nodes = append(nodes, pathEscaper.Replace(measurement))
+	if field != "" {
+		nodes = append(nodes, pathEscaper.Replace(field))
+	}
+
+	if !g.cfg.Tagged {
+		for _, tag := range mapTags(s.Labels, g.cfg.Naming.TagMap, true) {
+			value := tag.Value
+			if value == "" {
+				value = "_"
+			}
+			nodes = append(nodes, pathEscaper.Replace(value))
+		}
+		return strings.Join(nodes, ".")
+	}
+
+	var path strings.Builder
+	path.WriteString(strings.Join(nodes, "."))
+	for _, tag := range mapTags(s.Labels, g.cfg.Naming.TagMap, false) {
+		fmt.Fprintf(&path, ";%s=%s", tagEscaper.Replace(tag.Name), tagEscaper.Replace(tag.Value))
+	}
+	return path.String()
+}
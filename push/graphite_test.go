@@ -0,0 +1,116 @@
+package push
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+func TestGraphitePath(t *testing.T) {
+	sample := Sample{Name: "pve_guest_cpu_usage_ratio", Labels: []Label{{Name: "name", Value: "web.example.com"}, {Name: "node", Value: "pve1"}}}
+	// An empty pool must not shift the node into the pool position
+	noPool := Sample{Name: "pve_guest_cpu_usage_ratio", Labels: []Label{{Name: "name", Value: "web"}, {Name: "node", Value: "pve1"}, {Name: "pool", Value: ""}, {Name: "vmid", Value: "100"}}}
+
+	tests := []struct {
+		name   string
+		cfg    config.GraphiteConfig
+		sample Sample
+		want   string
+	}{
+		{
+			name:   "path",
+			cfg:    config.GraphiteConfig{Naming: config.NamingConfig{Measurement: config.MeasurementMetric}},
+			sample: sample,
+			want:   "pve_guest_cpu_usage_ratio.web_example_com.pve1",
+		},
+		{
+			name:   "prefix and subsystem",
+			cfg:    config.GraphiteConfig{Prefix: "proxmox", Naming: config.NamingConfig{Measurement: config.MeasurementSubsystem}},
+			sample: sample,
+			want:   "proxmox.pve_guest.cpu_usage_ratio.web_example_com.pve1",
+		},
+		{
+			name: "tagged with tag map",
+			cfg: config.GraphiteConfig{Tagged: true, Naming: config.NamingConfig{
+				Measurement: config.MeasurementMetric, TagMap: map[string]string{"node": "host"},
+			}},
+			sample: sample,
+			want:   "pve_guest_cpu_usage_ratio;host=pve1;name=web.example.com",
+		},
+		{
+			name:   "path with empty value",
+			cfg:    config.GraphiteConfig{Naming: config.NamingConfig{Measurement: config.MeasurementMetric}},
+			sample: noPool,
+			want:   "pve_guest_cpu_usage_ratio.web.pve1._.100",
+		},
+		{
+			name:   "tagged with empty value",
+			cfg:    config.GraphiteConfig{Tagged: true, Naming: config.NamingConfig{Measurement: config.MeasurementMetric}},
+			sample: noPool,
+			want:   "pve_guest_cpu_usage_ratio;name=web;node=pve1;vmid=100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewGraphite(tt.cfg).path(tt.sample); got != tt.want {
+				t.Errorf("path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphitePush(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		var received []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			received = append(received, scanner.Text())
+		}
+		lines <- received
+	}()
+
+	sink := NewGraphite(config.GraphiteConfig{
+		Address: listener.Addr().String(),
+		Prefix:  "pve",
+		Timeout: 5 * time.Second,
+		Naming:  config.NamingConfig{Measurement: config.MeasurementMetric},
+	})
+	samples := []Sample{
+		{Name: "pve_node_up", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 1},
+		{Name: "pve_node_memory_total_bytes", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 68719476736},
+	}
+	if err := sink.Push(context.Background(), samples, time.Unix(1700000000, 0)); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	want := []string{
+		"pve.pve_node_up.pve1 1 1700000000",
+		"pve.pve_node_memory_total_bytes.pve1 6.8719476736e+10 1700000000",
+	}
+	select {
+	case got := <-lines:
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the Graphite lines")
+	}
+}
@@ -0,0 +1,244 @@
+package push
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Entity kinds published to MQTT
+const (
+	entityNode  = "node"
+	entityGuest = "guest"
+)
+
+// nodeMetrics maps node metrics to entity values
+var nodeMetrics = map[string]string{
+	"pve_node_up":                 "up",
+	"pve_node_cpu_load":           "cpu",
+	"pve_node_memory_used_bytes":  "memory_used",
+	"pve_node_memory_total_bytes": "memory_total",
+	"pve_node_uptime_seconds":     "uptime",
+}
+
+// guestMetrics maps guest metric suffixes to entity values, for the legacy and unified naming schemes
+var guestMetrics = map[string]string{
+	"status":                "up",
+	"cpu_usage":             "cpu",
+	"memory_used_bytes":     "memory_used",
+	"memory_max_bytes":      "memory_total",
+	"uptime_seconds":        "uptime",
+	"last_backup_timestamp": "last_backup",
+}
+
+// guestPrefixes maps guest metric prefixes to guest types; unified metrics carry a type label instead
+var guestPrefixes = map[string]string{
+	"pve_vm_":    "qemu",
+	"pve_lxc_":   "lxc",
+	"pve_guest_": "",
+}
+
+// guestModels are the Home Assistant device models of guest types
+var guestModels = map[string]string{
+	"qemu": "QEMU virtual machine",
+	"lxc":  "LXC container",
+}
+
+// entity is a node or guest whose state is published to MQTT
+type entity struct {
+	kind      string
+	node      string
+	vmid      string // guests only
+	name      string // guests only
+	guestType string // guests only
+	values    map[string]float64
+}
+
+// id returns the identifier of the entity used in topics and unique IDs, e.g. node_pve1 or guest_100
+func (e *entity) id() string {
+	if e.kind == entityNode {
+		return entityNode + "_" + sanitizeID(e.node)
+	}
+	return entityGuest + "_" + sanitizeID(e.vmid)
+}
+
+// displayName returns the Home Assistant device name
+func (e *entity) displayName() string {
+	switch {
+	case e.kind == entityNode:
+		return e.node
+	case e.name != "":
+		return e.name
+	}
+	return "Guest " + e.vmid
+}
+
+// model returns the Home Assistant device model
+func (e *entity) model() string {
+	if e.kind == entityNode {
+		return "Proxmox VE node"
+	}
+	if model, ok := guestModels[e.guestType]; ok {
+		return model
+	}
+	return "Proxmox VE guest"
+}
+
+// state returns the JSON state of the entity. Every key of the entity kind is present; unknown values
+// are null, which Home Assistant shows as unknown.
+func (e *entity) state(now time.Time) map[string]any {
+	state := map[string]any{"cpu": nil, "memory": nil, "memory_used": nil, "uptime": nil}
+	upKey := "online"
+	if e.kind == entityGuest {
+		upKey = "running"
+		state["backup_age"] = nil
+	}
+	state[upKey] = nil
+
+	if up, ok := e.values["up"]; ok {
+		state[upKey] = up == 1
+	}
+	if cpu, ok := e.values["cpu"]; ok {
+		state["cpu"] = round(cpu*100, 1)
+	}
+	if used, ok := e.values["memory_used"]; ok {
+		state["memory_used"] = used
+		if total := e.values["memory_total"]; total > 0 {
+			state["memory"] = round(used/total*100, 1)
+		}
+	}
+	if uptime, ok := e.values["uptime"]; ok {
+		state["uptime"] = uptime
+	}
+	if last := e.values["last_backup"]; last > 0 && e.kind == entityGuest {
+		state["backup_age"] = round(now.Sub(time.Unix(int64(last), 0)).Hours(), 1)
+	}
+	return state
+}
+
+// collectEntities extracts node and guest state from samples, in order of first appearance
+func collectEntities(samples []Sample) []*entity {
+	var entities []*entity
+	index := make(map[string]*entity)
+	lookup := func(e *entity) *entity {
+		if existing, ok := index[e.id()]; ok {
+			return existing
+		}
+		e.values = make(map[string]float64)
+		index[e.id()] = e
+		entities = append(entities, e)
+		return e
+	}
+
+	for _, s := range samples {
+		if math.IsNaN(s.Value) {
+			continue
+		}
+		labels := make(map[string]string, len(s.Labels))
+		for _, l := range s.Labels {
+			labels[l.Name] = l.Value
+		}
+
+		if key, ok := nodeMetrics[s.Name]; ok && labels["node"] != "" {
+			lookup(&entity{kind: entityNode, node: labels["node"]}).values[key] = s.Value
+			continue
+		}
+		key, guestType, ok := guestMetric(s.Name, labels)
+		if !ok || labels["vmid"] == "" {
+			continue
+		}
+		e := lookup(&entity{kind: entityGuest, vmid: labels["vmid"]})
+		e.node, e.name, e.guestType = labels["node"], labels["name"], guestType
+		e.values[key] = s.Value
+	}
+	return entities
+}
+
+// guestMetric returns the entity value key and guest type of a guest metric
+func guestMetric(name string, labels map[string]string) (key, guestType string, ok bool) {
+	for prefix, prefixType := range guestPrefixes {
+		suffix, found := strings.CutPrefix(name, prefix)
+		if !found {
+			continue
+		}
+		if key, ok = guestMetrics[suffix]; !ok {
+			return "", "", false
+		}
+		if prefixType == "" {
+			prefixType = labels["type"]
+		}
+		return key, prefixType, true
+	}
+	return "", "", false
+}
+
+// haSensor describes a Home Assistant entity created through MQTT discovery
+type haSensor struct {
+	key         string
+	name        string
+	component   string
+	kind        string // empty for both nodes and guests
+	unit        string
+	deviceClass string
+	stateClass  string
+}
+
+// haSensors are the Home Assistant entities of every node and guest
+var haSensors = []haSensor{
+	{key: "online", name: "Online", component: "binary_sensor", kind: entityNode, deviceClass: "connectivity"},
+	{key: "running", name: "Running", component: "binary_sensor", kind: entityGuest, deviceClass: "running"},
+	{key: "cpu", name: "CPU", component: "sensor", unit: "%", stateClass: "measurement"},
+	{key: "memory", name: "Memory", component: "sensor", unit: "%", stateClass: "measurement"},
+	{key: "memory_used", name: "Memory used", component: "sensor", unit: "B", deviceClass: "data_size", stateClass: "measurement"},
+	{key: "uptime", name: "Uptime", component: "sensor", unit: "s", deviceClass: "duration"},
+	{key: "backup_age", name: "Backup age", component: "sensor", kind: entityGuest, unit: "h", deviceClass: "duration", stateClass: "measurement"},
+}
+
+// discoveryConfig builds the Home Assistant discovery payload of a sensor of an entity
+func discoveryConfig(sensor haSensor, e *entity, clientID, stateTopic, availabilityTopic, version string) map[string]any {
+	device := map[string]any{
+		"identifiers":  []string{sanitizeID(clientID) + "_" + e.id()},
+		"name":         e.displayName(),
+		"manufacturer": "Proxmox",
+		"model":        e.model(),
+	}
+	if e.kind == entityGuest && e.node != "" {
+		device["via_device"] = sanitizeID(clientID) + "_" + entityNode + "_" + sanitizeID(e.node)
+	}
+
+	template := "{{ value_json." + sensor.key + " }}"
+	if sensor.component == "binary_sensor" {
+		template = "{{ 'ON' if value_json." + sensor.key + " else 'OFF' }}"
+	}
+	config := map[string]any{
+		"name":               sensor.name,
+		"unique_id":          sanitizeID(clientID) + "_" + e.id() + "_" + sensor.key,
+		"state_topic":        stateTopic,
+		"value_template":     template,
+		"availability_topic": availabilityTopic,
+		"device":             device,
+		"origin":             map[string]any{"name": "pve-exporter", "sw_version": version},
+	}
+	for key, value := range map[string]string{"unit_of_measurement": sensor.unit, "device_class": sensor.deviceClass, "state_class": sensor.stateClass} {
+		if value != "" {
+			config[key] = value
+		}
+	}
+	return config
+}
+
+// sanitizeID replaces characters not allowed in Home Assistant discovery IDs and MQTT topic levels
+func sanitizeID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// round rounds to the given number of decimals
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
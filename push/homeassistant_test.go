@@ -0,0 +1,67 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollectEntities(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	guest := []Label{{Name: "name", Value: "db"}, {Name: "node", Value: "pve1"}, {Name: "type", Value: "lxc"}, {Name: "vmid", Value: "200"}}
+	samples := []Sample{
+		{Name: "pve_node_up", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 1},
+		{Name: "pve_node_memory_used_bytes", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 4},
+		{Name: "pve_node_memory_total_bytes", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 16},
+		{Name: "pve_node_cpu_mhz", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 3000},
+		{Name: "pve_guest_status", Labels: guest, Value: 0},
+		{Name: "pve_guest_uptime_seconds", Labels: guest, Value: 0},
+		{Name: "pve_guest_last_backup_timestamp", Labels: guest, Value: float64(now.Add(-36 * time.Hour).Unix())},
+		{Name: "pve_guest_disk_max_bytes", Labels: guest, Value: 1},
+	}
+
+	entities := collectEntities(samples)
+	if len(entities) != 2 {
+		t.Fatalf("got %d entities, want 2", len(entities))
+	}
+
+	node, lxc := entities[0], entities[1]
+	if node.id() != "node_pve1" || lxc.id() != "guest_200" || lxc.model() != "LXC container" || lxc.displayName() != "db" {
+		t.Errorf("unexpected entities %+v, %+v", node, lxc)
+	}
+
+	wantNode := map[string]any{"online": true, "cpu": nil, "memory": 25.0, "memory_used": 4.0, "uptime": nil}
+	if got := node.state(now); !reflect.DeepEqual(got, wantNode) {
+		t.Errorf("node state = %v, want %v", got, wantNode)
+	}
+	wantGuest := map[string]any{"running": false, "cpu": nil, "memory": nil, "memory_used": nil, "uptime": 0.0, "backup_age": 36.0}
+	if got := lxc.state(now); !reflect.DeepEqual(got, wantGuest) {
+		t.Errorf("guest state = %v, want %v", got, wantGuest)
+	}
+}
+
+func TestDiscoveryConfig(t *testing.T) {
+	e := &entity{kind: entityGuest, node: "pve1", vmid: "100", name: "web", guestType: "qemu"}
+	running := haSensors[1]
+
+	got := discoveryConfig(running, e, "pve-exporter", "pve/guest/100/state", "pve/status", "1.0.0")
+	want := map[string]any{
+		"name":               "Running",
+		"unique_id":          "pve-exporter_guest_100_running",
+		"state_topic":        "pve/guest/100/state",
+		"value_template":     "{{ 'ON' if value_json.running else 'OFF' }}",
+		"availability_topic": "pve/status",
+		"device_class":       "running",
+		"device": map[string]any{
+			"identifiers":  []string{"pve-exporter_guest_100"},
+			"name":         "web",
+			"manufacturer": "Proxmox",
+			"model":        "QEMU virtual machine",
+			"via_device":   "pve-exporter_node_pve1",
+		},
+		"origin": map[string]any{"name": "pve-exporter", "sw_version": "1.0.0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("discoveryConfig() =\n%v\nwant\n%v", got, want)
+	}
+}
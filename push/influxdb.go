@@ -0,0 +1,144 @@
+package push
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+var (
+	// measurementEscaper escapes measurement names in line protocol
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\ `)
+	// keyEscaper escapes tag keys, tag values and field keys in line protocol
+	keyEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `)
+)
+
+// InfluxDB writes samples as line protocol to the InfluxDB v2 HTTP API
+type InfluxDB struct {
+	cfg       config.InfluxDBConfig
+	client    *http.Client
+	writeURL  string
+	userAgent string
+	token     func() (config.Secret, error)
+}
+
+// NewInfluxDB creates an InfluxDB sink
+func NewInfluxDB(cfg config.InfluxDBConfig, userAgent string) (*InfluxDB, error) {
+	tlsConfig, err := cfg.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+	u.RawQuery = url.Values{"org": {cfg.Org}, "bucket": {cfg.Bucket}, "precision": {"ms"}}.Encode()
+
+	return &InfluxDB{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+		writeURL:  u.String(),
+		userAgent: userAgent,
+		token:     secretGetter(cfg.Token, cfg.TokenFile),
+	}, nil
+}
+
+// Name implements Sink
+func (i *InfluxDB) Name() string {
+	return "influxdb"
+}
+
+// Push implements Sink
+func (i *InfluxDB) Push(ctx context.Context, samples []Sample, timestamp time.Time) error {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if _, err := zw.Write(encodeLineProtocol(samples, i.cfg.Naming, timestamp.UnixMilli())); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.writeURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("User-Agent", i.userAgent)
+	token, err := i.token()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+string(token))
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influxdb write failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// encodeLineProtocol encodes samples as line protocol. Samples sharing measurement and tags are
+// written as one line with several fields. NaN and infinite values are skipped, InfluxDB rejects them.
+func encodeLineProtocol(samples []Sample, naming config.NamingConfig, tsMs int64) []byte {
+	type point struct {
+		series string
+		fields []string
+	}
+	var points []*point
+	index := make(map[string]*point)
+
+	for _, s := range samples {
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+		measurement, field := measurementName(naming.Measurement, s.Name)
+		if field == "" {
+			field = "value"
+		}
+
+		var series strings.Builder
+		series.WriteString(measurementEscaper.Replace(measurement))
+		for _, tag := range mapTags(s.Labels, naming.TagMap, false) {
+			fmt.Fprintf(&series, ",%s=%s", keyEscaper.Replace(tag.Name), keyEscaper.Replace(tag.Value))
+		}
+
+		p, ok := index[series.String()]
+		if !ok {
+			p = &point{series: series.String()}
+			index[p.series] = p
+			points = append(points, p)
+		}
+		p.fields = append(p.fields, keyEscaper.Replace(field)+"="+strconv.FormatFloat(s.Value, 'f', -1, 64))
+	}
+
+	var buf bytes.Buffer
+	for _, p := range points {
+		fmt.Fprintf(&buf, "%s %s %d\n", p.series, strings.Join(p.fields, ","), tsMs)
+	}
+	return buf.Bytes()
+}
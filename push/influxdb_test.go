@@ -0,0 +1,99 @@
+package push
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+func TestEncodeLineProtocol(t *testing.T) {
+	samples := []Sample{
+		{Name: "pve_guest_cpu_usage_ratio", Labels: []Label{{Name: "name", Value: "web server"}, {Name: "vmid", Value: "100"}}, Value: 0.25},
+		{Name: "pve_guest_memory_usage_bytes", Labels: []Label{{Name: "name", Value: "web server"}, {Name: "vmid", Value: "100"}}, Value: 1073741824},
+		{Name: "pve_storage_info", Labels: []Label{{Name: "content", Value: "images,rootdir"}, {Name: "storage", Value: "local"}}, Value: 1},
+		{Name: "pve_node_load1", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: math.NaN()},
+	}
+
+	tests := []struct {
+		name   string
+		naming config.NamingConfig
+		want   string
+	}{
+		{
+			name:   "metric",
+			naming: config.NamingConfig{Measurement: config.MeasurementMetric},
+			want: "pve_guest_cpu_usage_ratio,name=web\\ server,vmid=100 value=0.25 1700000000000\n" +
+				"pve_guest_memory_usage_bytes,name=web\\ server,vmid=100 value=1073741824 1700000000000\n" +
+				"pve_storage_info,content=images\\,rootdir,storage=local value=1 1700000000000\n",
+		},
+		{
+			name:   "subsystem with tag map",
+			naming: config.NamingConfig{Measurement: config.MeasurementSubsystem, TagMap: map[string]string{"name": "guest"}},
+			want: "pve_guest,guest=web\\ server,vmid=100 cpu_usage_ratio=0.25,memory_usage_bytes=1073741824 1700000000000\n" +
+				"pve_storage,content=images\\,rootdir,storage=local info=1 1700000000000\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(encodeLineProtocol(samples, tt.naming, 1700000000000)); got != tt.want {
+				t.Errorf("encodeLineProtocol() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfluxDBPush(t *testing.T) {
+	var query, auth, body string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, auth = r.URL.Path+"?"+r.URL.RawQuery, r.Header.Get("Authorization")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := io.ReadAll(zr)
+		body = string(b)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := NewInfluxDB(config.InfluxDBConfig{
+		URL:     server.URL + "/",
+		Org:     "homelab",
+		Bucket:  "pve",
+		Token:   "secret",
+		Timeout: 5 * time.Second,
+		Naming:  config.NamingConfig{Measurement: config.MeasurementMetric},
+	}, "pve-exporter/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := []Sample{{Name: "pve_node_up", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 1}}
+	if err := sink.Push(context.Background(), samples, time.UnixMilli(1700000000000)); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if query != "/api/v2/write?bucket=pve&org=homelab&precision=ms" {
+		t.Errorf("request = %q", query)
+	}
+	if auth != "Token secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if body != "pve_node_up,node=pve1 value=1 1700000000000\n" {
+		t.Errorf("body = %q", body)
+	}
+
+	status = http.StatusUnauthorized
+	if err := sink.Push(context.Background(), samples, time.Now()); err == nil {
+		t.Error("expected error for 401 response")
+	}
+}
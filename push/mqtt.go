@@ -0,0 +1,207 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTT publishes node and guest state as JSON to per-entity topics, with Home Assistant discovery
+type MQTT struct {
+	cfg     config.MQTTConfig
+	version string
+	client  mqtt.Client
+
+	mu sync.Mutex
+	// published holds the retained topics of every entity by ID; discovery configs are cleared on reconnect
+	published map[string]*publishedEntity
+}
+
+// publishedEntity holds the retained topics of an entity, to remove them when the entity disappears
+type publishedEntity struct {
+	stateTopic string
+	configs    map[string]string // discovery topic to payload
+}
+
+// NewMQTT creates an MQTT sink and starts connecting to the broker in the background
+func NewMQTT(cfg config.MQTTConfig, version string) (*MQTT, error) {
+	tlsConfig, err := cfg.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	m := &MQTT{cfg: cfg, version: version, published: make(map[string]*publishedEntity)}
+	password := secretGetter(cfg.Password, cfg.PasswordFile)
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetTLSConfig(tlsConfig).
+		SetConnectTimeout(cfg.Timeout).
+		SetWriteTimeout(cfg.Timeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(m.availabilityTopic(), "offline", byte(cfg.QoS), true).
+		SetOnConnectHandler(m.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("MQTT connection lost: %v", err)
+		})
+	if cfg.Username != "" {
+		opts.SetCredentialsProvider(func() (string, string) {
+			secret, err := password()
+			if err != nil {
+				log.Printf("Error reading MQTT password: %v", err)
+			}
+			return cfg.Username, string(secret)
+		})
+	}
+
+	m.client = mqtt.NewClient(opts)
+	m.client.Connect()
+	return m, nil
+}
+
+// onConnect marks the exporter available and republishes discovery configs, which the broker may have lost
+func (m *MQTT) onConnect(client mqtt.Client) {
+	log.Printf("Connected to MQTT broker %s", m.cfg.Broker)
+	m.mu.Lock()
+	for _, p := range m.published {
+		p.configs = nil
+	}
+	m.mu.Unlock()
+	client.Publish(m.availabilityTopic(), byte(m.cfg.QoS), true, "online")
+}
+
+// Name implements Sink
+func (m *MQTT) Name() string {
+	return "mqtt"
+}
+
+// Push implements Sink. Entities that disappeared since the last push (deleted, or excluded by the
+// guest filters) are removed from Home Assistant.
+func (m *MQTT) Push(ctx context.Context, samples []Sample, timestamp time.Time) error {
+	if !m.client.IsConnectionOpen() {
+		return fmt.Errorf("not connected to MQTT broker %s", m.cfg.Broker)
+	}
+	entities := collectEntities(samples)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[string]bool, len(entities))
+	for _, e := range entities {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		seen[e.id()] = true
+		p, ok := m.published[e.id()]
+		if !ok {
+			p = &publishedEntity{}
+			m.published[e.id()] = p
+		}
+		if err := m.announce(e, p); err != nil {
+			return err
+		}
+		state, err := json.Marshal(e.state(timestamp))
+		if err != nil {
+			return err
+		}
+		p.stateTopic = m.stateTopic(e)
+		if err := m.publish(p.stateTopic, m.cfg.Retain, state); err != nil {
+			return err
+		}
+	}
+
+	// Without any node, the collection failed; keep the entities rather than removing all of them
+	if !hasNode(entities) {
+		return nil
+	}
+	return m.removeVanished(seen)
+}
+
+// announce publishes the discovery configs of an entity unless they were already published unchanged
+func (m *MQTT) announce(e *entity, p *publishedEntity) error {
+	if !m.cfg.Discovery {
+		return nil
+	}
+	configs := make(map[string]string)
+	for _, sensor := range haSensors {
+		if sensor.kind != "" && sensor.kind != e.kind {
+			continue
+		}
+		topic := fmt.Sprintf("%s/%s/%s/%s_%s/config", m.cfg.DiscoveryPrefix, sensor.component, sanitizeID(m.cfg.ClientID), e.id(), sensor.key)
+		payload, err := json.Marshal(discoveryConfig(sensor, e, m.cfg.ClientID, m.stateTopic(e), m.availabilityTopic(), m.version))
+		if err != nil {
+			return err
+		}
+		configs[topic] = string(payload)
+	}
+
+	for topic, payload := range configs {
+		if p.configs[topic] == payload {
+			continue
+		}
+		if err := m.publish(topic, true, []byte(payload)); err != nil {
+			return err
+		}
+	}
+	p.configs = configs
+	return nil
+}
+
+// removeVanished clears the retained discovery configs and state of entities missing from the last push
+func (m *MQTT) removeVanished(seen map[string]bool) error {
+	for id, p := range m.published {
+		if seen[id] {
+			continue
+		}
+		for topic := range p.configs {
+			if err := m.publish(topic, true, nil); err != nil {
+				return err
+			}
+		}
+		if err := m.publish(p.stateTopic, true, nil); err != nil {
+			return err
+		}
+		delete(m.published, id)
+	}
+	return nil
+}
+
+// publish publishes a message and waits for it to be sent (QoS 0) or acknowledged
+func (m *MQTT) publish(topic string, retained bool, payload []byte) error {
+	token := m.client.Publish(topic, byte(m.cfg.QoS), retained, payload)
+	if !token.WaitTimeout(m.cfg.Timeout) {
+		return fmt.Errorf("timed out publishing to %s", topic)
+	}
+	return token.Error()
+}
+
+// stateTopic returns the state topic of an entity, e.g. pve/guest/100/state
+func (m *MQTT) stateTopic(e *entity) string {
+	name := e.vmid
+	if e.kind == entityNode {
+		name = e.node
+	}
+	return fmt.Sprintf("%s/%s/%s/state", m.cfg.TopicPrefix, e.kind, sanitizeID(name))
+}
+
+// availabilityTopic returns the topic holding "online" while the exporter is connected, "offline" otherwise
+func (m *MQTT) availabilityTopic() string {
+	return m.cfg.TopicPrefix + "/status"
+}
+
+// hasNode reports whether any of the entities is a node
+func hasNode(entities []*entity) bool {
+	for _, e := range entities {
+		if e.kind == entityNode {
+			return true
+		}
+	}
+	return false
+}
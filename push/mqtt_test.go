@@ -0,0 +1,214 @@
+package push
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+// mqttMessage is a PUBLISH received by testBroker
+type mqttMessage struct {
+	topic    string
+	payload  string
+	retained bool
+}
+
+// testBroker is a minimal MQTT 3.1.1 broker accepting connections and recording published messages
+type testBroker struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []mqttMessage
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+// serve answers CONNECT, PUBLISH (QoS 0 and 1) and PINGREQ packets until the connection closes
+func (b *testBroker) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		length, err := binary.ReadUvarint(r) // MQTT uses the same 7-bit variable length encoding
+		if err != nil {
+			return
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case 1: // CONNECT
+			_, _ = conn.Write([]byte{0x20, 2, 0, 0})
+		case 3: // PUBLISH
+			topicLen := int(binary.BigEndian.Uint16(body))
+			msg := mqttMessage{topic: string(body[2 : 2+topicLen]), retained: header&1 == 1}
+			rest := body[2+topicLen:]
+			if qos := (header >> 1) & 3; qos > 0 {
+				_, _ = conn.Write([]byte{0x40, 2, rest[0], rest[1]})
+				rest = rest[2:]
+			}
+			msg.payload = string(rest)
+			b.mu.Lock()
+			b.messages = append(b.messages, msg)
+			b.mu.Unlock()
+		case 12: // PINGREQ
+			_, _ = conn.Write([]byte{0xd0, 0})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+// take returns and clears the messages received so far
+func (b *testBroker) take() []mqttMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	messages := b.messages
+	b.messages = nil
+	return messages
+}
+
+// byTopic returns the last message per topic
+func byTopic(messages []mqttMessage) map[string]mqttMessage {
+	m := make(map[string]mqttMessage)
+	for _, msg := range messages {
+		m[msg.topic] = msg
+	}
+	return m
+}
+
+// newTestMQTT creates an MQTT sink connected to the broker
+func newTestMQTT(t *testing.T, broker string) *MQTT {
+	t.Helper()
+	sink, err := NewMQTT(config.MQTTConfig{
+		Broker:          broker,
+		ClientID:        "pve-test",
+		TopicPrefix:     "pve",
+		QoS:             1,
+		Retain:          true,
+		Discovery:       true,
+		DiscoveryPrefix: "homeassistant",
+		Timeout:         5 * time.Second,
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sink.client.Disconnect(0) })
+	return sink
+}
+
+var mqttSamples = []Sample{
+	{Name: "pve_node_up", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 1},
+	{Name: "pve_node_cpu_load", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 0.125},
+	{Name: "pve_vm_status", Labels: []Label{{Name: "name", Value: "web"}, {Name: "node", Value: "pve1"}, {Name: "vmid", Value: "100"}}, Value: 1},
+	{Name: "pve_vm_cpu_usage", Labels: []Label{{Name: "name", Value: "web"}, {Name: "node", Value: "pve1"}, {Name: "vmid", Value: "100"}}, Value: 0.5},
+}
+
+func TestMQTTPush(t *testing.T) {
+	broker := newTestBroker(t)
+	sink := newTestMQTT(t, "tcp://"+broker.listener.Addr().String())
+
+	if msg := waitForMessage(t, broker, "pve/status"); msg.payload != "online" || !msg.retained {
+		t.Errorf("availability = %+v", msg)
+	}
+
+	if err := sink.Push(context.Background(), mqttSamples, time.Now()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	checkGuestPublished(t, byTopic(broker.take()))
+
+	// Unchanged discovery configs are not republished; the vanished guest is removed
+	if err := sink.Push(context.Background(), mqttSamples[:2], time.Now()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	messages := byTopic(broker.take())
+	if _, ok := messages["homeassistant/binary_sensor/pve-test/node_pve1_online/config"]; ok {
+		t.Error("unchanged node discovery config republished")
+	}
+	for _, topic := range []string{"homeassistant/sensor/pve-test/guest_100_cpu/config", "pve/guest/100/state"} {
+		if msg, ok := messages[topic]; !ok || msg.payload != "" || !msg.retained {
+			t.Errorf("%s not cleared: %+v", topic, msg)
+		}
+	}
+}
+
+// waitForMessage waits until the broker received a message on the topic, discarding other messages
+func waitForMessage(t *testing.T, broker *testBroker, topic string) mqttMessage {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if msg, ok := byTopic(broker.take())[topic]; ok {
+			return msg
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for a message on %s", topic)
+	return mqttMessage{}
+}
+
+// checkGuestPublished verifies the state and discovery messages of the first push of mqttSamples
+func checkGuestPublished(t *testing.T, messages map[string]mqttMessage) {
+	t.Helper()
+	var state map[string]any
+	if err := json.Unmarshal([]byte(messages["pve/guest/100/state"].payload), &state); err != nil {
+		t.Fatalf("guest state: %v", err)
+	}
+	if state["running"] != true || state["cpu"] != 50.0 || state["backup_age"] != nil {
+		t.Errorf("guest state = %v", state)
+	}
+
+	var discovery map[string]any
+	if err := json.Unmarshal([]byte(messages["homeassistant/sensor/pve-test/guest_100_cpu/config"].payload), &discovery); err != nil {
+		t.Fatalf("guest CPU discovery: %v", err)
+	}
+	if discovery["state_topic"] != "pve/guest/100/state" || discovery["unique_id"] != "pve-test_guest_100_cpu" {
+		t.Errorf("guest CPU discovery = %v", discovery)
+	}
+	if _, ok := messages["homeassistant/binary_sensor/pve-test/node_pve1_online/config"]; !ok {
+		t.Error("missing node online discovery config")
+	}
+}
+
+func TestMQTTPushNotConnected(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	sink := newTestMQTT(t, "tcp://"+addr)
+	if err := sink.Push(context.Background(), mqttSamples, time.Now()); err == nil {
+		t.Error("expected error without broker connection")
+	}
+}
@@ -0,0 +1,47 @@
+package push
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+// singleMeasurement is the measurement name used by the single naming scheme
+const singleMeasurement = "pve"
+
+// measurementName splits a metric name into measurement and field according to the naming scheme.
+// The field is empty with the metric scheme, where each metric is its own measurement.
+func measurementName(scheme, name string) (measurement, field string) {
+	switch scheme {
+	case config.MeasurementSubsystem:
+		// pve_guest_cpu_usage_ratio -> pve_guest, cpu_usage_ratio
+		if i := strings.IndexByte(name, '_'); i >= 0 {
+			if j := strings.IndexByte(name[i+1:], '_'); j >= 0 {
+				return name[:i+1+j], name[i+2+j:]
+			}
+		}
+		return name, ""
+	case config.MeasurementSingle:
+		return singleMeasurement, name
+	}
+	return name, ""
+}
+
+// mapTags renames labels to tags with the tag map, dropping labels mapped to "" and, unless keepEmpty
+// is set, empty values. The result is sorted by tag name.
+func mapTags(labels []Label, tagMap map[string]string, keepEmpty bool) []Label {
+	tags := make([]Label, 0, len(labels))
+	for _, l := range labels {
+		name := l.Name
+		if mapped, ok := tagMap[name]; ok {
+			name = mapped
+		}
+		if name == "" || (l.Value == "" && !keepEmpty) {
+			continue
+		}
+		tags = append(tags, Label{Name: name, Value: l.Value})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags
+}
@@ -0,0 +1,40 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/config"
+)
+
+func TestMeasurementName(t *testing.T) {
+	tests := []struct {
+		scheme          string
+		name            string
+		wantMeasurement string
+		wantField       string
+	}{
+		{scheme: config.MeasurementMetric, name: "pve_guest_cpu_usage_ratio", wantMeasurement: "pve_guest_cpu_usage_ratio"},
+		{scheme: config.MeasurementSubsystem, name: "pve_guest_cpu_usage_ratio", wantMeasurement: "pve_guest", wantField: "cpu_usage_ratio"},
+		{scheme: config.MeasurementSubsystem, name: "pve_up", wantMeasurement: "pve_up"},
+		{scheme: config.MeasurementSingle, name: "pve_node_up", wantMeasurement: "pve", wantField: "pve_node_up"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme+"/"+tt.name, func(t *testing.T) {
+			measurement, field := measurementName(tt.scheme, tt.name)
+			if measurement != tt.wantMeasurement || field != tt.wantField {
+				t.Errorf("measurementName() = %q, %q, want %q, %q", measurement, field, tt.wantMeasurement, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestMapTags(t *testing.T) {
+	labels := []Label{{Name: "id", Value: "qemu/100"}, {Name: "node", Value: "pve1"}, {Name: "tags", Value: ""}, {Name: "vmid", Value: "100"}}
+	got := mapTags(labels, map[string]string{"node": "host", "id": ""}, false)
+	want := []Label{{Name: "host", Value: "pve1"}, {Name: "vmid", Value: "100"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mapTags() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,268 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// resourceLabels map the labels identifying a PVE node or guest to OpenTelemetry resource attributes.
+// name and type only identify the guest on guest metrics; on other metrics (e.g. storage) they stay attributes.
+var resourceLabels = []struct {
+	label     string
+	attribute string
+	guestOnly bool
+}{
+	{label: "node", attribute: "host.name"},
+	{label: "vmid", attribute: "proxmox.vmid", guestOnly: true},
+	{label: "name", attribute: "proxmox.guest.name", guestOnly: true},
+	{label: "type", attribute: "proxmox.guest.type", guestOnly: true},
+}
+
+// OTLP exports samples to an OpenTelemetry collector over OTLP/HTTP (protobuf) or OTLP/gRPC
+type OTLP struct {
+	cfg       config.OTLPConfig
+	version   string
+	startTime time.Time
+
+	// Exactly one of these is set, depending on the protocol
+	httpClient *http.Client
+	httpURL    string
+	grpcConn   *grpc.ClientConn
+}
+
+// NewOTLP creates an OTLP sink. The gRPC connection is established lazily.
+func NewOTLP(cfg config.OTLPConfig, version string) (*OTLP, error) {
+	tlsConfig, err := cfg.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OTLP{cfg: cfg, version: version, startTime: time.Now()}
+	if cfg.Protocol == config.OTLPProtocolGRPC {
+		creds := insecure.NewCredentials()
+		if u.Scheme == "https" {
+			creds = credentials.NewTLS(tlsConfig)
+		}
+		if o.grpcConn, err = grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds)); err != nil {
+			return nil, err
+		}
+		return o, nil
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/metrics"
+	}
+	o.httpURL = u.String()
+	o.httpClient = &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+	return o, nil
+}
+
+// Name implements Sink
+func (o *OTLP) Name() string {
+	return "otlp"
+}
+
+// Push implements Sink
+func (o *OTLP) Push(ctx context.Context, samples []Sample, timestamp time.Time) error {
+	req := o.buildRequest(samples, timestamp)
+	ctx, cancel := context.WithTimeout(ctx, o.cfg.Timeout)
+	defer cancel()
+
+	if o.grpcConn != nil {
+		return o.pushGRPC(ctx, req)
+	}
+	return o.pushHTTP(ctx, req)
+}
+
+// pushGRPC sends the request with the MetricsService Export RPC
+func (o *OTLP) pushGRPC(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) error {
+	md := metadata.New(nil)
+	for name, value := range o.cfg.Headers {
+		md.Set(name, string(value))
+	}
+	resp, err := collectorpb.NewMetricsServiceClient(o.grpcConn).Export(metadata.NewOutgoingContext(ctx, md), req)
+	if err != nil {
+		return fmt.Errorf("otlp export failed: %w", err)
+	}
+	return partialSuccessError(resp)
+}
+
+// pushHTTP posts the request as binary protobuf
+func (o *OTLP) pushHTTP(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.httpURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("User-Agent", "pve-exporter/"+o.version)
+	for name, value := range o.cfg.Headers {
+		httpReq.Header.Set(name, string(value))
+	}
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export failed with status %d", resp.StatusCode)
+	}
+	var result collectorpb.ExportMetricsServiceResponse
+	if err := proto.Unmarshal(data, &result); err != nil {
+		return nil // an empty or non-protobuf body still means success
+	}
+	return partialSuccessError(&result)
+}
+
+// partialSuccessError reports data points the collector rejected
+func partialSuccessError(resp *collectorpb.ExportMetricsServiceResponse) error {
+	if rejected := resp.GetPartialSuccess().GetRejectedDataPoints(); rejected > 0 {
+		return fmt.Errorf("otlp collector rejected %d data points: %s", rejected, resp.GetPartialSuccess().GetErrorMessage())
+	}
+	return nil
+}
+
+// buildRequest groups samples by the node or guest they describe, with one resource per group.
+// Counters become cumulative monotonic sums, everything else gauges.
+func (o *OTLP) buildRequest(samples []Sample, timestamp time.Time) *collectorpb.ExportMetricsServiceRequest {
+	now := uint64(timestamp.UnixNano())
+	start := uint64(o.startTime.UnixNano())
+	scope := &commonpb.InstrumentationScope{Name: "pve-exporter", Version: o.version}
+
+	type resourceGroup struct {
+		resource *metricspb.ResourceMetrics
+		metrics  map[string]*metricspb.Metric
+	}
+	groups := make(map[string]*resourceGroup)
+	var keys []string
+
+	for _, s := range samples {
+		resourceAttrs, attrs := splitAttributes(s.Labels)
+		key := attributesKey(resourceAttrs)
+		group, ok := groups[key]
+		if !ok {
+			resourceAttrs = append(resourceAttrs,
+				stringAttribute("service.name", "pve-exporter"),
+				stringAttribute("service.version", o.version))
+			group = &resourceGroup{
+				resource: &metricspb.ResourceMetrics{
+					Resource:     &resourcepb.Resource{Attributes: resourceAttrs},
+					ScopeMetrics: []*metricspb.ScopeMetrics{{Scope: scope}},
+				},
+				metrics: make(map[string]*metricspb.Metric),
+			}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+
+		point := &metricspb.NumberDataPoint{
+			Attributes:   attrs,
+			TimeUnixNano: now,
+			Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: s.Value},
+		}
+		metric, ok := group.metrics[s.Name]
+		if !ok {
+			metric = newMetric(s)
+			group.metrics[s.Name] = metric
+			scopeMetrics := group.resource.ScopeMetrics[0]
+			scopeMetrics.Metrics = append(scopeMetrics.Metrics, metric)
+		}
+		if sum := metric.GetSum(); sum != nil {
+			point.StartTimeUnixNano = start
+			sum.DataPoints = append(sum.DataPoints, point)
+		} else {
+			metric.GetGauge().DataPoints = append(metric.GetGauge().DataPoints, point)
+		}
+	}
+
+	req := &collectorpb.ExportMetricsServiceRequest{}
+	for _, key := range keys {
+		req.ResourceMetrics = append(req.ResourceMetrics, groups[key].resource)
+	}
+	return req
+}
+
+// newMetric creates an empty gauge or cumulative sum metric for a sample
+func newMetric(s Sample) *metricspb.Metric {
+	metric := &metricspb.Metric{Name: s.Name, Description: s.Help}
+	if s.Counter {
+		metric.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+	} else {
+		metric.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+	}
+	return metric
+}
+
+// splitAttributes separates the labels identifying a node or guest from the other labels
+func splitAttributes(labels []Label) (resource, attrs []*commonpb.KeyValue) {
+	values := make(map[string]string, len(labels))
+	for _, l := range labels {
+		values[l.Name] = l.Value
+	}
+	_, isGuest := values["vmid"]
+
+	lifted := make(map[string]bool)
+	for _, rl := range resourceLabels {
+		value, ok := values[rl.label]
+		if !ok || (rl.guestOnly && !isGuest) {
+			continue
+		}
+		resource = append(resource, stringAttribute(rl.attribute, value))
+		lifted[rl.label] = true
+	}
+	for _, l := range labels {
+		if !lifted[l.Name] {
+			attrs = append(attrs, stringAttribute(l.Name, l.Value))
+		}
+	}
+	return resource, attrs
+}
+
+// attributesKey returns a key identifying a set of attributes
+func attributesKey(attrs []*commonpb.KeyValue) string {
+	parts := make([]string, 0, len(attrs))
+	for _, kv := range attrs {
+		parts = append(parts, kv.GetKey()+"="+kv.GetValue().GetStringValue())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\x00")
+}
+
+// stringAttribute creates a string attribute
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
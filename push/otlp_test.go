@@ -0,0 +1,222 @@
+package push
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+var otlpSamples = []Sample{
+	{Name: "pve_node_up", Help: "Node status", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 1},
+	{Name: "pve_guest_cpu_usage_ratio", Help: "Guest CPU", Labels: []Label{
+		{Name: "name", Value: "web"}, {Name: "node", Value: "pve1"}, {Name: "type", Value: "qemu"}, {Name: "vmid", Value: "100"},
+	}, Value: 0.25},
+	{Name: "pve_guest_network_receive_bytes_total", Help: "Guest RX", Counter: true, Labels: []Label{
+		{Name: "device", Value: "net0"}, {Name: "name", Value: "web"}, {Name: "node", Value: "pve1"}, {Name: "type", Value: "qemu"}, {Name: "vmid", Value: "100"},
+	}, Value: 1024},
+	{Name: "pve_storage_size_bytes", Help: "Storage size", Labels: []Label{
+		{Name: "node", Value: "pve1"}, {Name: "storage", Value: "local"}, {Name: "type", Value: "dir"},
+	}, Value: 100},
+	{Name: "pve_cluster_quorate", Help: "Quorum", Labels: []Label{}, Value: 1},
+}
+
+// attributeMap converts attributes to a map for comparisons
+func attributeMap(attrs []*commonpb.KeyValue) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		m[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	return m
+}
+
+// checkOTLPRequest verifies the resource mapping of otlpSamples
+func checkOTLPRequest(t *testing.T, req *collectorpb.ExportMetricsServiceRequest) {
+	t.Helper()
+	resources := req.GetResourceMetrics()
+	if len(resources) != 3 {
+		t.Fatalf("got %d resources, want 3 (node, guest, cluster)", len(resources))
+	}
+
+	checkNodeResource(t, resources[0])
+	checkGuestResource(t, resources[1])
+	if cluster := attributeMap(resources[2].GetResource().GetAttributes()); cluster["host.name"] != "" {
+		t.Errorf("cluster resource = %v", cluster)
+	}
+}
+
+// checkNodeResource verifies the node resource: node labels are lifted, storage labels stay attributes
+func checkNodeResource(t *testing.T, resource *metricspb.ResourceMetrics) {
+	t.Helper()
+	node := attributeMap(resource.GetResource().GetAttributes())
+	if node["host.name"] != "pve1" || node["service.name"] != "pve-exporter" || node["proxmox.vmid"] != "" {
+		t.Errorf("node resource = %v", node)
+	}
+	nodeMetrics := resource.GetScopeMetrics()[0].GetMetrics()
+	if len(nodeMetrics) != 2 {
+		t.Fatalf("got %d node metrics, want 2", len(nodeMetrics))
+	}
+	storage := attributeMap(nodeMetrics[1].GetGauge().GetDataPoints()[0].GetAttributes())
+	if storage["storage"] != "local" || storage["type"] != "dir" || storage["node"] != "" {
+		t.Errorf("storage attributes = %v", storage)
+	}
+}
+
+// checkGuestResource verifies the guest resource and that counters become cumulative sums
+func checkGuestResource(t *testing.T, resource *metricspb.ResourceMetrics) {
+	t.Helper()
+	guest := attributeMap(resource.GetResource().GetAttributes())
+	want := map[string]string{"host.name": "pve1", "proxmox.vmid": "100", "proxmox.guest.name": "web", "proxmox.guest.type": "qemu"}
+	for k, v := range want {
+		if guest[k] != v {
+			t.Errorf("guest resource %s = %q, want %q", k, guest[k], v)
+		}
+	}
+	guestMetrics := resource.GetScopeMetrics()[0].GetMetrics()
+	if len(guestMetrics) != 2 || guestMetrics[0].GetGauge() == nil {
+		t.Fatalf("unexpected guest metrics %v", guestMetrics)
+	}
+	sum := guestMetrics[1].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() || sum.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Fatalf("counter not exported as cumulative monotonic sum: %v", guestMetrics[1])
+	}
+	point := sum.GetDataPoints()[0]
+	if point.GetAsDouble() != 1024 || point.GetStartTimeUnixNano() == 0 || attributeMap(point.GetAttributes())["device"] != "net0" {
+		t.Errorf("unexpected sum data point %v", point)
+	}
+	if guestMetrics[1].GetDescription() != "Guest RX" {
+		t.Errorf("description = %q", guestMetrics[1].GetDescription())
+	}
+}
+
+func TestOTLPHTTP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received *collectorpb.ExportMetricsServiceRequest
+		path     string
+		apiKey   string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := &collectorpb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received, path, apiKey = req, r.URL.Path, r.Header.Get("X-Api-Key")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	sink, err := NewOTLP(config.OTLPConfig{
+		Endpoint: server.URL,
+		Protocol: config.OTLPProtocolHTTP,
+		Headers:  map[string]config.Secret{"X-Api-Key": "key"},
+		Timeout:  5 * time.Second,
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Push(context.Background(), otlpSamples, time.Now()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/v1/metrics" || apiKey != "key" {
+		t.Errorf("path = %q, api key = %q", path, apiKey)
+	}
+	checkOTLPRequest(t, received)
+}
+
+func TestOTLPHTTPErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "server error", handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) }},
+		{name: "partial success", handler: func(w http.ResponseWriter, r *http.Request) {
+			body, _ := proto.Marshal(&collectorpb.ExportMetricsServiceResponse{
+				PartialSuccess: &collectorpb.ExportMetricsPartialSuccess{RejectedDataPoints: 2, ErrorMessage: "invalid"},
+			})
+			_, _ = w.Write(body)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			sink, err := NewOTLP(config.OTLPConfig{Endpoint: server.URL, Protocol: config.OTLPProtocolHTTP, Timeout: 5 * time.Second}, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Push(context.Background(), otlpSamples, time.Now()); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// metricsReceiver is an OTLP/gRPC metrics service recording the last request
+type metricsReceiver struct {
+	collectorpb.UnimplementedMetricsServiceServer
+	mu       sync.Mutex
+	received *collectorpb.ExportMetricsServiceRequest
+	apiKey   []string
+}
+
+func (m *metricsReceiver) Export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.received, m.apiKey = req, md.Get("x-api-key")
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestOTLPGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := &metricsReceiver{}
+	server := grpc.NewServer()
+	collectorpb.RegisterMetricsServiceServer(server, receiver)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	sink, err := NewOTLP(config.OTLPConfig{
+		Endpoint: "http://" + listener.Addr().String(),
+		Protocol: config.OTLPProtocolGRPC,
+		Headers:  map[string]config.Secret{"X-Api-Key": "key"},
+		Timeout:  5 * time.Second,
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Push(context.Background(), otlpSamples, time.Now()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.apiKey) != 1 || receiver.apiKey[0] != "key" {
+		t.Errorf("api key metadata = %v", receiver.apiKey)
+	}
+	checkOTLPRequest(t, receiver.received)
+}
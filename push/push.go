@@ -0,0 +1,121 @@
+package push
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sink sends samples to a remote system
+type Sink interface {
+	// Name identifies the sink in logs and metrics
+	Name() string
+	// Push sends samples taken at the given time
+	Push(ctx context.Context, samples []Sample, timestamp time.Time) error
+}
+
+// bufferedSink is a sink that keeps unsent samples for later
+type bufferedSink interface {
+	Sink
+	// Buffered returns the number of batches waiting to be sent
+	Buffered() int
+}
+
+// Pusher periodically gathers metrics and pushes them to every sink
+type Pusher struct {
+	gatherer prometheus.Gatherer
+	interval time.Duration
+	sinks    []Sink
+
+	lastSuccess *prometheus.GaugeVec
+	failures    *prometheus.CounterVec
+	buffered    *prometheus.GaugeVec
+}
+
+// NewPusher creates a pusher for the sinks. It is also a collector for its own metrics.
+func NewPusher(gatherer prometheus.Gatherer, interval time.Duration, sinks []Sink) *Pusher {
+	return &Pusher{
+		gatherer: gatherer,
+		interval: interval,
+		sinks:    sinks,
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pve_exporter_push_last_success_timestamp_seconds",
+			Help: "Timestamp of the last successful push",
+		}, []string{"sink"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pve_exporter_push_failures_total",
+			Help: "Number of failed pushes",
+		}, []string{"sink"}),
+		buffered: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pve_exporter_push_buffered_batches",
+			Help: "Number of batches buffered on disk until the push target is reachable",
+		}, []string{"sink"}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (p *Pusher) Describe(ch chan<- *prometheus.Desc) {
+	p.lastSuccess.Describe(ch)
+	p.failures.Describe(ch)
+	p.buffered.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (p *Pusher) Collect(ch chan<- prometheus.Metric) {
+	p.lastSuccess.Collect(ch)
+	p.failures.Collect(ch)
+	p.buffered.Collect(ch)
+}
+
+// Run pushes immediately and then on every interval until the context is cancelled
+func (p *Pusher) Run(ctx context.Context) {
+	for _, sink := range p.sinks {
+		p.failures.WithLabelValues(sink.Name())
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.push(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// push gathers once and sends the samples to all sinks in parallel, each bounded by the interval
+func (p *Pusher) push(ctx context.Context) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		// Gather returns whatever it could collect along with the error
+		log.Printf("Error gathering metrics for push: %v", err)
+	}
+	samples := Flatten(families)
+	now := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, p.interval)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, sink := range p.sinks {
+		wg.Add(1)
+		go func(sink Sink) {
+			defer wg.Done()
+			if err := sink.Push(ctx, samples, now); err != nil {
+				log.Printf("Error pushing to %s: %v", sink.Name(), err)
+				p.failures.WithLabelValues(sink.Name()).Inc()
+			} else {
+				p.lastSuccess.WithLabelValues(sink.Name()).SetToCurrentTime()
+			}
+			if b, ok := sink.(bufferedSink); ok {
+				p.buffered.WithLabelValues(sink.Name()).Set(float64(b.Buffered()))
+			}
+		}(sink)
+	}
+	wg.Wait()
+}
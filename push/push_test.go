@@ -0,0 +1,50 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeSink records pushed samples and fails if err is set
+type fakeSink struct {
+	name    string
+	err     error
+	samples []Sample
+}
+
+func (s *fakeSink) Name() string { return s.name }
+
+func (s *fakeSink) Push(_ context.Context, samples []Sample, _ time.Time) error {
+	s.samples = samples
+	return s.err
+}
+
+func TestPusherPush(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "pve_cluster_quorate", Help: "quorate"})
+	gauge.Set(1)
+	registry.MustRegister(gauge)
+
+	ok := &fakeSink{name: "ok"}
+	failing := &fakeSink{name: "failing", err: errors.New("unreachable")}
+	p := NewPusher(registry, time.Minute, []Sink{ok, failing})
+	p.push(context.Background())
+
+	if len(ok.samples) != 1 || ok.samples[0].Name != "pve_cluster_quorate" || ok.samples[0].Value != 1 {
+		t.Errorf("unexpected samples: %+v", ok.samples)
+	}
+	if got := testutil.ToFloat64(p.failures.WithLabelValues("failing")); got != 1 {
+		t.Errorf("failures{sink=failing} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(p.failures.WithLabelValues("ok")); got != 0 {
+		t.Errorf("failures{sink=ok} = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(p.lastSuccess.WithLabelValues("ok")); got == 0 {
+		t.Error("last success timestamp not set")
+	}
+}
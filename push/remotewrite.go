@@ -0,0 +1,250 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// recoverableError is a failure worth retrying: a network error, a 5xx or a 429 response
+type recoverableError struct {
+	err error
+}
+
+func (e *recoverableError) Error() string { return e.err.Error() }
+func (e *recoverableError) Unwrap() error { return e.err }
+
+// isRecoverable reports whether err may succeed when retried
+func isRecoverable(err error) bool {
+	var recoverable *recoverableError
+	return errors.As(err, &recoverable)
+}
+
+// RemoteWrite pushes samples with the Prometheus remote write 1.0 protocol (snappy-compressed protobuf)
+type RemoteWrite struct {
+	cfg       config.RemoteWriteConfig
+	client    *http.Client
+	userAgent string
+	password  func() (config.Secret, error)
+	token     func() (config.Secret, error)
+	buffer    *diskBuffer // nil if buffering is disabled
+}
+
+// NewRemoteWrite creates a remote write sink
+func NewRemoteWrite(cfg config.RemoteWriteConfig, userAgent string) (*RemoteWrite, error) {
+	tlsConfig, err := cfg.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	r := &RemoteWrite{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+		userAgent: userAgent,
+		password:  secretGetter(cfg.Auth.Password, cfg.Auth.PasswordFile),
+		token:     secretGetter(cfg.Auth.BearerToken, cfg.Auth.BearerTokenFile),
+	}
+	if cfg.BufferDir != "" {
+		if r.buffer, err = newDiskBuffer(cfg.BufferDir, cfg.BufferMaxBatches); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// secretGetter returns a function reading the secret from file if set, or the inline value
+func secretGetter(inline config.Secret, file string) func() (config.Secret, error) {
+	if file != "" {
+		return config.NewSecretFile(file).Get
+	}
+	return func() (config.Secret, error) { return inline, nil }
+}
+
+// Name implements Sink
+func (r *RemoteWrite) Name() string {
+	return "remote_write"
+}
+
+// Buffered returns the number of batches waiting on disk
+func (r *RemoteWrite) Buffered() int {
+	if r.buffer == nil {
+		return 0
+	}
+	return r.buffer.len()
+}
+
+// Push sends buffered batches oldest first, then the new samples. Receivers reject samples older than
+// what they already have for a series, so new samples are buffered as well while older ones are pending.
+func (r *RemoteWrite) Push(ctx context.Context, samples []Sample, timestamp time.Time) error {
+	payload := snappy.Encode(nil, encodeWriteRequest(samples, timestamp.UnixMilli()))
+
+	err := r.flush(ctx)
+	if err == nil {
+		err = r.sendWithRetry(ctx, payload)
+	}
+	if err == nil {
+		return nil
+	}
+	if r.buffer == nil || !isRecoverable(err) {
+		return err
+	}
+	if berr := r.buffer.add(payload); berr != nil {
+		return fmt.Errorf("%w (buffering failed: %v)", err, berr)
+	}
+	return fmt.Errorf("%w (batch buffered)", err)
+}
+
+// flush sends buffered batches until the buffer is empty or a recoverable error occurs.
+// Batches rejected by the receiver are dropped, since resending them would never succeed.
+func (r *RemoteWrite) flush(ctx context.Context) error {
+	if r.buffer == nil {
+		return nil
+	}
+	for {
+		name, payload, err := r.buffer.oldest()
+		if err != nil || name == "" {
+			return err
+		}
+		if err := r.sendWithRetry(ctx, payload); err != nil {
+			if isRecoverable(err) {
+				return err
+			}
+			log.Printf("Dropping buffered remote write batch %s: %v", name, err)
+		}
+		if err := r.buffer.remove(name); err != nil {
+			return err
+		}
+	}
+}
+
+// sendWithRetry sends a payload, retrying recoverable errors with exponential backoff
+func (r *RemoteWrite) sendWithRetry(ctx context.Context, payload []byte) error {
+	backoff := r.cfg.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := r.send(ctx, payload)
+		if err == nil || !isRecoverable(err) || attempt >= r.cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, r.cfg.MaxBackoff)
+	}
+}
+
+// send makes a single remote write request
+func (r *RemoteWrite) send(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", r.userAgent)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if err := r.authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return &recoverableError{err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("remote write failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return &recoverableError{err: err}
+	}
+	return err
+}
+
+// authorize adds basic or bearer authentication to a request
+func (r *RemoteWrite) authorize(req *http.Request) error {
+	if r.cfg.Auth.Username != "" {
+		password, err := r.password()
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(r.cfg.Auth.Username, string(password))
+		return nil
+	}
+	token, err := r.token()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	}
+	return nil
+}
+
+// encodeWriteRequest encodes samples as a remote write 1.0 WriteRequest protobuf message:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(samples []Sample, timestampMs int64) []byte {
+	var buf, series, sample []byte
+	for _, s := range samples {
+		// Labels must be sorted by name, including __name__. Empty values mean an absent label in
+		// Prometheus, and some receivers reject them.
+		labels := []Label{{Name: "__name__", Value: s.Name}}
+		for _, l := range s.Labels {
+			if l.Value != "" {
+				labels = append(labels, l)
+			}
+		}
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+		series = series[:0]
+		for _, l := range labels {
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, encodeLabel(l))
+		}
+
+		sample = sample[:0]
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(timestampMs))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, series)
+	}
+	return buf
+}
+
+// encodeLabel encodes a Label message
+func encodeLabel(l Label) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, l.Name)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, l.Value)
+	return b
+}
@@ -0,0 +1,215 @@
+package push
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodedSeries is a time series decoded from a WriteRequest
+type decodedSeries struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeWriteRequest decodes the fields of a WriteRequest that encodeWriteRequest writes
+func decodeWriteRequest(t *testing.T, b []byte) []decodedSeries {
+	t.Helper()
+	var result []decodedSeries
+	forEachField(t, b, func(_ protowire.Number, series []byte) {
+		s := decodedSeries{labels: make(map[string]string)}
+		var names []string
+		forEachField(t, series, func(num protowire.Number, v []byte) {
+			if num == 1 {
+				var name, value string
+				forEachField(t, v, func(num protowire.Number, v []byte) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				names = append(names, name)
+				s.labels[name] = value
+				return
+			}
+			bits, n := protowire.ConsumeFixed64(v[1:])
+			ts, _ := protowire.ConsumeVarint(v[1+n+1:])
+			s.value, s.timestamp = math.Float64frombits(bits), int64(ts)
+		})
+		for i := 1; i < len(names); i++ {
+			if names[i-1] >= names[i] {
+				t.Errorf("labels not sorted: %v", names)
+			}
+		}
+		result = append(result, s)
+	})
+	return result
+}
+
+// forEachField calls fn with every length-delimited field of a message
+func forEachField(t *testing.T, b []byte, fn func(protowire.Number, []byte)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ != protowire.BytesType {
+			t.Fatalf("unexpected field type %v", typ)
+		}
+		v, m := protowire.ConsumeBytes(b[n:])
+		if m < 0 {
+			t.Fatal("invalid length-delimited field")
+		}
+		fn(num, v)
+		b = b[n+m:]
+	}
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	samples := []Sample{
+		{Name: "pve_node_up", Labels: []Label{{Name: "Zone", Value: "a"}, {Name: "node", Value: "pve1"}}, Value: 1},
+		{Name: "pve_cluster_quorate", Value: 0.5},
+		{Name: "pve_vm_status", Labels: []Label{{Name: "pool", Value: ""}, {Name: "vmid", Value: "100"}}, Value: 1},
+	}
+	series := decodeWriteRequest(t, encodeWriteRequest(samples, 1700000000123))
+	if len(series) != 3 {
+		t.Fatalf("got %d series, want 3", len(series))
+	}
+	if series[0].labels["__name__"] != "pve_node_up" || series[0].labels["node"] != "pve1" || series[0].value != 1 {
+		t.Errorf("unexpected first series: %+v", series[0])
+	}
+	if series[1].labels["__name__"] != "pve_cluster_quorate" || series[1].value != 0.5 || series[1].timestamp != 1700000000123 {
+		t.Errorf("unexpected second series: %+v", series[1])
+	}
+	if _, ok := series[2].labels["pool"]; ok || series[2].labels["vmid"] != "100" || len(series[2].labels) != 2 {
+		t.Errorf("expected empty label to be dropped: %+v", series[2])
+	}
+}
+
+// remoteWriteServer records received series and answers with the queued status codes, then 204
+type remoteWriteServer struct {
+	mu       sync.Mutex
+	statuses []int
+	received [][]decodedSeries
+	auth     []string
+}
+
+func (s *remoteWriteServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.auth = append(s.auth, r.Header.Get("Authorization"))
+		if len(s.statuses) > 0 {
+			status := s.statuses[0]
+			s.statuses = s.statuses[1:]
+			w.WriteHeader(status)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, body)
+		if err != nil {
+			t.Errorf("invalid snappy payload: %v", err)
+		}
+		s.received = append(s.received, decodeWriteRequest(t, data))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// newTestRemoteWrite creates a remote write sink for a test server, with fast retries
+func newTestRemoteWrite(t *testing.T, url, bufferDir string) *RemoteWrite {
+	t.Helper()
+	r, err := NewRemoteWrite(config.RemoteWriteConfig{
+		URL:              url,
+		Timeout:          time.Second,
+		Auth:             config.PushAuth{Username: "pve", Password: "secret"},
+		MaxRetries:       2,
+		MinBackoff:       time.Millisecond,
+		MaxBackoff:       time.Millisecond,
+		BufferDir:        bufferDir,
+		BufferMaxBatches: 2,
+	}, "pve-exporter/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRemoteWriteRetry(t *testing.T) {
+	srv := &remoteWriteServer{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(srv.handler(t))
+	defer server.Close()
+
+	r := newTestRemoteWrite(t, server.URL, "")
+	if err := r.Push(context.Background(), []Sample{{Name: "pve_node_up", Value: 1}}, time.Now()); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if len(srv.received) != 1 || len(srv.auth) != 3 {
+		t.Errorf("got %d batches after %d requests, want 1 after 3", len(srv.received), len(srv.auth))
+	}
+	if srv.auth[0] != "Basic cHZlOnNlY3JldA==" {
+		t.Errorf("unexpected Authorization header %q", srv.auth[0])
+	}
+}
+
+func TestRemoteWriteBuffer(t *testing.T) {
+	srv := &remoteWriteServer{}
+	server := httptest.NewServer(srv.handler(t))
+	defer server.Close()
+
+	dir := t.TempDir()
+	r := newTestRemoteWrite(t, server.URL, dir)
+	start := time.Unix(1700000000, 0)
+
+	// Three failed pushes: each is tried three times, and the buffer keeps the newest two
+	for i := 0; i < 3; i++ {
+		srv.statuses = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}
+		if err := r.Push(context.Background(), []Sample{{Name: "pve_node_up", Value: float64(i)}}, start.Add(time.Duration(i)*time.Minute)); err == nil {
+			t.Fatal("expected push to fail")
+		}
+	}
+	if got := r.Buffered(); got != 2 {
+		t.Fatalf("Buffered() = %d, want 2", got)
+	}
+
+	// Once the endpoint is back, buffered batches are sent oldest first
+	if err := r.Push(context.Background(), []Sample{{Name: "pve_node_up", Value: 3}}, start.Add(3*time.Minute)); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	var values []float64
+	for _, batch := range srv.received {
+		values = append(values, batch[0].value)
+	}
+	if len(values) != 3 || values[0] != 1 || values[1] != 2 || values[2] != 3 {
+		t.Errorf("received values %v, want [1 2 3]", values)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 || r.Buffered() != 0 {
+		t.Errorf("buffer not emptied: %v", entries)
+	}
+}
+
+func TestRemoteWriteNotRecoverable(t *testing.T) {
+	srv := &remoteWriteServer{statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(srv.handler(t))
+	defer server.Close()
+
+	r := newTestRemoteWrite(t, server.URL, t.TempDir())
+	if err := r.Push(context.Background(), []Sample{{Name: "pve_node_up", Value: 1}}, time.Now()); err == nil {
+		t.Fatal("expected push to fail")
+	}
+	if len(srv.auth) != 1 || r.Buffered() != 0 {
+		t.Errorf("rejected batch was retried or buffered: %d requests, %d buffered", len(srv.auth), r.Buffered())
+	}
+}
@@ -0,0 +1,98 @@
+package push
+
+import (
+	"math"
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Label is a label name and value
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single value of a flattened metric family. Histograms and summaries are expanded into
+// their _bucket, _sum and _count series, like in the Prometheus text format.
+type Sample struct {
+	Name string
+	Help string
+	// Counter is set for monotonically increasing values: counters and histogram and summary counts and sums
+	Counter bool
+	Labels  []Label // sorted by name, as gathered
+	Value   float64
+}
+
+// Flatten converts gathered metric families into samples
+func Flatten(families []*dto.MetricFamily) []Sample {
+	var samples []Sample
+	for _, mf := range families {
+		name, help := mf.GetName(), mf.GetHelp()
+		for _, m := range mf.GetMetric() {
+			labels := make([]Label, 0, len(m.GetLabel()))
+			for _, lp := range m.GetLabel() {
+				labels = append(labels, Label{Name: lp.GetName(), Value: lp.GetValue()})
+			}
+
+			base := Sample{Name: name, Help: help, Labels: labels}
+			switch {
+			case m.Gauge != nil:
+				base.Value = m.Gauge.GetValue()
+				samples = append(samples, base)
+			case m.Counter != nil:
+				base.Value, base.Counter = m.Counter.GetValue(), true
+				samples = append(samples, base)
+			case m.Untyped != nil:
+				base.Value = m.Untyped.GetValue()
+				samples = append(samples, base)
+			case m.Histogram != nil:
+				samples = appendHistogram(samples, base, m.Histogram)
+			case m.Summary != nil:
+				samples = appendSummary(samples, base, m.Summary)
+			}
+		}
+	}
+	return samples
+}
+
+// appendHistogram appends the bucket, sum and count samples of a histogram
+func appendHistogram(samples []Sample, base Sample, h *dto.Histogram) []Sample {
+	base.Counter = true
+	hasInf := false
+	for _, b := range h.GetBucket() {
+		hasInf = hasInf || math.IsInf(b.GetUpperBound(), 1)
+		samples = append(samples, base.derive("_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound())))
+	}
+	if !hasInf {
+		samples = append(samples, base.derive("_bucket", float64(h.GetSampleCount()), "le", "+Inf"))
+	}
+	return append(samples, base.derive("_sum", h.GetSampleSum()), base.derive("_count", float64(h.GetSampleCount())))
+}
+
+// appendSummary appends the quantile, sum and count samples of a summary
+func appendSummary(samples []Sample, base Sample, s *dto.Summary) []Sample {
+	for _, q := range s.GetQuantile() {
+		samples = append(samples, base.derive("", q.GetValue(), "quantile", formatFloat(q.GetQuantile())))
+	}
+	base.Counter = true
+	return append(samples, base.derive("_sum", s.GetSampleSum()), base.derive("_count", float64(s.GetSampleCount())))
+}
+
+// derive returns a sample with a name suffix, a new value and optionally an extra label
+func (s Sample) derive(suffix string, value float64, label ...string) Sample {
+	s.Name += suffix
+	s.Value = value
+	if len(label) == 2 {
+		s.Labels = append(append(make([]Label, 0, len(s.Labels)+1), s.Labels...), Label{Name: label[0], Value: label[1]})
+	}
+	return s
+}
+
+// formatFloat formats a bucket bound or quantile like the Prometheus text format
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
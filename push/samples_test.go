@@ -0,0 +1,42 @@
+package push
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestFlatten(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "pve_node_up", Help: "up"}, []string{"node"})
+	gauge.WithLabelValues("pve1").Set(1)
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "pve_test_total", Help: "counter"})
+	counter.Add(3)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "pve_test_seconds", Help: "histogram", Buckets: []float64{0.5, 1}})
+	histogram.Observe(0.7)
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "pve_test_size", Help: "summary", Objectives: map[float64]float64{0.5: 0.05}})
+	summary.Observe(4)
+	registry.MustRegister(gauge, counter, histogram, summary)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Sample{
+		{Name: "pve_node_up", Help: "up", Labels: []Label{{Name: "node", Value: "pve1"}}, Value: 1},
+		{Name: "pve_test_seconds_bucket", Help: "histogram", Counter: true, Labels: []Label{{Name: "le", Value: "0.5"}}, Value: 0},
+		{Name: "pve_test_seconds_bucket", Help: "histogram", Counter: true, Labels: []Label{{Name: "le", Value: "1"}}, Value: 1},
+		{Name: "pve_test_seconds_bucket", Help: "histogram", Counter: true, Labels: []Label{{Name: "le", Value: "+Inf"}}, Value: 1},
+		{Name: "pve_test_seconds_sum", Help: "histogram", Counter: true, Labels: []Label{}, Value: 0.7},
+		{Name: "pve_test_seconds_count", Help: "histogram", Counter: true, Labels: []Label{}, Value: 1},
+		{Name: "pve_test_size", Help: "summary", Labels: []Label{{Name: "quantile", Value: "0.5"}}, Value: 4},
+		{Name: "pve_test_size_sum", Help: "summary", Counter: true, Labels: []Label{}, Value: 4},
+		{Name: "pve_test_size_count", Help: "summary", Counter: true, Labels: []Label{}, Value: 1},
+		{Name: "pve_test_total", Help: "counter", Counter: true, Labels: []Label{}, Value: 3},
+	}
+	if got := Flatten(families); !reflect.DeepEqual(got, want) {
+		t.Errorf("Flatten() =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/bigtcze/pve-exporter/collector"
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reloader re-reads the configuration file and swaps in a new collector and access rules.
+// On any error the running configuration is kept.
+type reloader struct {
+	configFile string
+	collector  *collector.Reloadable
+	access     *accessControl
+
+	mu      sync.Mutex
+	current *config.Config
+
+	success   prometheus.Gauge
+	timestamp prometheus.Gauge
+}
+
+// newReloader creates a reloader for the running configuration
+func newReloader(configFile string, cfg *config.Config, c *collector.Reloadable, access *accessControl) *reloader {
+	r := &reloader{
+		configFile: configFile,
+		collector:  c,
+		access:     access,
+		current:    cfg,
+		success: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "pve_exporter_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt was successful",
+		}),
+		timestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "pve_exporter_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload",
+		}),
+	}
+	r.success.Set(1)
+	r.timestamp.SetToCurrentTime()
+	return r
+}
+
+// reload loads and validates the configuration file, then swaps it in
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.LoadFromFile(r.configFile)
+	if err == nil {
+		err = r.access.update(cfg.Server)
+	}
+	if err != nil {
+		r.success.Set(0)
+		return err
+	}
+
+	// The listener is set up once at startup
+	if cfg.Server.ListenAddress != r.current.Server.ListenAddress || cfg.Server.MetricsPath != r.current.Server.MetricsPath ||
+		cfg.Server.WebConfigFile != r.current.Server.WebConfigFile {
+		log.Printf("WARNING: changes to server.listen_address, server.metrics_path and server.web_config_file require a restart")
+	}
+	cfg.Server.ListenAddress = r.current.Server.ListenAddress
+	cfg.Server.MetricsPath = r.current.Server.MetricsPath
+	cfg.Server.WebConfigFile = r.current.Server.WebConfigFile
+
+	// Push targets are also set up once at startup
+	if !reflect.DeepEqual(cfg.Push, r.current.Push) {
+		log.Printf("WARNING: changes to push require a restart")
+	}
+	cfg.Push = r.current.Push
+
+	r.collector.Swap(collector.NewProxmoxCollector(cfg))
+	r.current = cfg
+	r.success.Set(1)
+	r.timestamp.SetToCurrentTime()
+	return nil
+}
+
+// handler serves POST /-/reload
+func (r *reloader) handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.reload(); err != nil {
+			log.Printf("Error reloading configuration: %v", err)
+			http.Error(w, fmt.Sprintf("failed to reload config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		log.Println("Configuration reloaded")
+		_, _ = fmt.Fprintf(w, "OK\n")
+	}
+}
+
+// watchSignals reloads the configuration on SIGHUP
+func (r *reloader) watchSignals() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := r.reload(); err != nil {
+				log.Printf("Error reloading configuration: %v", err)
+				continue
+			}
+			log.Println("Configuration reloaded")
+		}
+	}()
+}
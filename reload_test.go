@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigtcze/pve-exporter/collector"
+	"github.com/bigtcze/pve-exporter/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("proxmox:\n  host: pve1\n  password: secret\n")
+
+	cfg, err := config.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	c := collector.NewReloadable(collector.NewProxmoxCollector(cfg))
+	access, err := newAccessControl(http.NotFoundHandler(), cfg.Server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newReloader(path, cfg, c, access)
+
+	// Valid change: the collector is swapped
+	initial := c.Current()
+	write("proxmox:\n  host: pve2\n  password: secret\nserver:\n  listen_address: \":1234\"\n")
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if c.Current() == initial {
+		t.Error("expected collector to be replaced")
+	}
+	if r.current.Proxmox.Host != "pve2" {
+		t.Errorf("expected new host, got %s", r.current.Proxmox.Host)
+	}
+	if r.current.Server.ListenAddress != cfg.Server.ListenAddress {
+		t.Error("listen address must not change without a restart")
+	}
+	if testutil.ToFloat64(r.success) != 1 {
+		t.Error("expected successful reload to be reported")
+	}
+
+	// Invalid change: the running collector is kept
+	swapped := c.Current()
+	write("proxmox:\n  host: pve3\n  password: secret\nmetrics:\n  naming: short\n")
+	if err := r.reload(); err == nil {
+		t.Fatal("expected reload of invalid config to fail")
+	}
+	if c.Current() != swapped || r.current.Proxmox.Host != "pve2" {
+		t.Error("expected previous configuration to stay active")
+	}
+	if testutil.ToFloat64(r.success) != 0 {
+		t.Error("expected failed reload to be reported")
+	}
+}
+
+func TestReloadHandlerMethod(t *testing.T) {
+	r := &reloader{}
+	rec := httptest.NewRecorder()
+	r.handler()(rec, httptest.NewRequest("GET", "/-/reload", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}